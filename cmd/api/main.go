@@ -1,10 +1,21 @@
 package main
 
 import (
+	"escala-fds-api/internal/apitoken"
+	"escala-fds-api/internal/audit"
+	"escala-fds-api/internal/auth"
 	"escala-fds-api/internal/certificate"
 	"escala-fds-api/internal/comment"
+	"escala-fds-api/internal/escala"
 	"escala-fds-api/internal/holiday"
+	"escala-fds-api/internal/leave"
 	"escala-fds-api/internal/plataform/database"
+	"escala-fds-api/internal/report"
+	"escala-fds-api/internal/rules"
+	"escala-fds-api/internal/schedule"
+	"escala-fds-api/internal/shift"
+	"escala-fds-api/internal/site"
+	"escala-fds-api/internal/storage"
 	"escala-fds-api/internal/swap"
 	"escala-fds-api/internal/user"
 	"escala-fds-api/pkg/ierr"
@@ -47,19 +58,50 @@ func main() {
 		logger.Fatal("database connection error", zap.Error(err))
 	}
 
+	keySet, err := auth.LoadKeySetFromEnv()
+	if err != nil {
+		logger.Fatal("jwt key configuration error", zap.Error(err))
+	}
+	auth.SetKeySet(keySet)
+
+	fileStorage, err := storage.NewFromEnv()
+	if err != nil {
+		logger.Fatal("storage configuration error", zap.Error(err))
+	}
+
 	// Repositories
 	userRepo := user.NewRepository(db)
 	swapRepo := swap.NewRepository(db)
 	commentRepo := comment.NewRepository(db)
 	holidayRepo := holiday.NewRepository(db)
 	certificateRepo := certificate.NewRepository(db)
+	apiTokenRepo := apitoken.NewRepository(db)
+	leaveRepo := leave.NewRepository(db)
+	shiftRepo := shift.NewRepository(db)
+	escalaRepo := escala.NewRepository(db)
+	siteRepo := site.NewRepository(db)
+	rulesRepo := rules.NewRepository(db)
+	auditRepo := audit.NewRepository(db)
 
 	// Services
-	userService := user.NewService(userRepo)
-	swapService := swap.NewService(swapRepo, userRepo, holidayRepo)
+	shiftService := shift.NewService(shiftRepo)
+	if err := shiftService.SeedDefaults(); err != nil {
+		logger.Fatal("shift seed error", zap.Error(err))
+	}
+	rulesService := rules.NewService(rulesRepo)
+	auditService := audit.NewService(auditRepo, userRepo)
+	scheduleService := schedule.NewService(swapRepo, holidayRepo, shiftRepo, escalaRepo, userRepo)
+	userService := user.NewService(userRepo, keySet, shiftRepo, siteRepo)
+	swapService := swap.NewService(swapRepo, userRepo, scheduleService, rulesService, auditService)
 	commentService := comment.NewService(commentRepo, userRepo)
 	holidayService := holiday.NewService(holidayRepo)
-	certificateService := certificate.NewService(certificateRepo, userRepo)
+	leaveService := leave.NewService(leaveRepo, userRepo)
+	certificateService := certificate.NewService(certificateRepo, userRepo, scheduleService, fileStorage, leaveService, rulesService)
+	escalaService := escala.NewService(escalaRepo, userRepo, shiftRepo, holidayRepo, certificateRepo, scheduleService, rulesService)
+	reportService := report.NewService(userRepo, holidayRepo, certificateRepo, swapRepo, scheduleService)
+	siteService := site.NewService(siteRepo)
+	apiTokenService := apitoken.NewService(apiTokenRepo, userRepo)
+	auth.SetTokenAuthenticator(apiTokenService)
 
 	// Handlers
 	userHandler := user.NewHandler(userService)
@@ -67,6 +109,15 @@ func main() {
 	commentHandler := comment.NewHandler(commentService)
 	holidayHandler := holiday.NewHandler(holidayService)
 	certificateHandler := certificate.NewHandler(certificateService)
+	apiTokenHandler := apitoken.NewHandler(apiTokenService)
+	leaveHandler := leave.NewHandler(leaveService)
+	shiftHandler := shift.NewHandler(shiftService)
+	escalaHandler := escala.NewHandler(escalaService)
+	reportHandler := report.NewHandler(reportService)
+	scheduleHandler := schedule.NewHandler(scheduleService, userRepo)
+	siteHandler := site.NewHandler(siteService)
+	rulesHandler := rules.NewHandler(rulesService)
+	auditHandler := audit.NewHandler(auditService)
 
 	// Router
 	router := gin.New()
@@ -79,6 +130,8 @@ func main() {
 	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
 	router.Use(cors.New(config))
 
+	router.GET("/.well-known/jwks.json", auth.JWKSHandler(keySet))
+
 	api := router.Group("/api")
 
 	userHandler.RegisterRoutes(api)
@@ -86,6 +139,15 @@ func main() {
 	commentHandler.RegisterRoutes(api)
 	holidayHandler.RegisterRoutes(api)
 	certificateHandler.RegisterRoutes(api)
+	apiTokenHandler.RegisterRoutes(api)
+	leaveHandler.RegisterRoutes(api)
+	shiftHandler.RegisterRoutes(api)
+	escalaHandler.RegisterRoutes(api)
+	reportHandler.RegisterRoutes(api)
+	scheduleHandler.RegisterRoutes(api)
+	siteHandler.RegisterRoutes(api)
+	rulesHandler.RegisterRoutes(api)
+	auditHandler.RegisterRoutes(api)
 
 	port := os.Getenv("SERVER_PORT")
 	if port == "" {
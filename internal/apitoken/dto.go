@@ -0,0 +1,50 @@
+package apitoken
+
+import (
+	"escala-fds-api/internal/constants"
+	"escala-fds-api/internal/entity"
+	"strings"
+)
+
+type CreateTokenRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expiresInDays" binding:"omitempty,min=1,max=365"`
+}
+
+type TokenResponse struct {
+	ID         uint     `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  *string  `json:"expiresAt,omitempty"`
+	LastUsedAt *string  `json:"lastUsedAt,omitempty"`
+	CreatedAt  string   `json:"createdAt"`
+}
+
+type CreateTokenResponse struct {
+	TokenResponse
+	Token string `json:"token"`
+}
+
+func ToTokenResponse(token *entity.APIToken) TokenResponse {
+	var expiresAt *string
+	if token.ExpiresAt != nil {
+		formatted := token.ExpiresAt.Format(constants.ApiTimestampLayout)
+		expiresAt = &formatted
+	}
+	var lastUsedAt *string
+	if token.LastUsedAt != nil {
+		formatted := token.LastUsedAt.Format(constants.ApiTimestampLayout)
+		lastUsedAt = &formatted
+	}
+	return TokenResponse{
+		ID:         token.ID,
+		Name:       token.Name,
+		Prefix:     token.Prefix,
+		Scopes:     strings.Fields(token.Scopes),
+		ExpiresAt:  expiresAt,
+		LastUsedAt: lastUsedAt,
+		CreatedAt:  token.CreatedAt.Format(constants.ApiTimestampLayout),
+	}
+}
@@ -0,0 +1,79 @@
+package apitoken
+
+import (
+	"escala-fds-api/internal/auth"
+	"escala-fds-api/internal/validation"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	tokenRoutes := router.Group("/me/tokens")
+	tokenRoutes.Use(auth.Middleware(), auth.RequireSession())
+	{
+		tokenRoutes.POST("", h.Create)
+		tokenRoutes.GET("", h.FindAll)
+		tokenRoutes.DELETE("/:id", h.Revoke)
+	}
+}
+
+func (h *Handler) Create(c *gin.Context) {
+	userID, errAuth := auth.GetUserIDFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+
+	var req CreateTokenRequest
+	if errBind := validation.BindJSON(c, &req); errBind != nil {
+		c.JSON(errBind.Code, errBind)
+		return
+	}
+
+	token, err := h.service.CreateToken(userID, req.Name, req.Scopes, req.ExpiresInDays)
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusCreated, token)
+}
+
+func (h *Handler) FindAll(c *gin.Context) {
+	userID, errAuth := auth.GetUserIDFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+
+	tokens, err := h.service.FindTokensForUser(userID)
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *Handler) Revoke(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	userID, errAuth := auth.GetUserIDFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+
+	if err := h.service.RevokeToken(uint(id), userID); err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
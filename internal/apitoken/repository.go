@@ -0,0 +1,59 @@
+package apitoken
+
+import (
+	"escala-fds-api/internal/entity"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	Create(token *entity.APIToken) error
+	FindByID(id uint) (*entity.APIToken, error)
+	FindBySecretHash(hash string) (*entity.APIToken, error)
+	FindByUserID(userID uint) ([]entity.APIToken, error)
+	UpdateLastUsed(id uint, usedAt time.Time) error
+	Delete(id uint) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Create(token *entity.APIToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *repository) FindByID(id uint) (*entity.APIToken, error) {
+	var token entity.APIToken
+	if err := r.db.First(&token, id).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *repository) FindBySecretHash(hash string) (*entity.APIToken, error) {
+	var token entity.APIToken
+	if err := r.db.Where("secret_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *repository) FindByUserID(userID uint) ([]entity.APIToken, error) {
+	var tokens []entity.APIToken
+	err := r.db.Where("user_id = ?", userID).Order("created_at desc").Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *repository) UpdateLastUsed(id uint, usedAt time.Time) error {
+	return r.db.Model(&entity.APIToken{}).Where("id = ?", id).UpdateColumn("last_used_at", usedAt).Error
+}
+
+func (r *repository) Delete(id uint) error {
+	return r.db.Delete(&entity.APIToken{}, id).Error
+}
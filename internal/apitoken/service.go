@@ -0,0 +1,155 @@
+package apitoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"escala-fds-api/internal/auth"
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/user"
+	"escala-fds-api/pkg/ierr"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const defaultExpirationDays = 90
+
+type Service interface {
+	CreateToken(userID uint, name string, scopes []string, expiresInDays int) (*CreateTokenResponse, *ierr.RestErr)
+	FindTokensForUser(userID uint) ([]TokenResponse, *ierr.RestErr)
+	RevokeToken(id, userID uint) *ierr.RestErr
+	AuthenticateToken(token string) (*auth.Principal, *ierr.RestErr)
+}
+
+type service struct {
+	repo     Repository
+	userRepo user.Repository
+}
+
+func NewService(repo Repository, userRepo user.Repository) Service {
+	return &service{repo: repo, userRepo: userRepo}
+}
+
+func (s *service) CreateToken(userID uint, name string, scopes []string, expiresInDays int) (*CreateTokenResponse, *ierr.RestErr) {
+	var causes []ierr.Causes
+	seen := make(map[string]bool)
+	var validScopes []string
+	for _, scope := range scopes {
+		if !entity.APITokenScope(scope).IsValid() {
+			causes = append(causes, ierr.Causes{Field: "scopes", Message: fmt.Sprintf("unknown scope: %s", scope)})
+			continue
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			validScopes = append(validScopes, scope)
+		}
+	}
+	if len(causes) > 0 {
+		return nil, ierr.NewBadRequestValidationError("invalid token scopes", causes)
+	}
+
+	if expiresInDays == 0 {
+		expiresInDays = defaultExpirationDays
+	}
+	expiresAt := time.Now().UTC().AddDate(0, 0, expiresInDays)
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error generating token")
+	}
+	raw := auth.APITokenPrefix + secret
+
+	token := entity.APIToken{
+		UserID:     userID,
+		Name:       name,
+		Prefix:     raw[:len(auth.APITokenPrefix)+8],
+		SecretHash: hashToken(raw),
+		Scopes:     strings.Join(validScopes, " "),
+		ExpiresAt:  &expiresAt,
+	}
+	if err := s.repo.Create(&token); err != nil {
+		return nil, ierr.NewInternalServerError("error creating token")
+	}
+
+	return &CreateTokenResponse{
+		TokenResponse: ToTokenResponse(&token),
+		Token:         raw,
+	}, nil
+}
+
+func (s *service) FindTokensForUser(userID uint) ([]TokenResponse, *ierr.RestErr) {
+	tokens, err := s.repo.FindByUserID(userID)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding tokens")
+	}
+	responses := make([]TokenResponse, 0, len(tokens))
+	for i := range tokens {
+		responses = append(responses, ToTokenResponse(&tokens[i]))
+	}
+	return responses, nil
+}
+
+func (s *service) RevokeToken(id, userID uint) *ierr.RestErr {
+	token, err := s.repo.FindByID(id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ierr.NewNotFoundError("token not found")
+		}
+		return ierr.NewInternalServerError("error finding token")
+	}
+	if token.UserID != userID {
+		return ierr.NewNotFoundError("token not found")
+	}
+	if err := s.repo.Delete(id); err != nil {
+		return ierr.NewInternalServerError("error revoking token")
+	}
+	return nil
+}
+
+func (s *service) AuthenticateToken(raw string) (*auth.Principal, *ierr.RestErr) {
+	token, err := s.repo.FindBySecretHash(hashToken(raw))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ierr.NewUnauthorizedError("invalid or expired token")
+		}
+		return nil, ierr.NewInternalServerError("error validating token")
+	}
+	now := time.Now().UTC()
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return nil, ierr.NewUnauthorizedError("invalid or expired token")
+	}
+
+	owner, err := s.userRepo.FindUserByID(token.UserID)
+	if err != nil {
+		return nil, ierr.NewUnauthorizedError("token owner no longer exists")
+	}
+
+	if err := s.repo.UpdateLastUsed(token.ID, now); err != nil {
+		log.Printf("failed to update last use of token %d: %v", token.ID, err)
+	}
+
+	return &auth.Principal{
+		UserID:   owner.ID,
+		UserType: string(owner.UserType),
+		Team:     string(owner.Team),
+		Scopes:   strings.Fields(token.Scopes),
+	}, nil
+}
+
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,42 @@
+package audit
+
+import (
+	"escala-fds-api/internal/constants"
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/user"
+)
+
+type Filters struct {
+	Action     entity.AuditAction
+	EntityType string
+	EntityID   uint
+}
+
+type AuditEntryResponse struct {
+	ID         uint               `json:"id"`
+	Actor      *user.UserResponse `json:"actor,omitempty"`
+	Action     entity.AuditAction `json:"action"`
+	EntityType string             `json:"entityType"`
+	EntityID   uint               `json:"entityId"`
+	Reason     string             `json:"reason,omitempty"`
+	Details    string             `json:"details,omitempty"`
+	CreatedAt  string             `json:"createdAt"`
+}
+
+func ToAuditEntryResponse(entry *entity.AuditEntry, actor *entity.User) AuditEntryResponse {
+	var actorResponse *user.UserResponse
+	if actor != nil {
+		res := user.ToUserResponse(actor)
+		actorResponse = &res
+	}
+	return AuditEntryResponse{
+		ID:         entry.ID,
+		Actor:      actorResponse,
+		Action:     entry.Action,
+		EntityType: entry.EntityType,
+		EntityID:   entry.EntityID,
+		Reason:     entry.Reason,
+		Details:    entry.Details,
+		CreatedAt:  entry.CreatedAt.Format(constants.ApiTimestampLayout),
+	}
+}
@@ -0,0 +1,48 @@
+package audit
+
+import (
+	"escala-fds-api/internal/auth"
+	"escala-fds-api/internal/entity"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	auditRoutes := router.Group("/audit")
+	auditRoutes.Use(auth.Middleware(), auth.RequireScope("audit"))
+	{
+		auditRoutes.GET("", h.FindAll)
+	}
+}
+
+// FindAll lists the audit trail, newest first, optionally filtered by
+// "action", "entityType" and "entityId".
+func (h *Handler) FindAll(c *gin.Context) {
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	entityID, _ := strconv.Atoi(c.Query("entityId"))
+	filters := Filters{
+		Action:     entity.AuditAction(c.Query("action")),
+		EntityType: c.Query("entityType"),
+		EntityID:   uint(entityID),
+	}
+	entries, err := h.service.FindEntries(filters, entity.UserType(requestorType))
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,40 @@
+package audit
+
+import (
+	"escala-fds-api/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	Create(entry *entity.AuditEntry) error
+	FindAll(filters Filters) ([]entity.AuditEntry, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Create(entry *entity.AuditEntry) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *repository) FindAll(filters Filters) ([]entity.AuditEntry, error) {
+	query := r.db
+	if filters.Action != "" {
+		query = query.Where("action = ?", filters.Action)
+	}
+	if filters.EntityType != "" {
+		query = query.Where("entity_type = ?", filters.EntityType)
+	}
+	if filters.EntityID != 0 {
+		query = query.Where("entity_id = ?", filters.EntityID)
+	}
+	var entries []entity.AuditEntry
+	err := query.Order("created_at desc").Find(&entries).Error
+	return entries, err
+}
@@ -0,0 +1,61 @@
+package audit
+
+import (
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/user"
+	"escala-fds-api/pkg/ierr"
+)
+
+type Service interface {
+	Record(entry entity.AuditEntry) *ierr.RestErr
+	FindEntries(filters Filters, requestorType entity.UserType) ([]AuditEntryResponse, *ierr.RestErr)
+}
+
+type service struct {
+	repo     Repository
+	userRepo user.Repository
+}
+
+func NewService(repo Repository, userRepo user.Repository) Service {
+	return &service{repo: repo, userRepo: userRepo}
+}
+
+func (s *service) Record(entry entity.AuditEntry) *ierr.RestErr {
+	if err := s.repo.Create(&entry); err != nil {
+		return ierr.NewInternalServerError("error recording audit entry")
+	}
+	return nil
+}
+
+func (s *service) FindEntries(filters Filters, requestorType entity.UserType) ([]AuditEntryResponse, *ierr.RestErr) {
+	if requestorType != entity.UserTypeMaster {
+		return nil, ierr.NewForbiddenError("only masters can read the audit trail")
+	}
+	entries, err := s.repo.FindAll(filters)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding audit entries")
+	}
+
+	var actorIDs []uint
+	seen := make(map[uint]bool)
+	for _, entry := range entries {
+		if !seen[entry.ActorID] {
+			seen[entry.ActorID] = true
+			actorIDs = append(actorIDs, entry.ActorID)
+		}
+	}
+	actors, err := s.userRepo.FindUsersByIDs(actorIDs)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error fetching actors of audit entries")
+	}
+	actorMap := make(map[uint]*entity.User, len(actors))
+	for i := range actors {
+		actorMap[actors[i].ID] = &actors[i]
+	}
+
+	responses := make([]AuditEntryResponse, 0, len(entries))
+	for i := range entries {
+		responses = append(responses, ToAuditEntryResponse(&entries[i], actorMap[entries[i].ActorID]))
+	}
+	return responses, nil
+}
@@ -3,17 +3,37 @@ package auth
 import (
 	"escala-fds-api/internal/constants"
 	"escala-fds-api/pkg/ierr"
-	"fmt"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+var keySet *KeySet
+
+// SetKeySet configures the keys used by Middleware to verify tokens.
+func SetKeySet(ks *KeySet) {
+	keySet = ks
+}
+
+// JWKSHandler publishes the public verification keys so other services can
+// validate tokens issued by this API.
+func JWKSHandler(ks *KeySet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, ks.JWKS())
+	}
+}
+
 func Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if keySet == nil {
+			err := ierr.NewInternalServerError("token verification keys are not configured")
+			c.AbortWithStatusJSON(err.Code, err)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			err := ierr.NewUnauthorizedError("authorization header is required")
@@ -29,14 +49,14 @@ func Middleware() gin.HandlerFunc {
 		}
 
 		tokenString := parts[1]
+		if HasAPITokenPrefix(tokenString) {
+			authenticateAPIToken(c, tokenString)
+			return
+		}
+
 		claims := &jwt.MapClaims{}
 
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(os.Getenv("JWT_SECRET_KEY")), nil
-		})
+		token, err := jwt.ParseWithClaims(tokenString, claims, keySet.Keyfunc)
 
 		if err != nil {
 			errRest := ierr.NewUnauthorizedError("invalid or expired token")
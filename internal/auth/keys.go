@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// VerificationKey is a public key accepted when validating tokens. Keys are
+// looked up by the "kid" header so old keys can stay active during rotation.
+type VerificationKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	Public crypto.PublicKey
+}
+
+type signingKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private crypto.PrivateKey
+}
+
+type KeySet struct {
+	signing      *signingKey
+	verification map[string]*VerificationKey
+	hmacSecret   []byte
+	// legacyUntil is when HMAC tokens stop being accepted once a signing key
+	// is configured. The zero value rejects them right away.
+	legacyUntil time.Time
+}
+
+// LoadKeySetFromEnv builds the key set from the environment:
+//
+//	JWT_SIGNING_KEY_FILE      PEM private key (RSA for RS256, Ed25519 for EdDSA)
+//	JWT_SIGNING_KEY_ID        kid of the signing key (defaults to the file name)
+//	JWT_VERIFICATION_KEYS_DIR directory of PEM public keys, kid = file name
+//	JWT_SECRET_KEY            legacy HMAC secret, used to sign only when no
+//	                          signing key file is configured
+//	JWT_LEGACY_HMAC_UNTIL     RFC 3339 time until which HMAC tokens are still
+//	                          accepted alongside a signing key file
+func LoadKeySetFromEnv() (*KeySet, error) {
+	ks := &KeySet{verification: make(map[string]*VerificationKey)}
+
+	if secret := os.Getenv("JWT_SECRET_KEY"); secret != "" {
+		ks.hmacSecret = []byte(secret)
+	}
+	if until := os.Getenv("JWT_LEGACY_HMAC_UNTIL"); until != "" {
+		cutoff, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT_LEGACY_HMAC_UNTIL: %w", err)
+		}
+		ks.legacyUntil = cutoff
+	}
+
+	if dir := os.Getenv("JWT_VERIFICATION_KEYS_DIR"); dir != "" {
+		if err := ks.loadVerificationKeys(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	if path := os.Getenv("JWT_SIGNING_KEY_FILE"); path != "" {
+		kid := os.Getenv("JWT_SIGNING_KEY_ID")
+		if kid == "" {
+			kid = keyIDFromPath(path)
+		}
+		if err := ks.loadSigningKey(path, kid); err != nil {
+			return nil, err
+		}
+	}
+
+	if ks.signing == nil && ks.hmacSecret == nil {
+		return nil, errors.New("no JWT signing key configured: set JWT_SIGNING_KEY_FILE or JWT_SECRET_KEY")
+	}
+	return ks, nil
+}
+
+func (ks *KeySet) loadSigningKey(path, kid string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read signing key: %w", err)
+	}
+
+	if rsaKey, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+		ks.signing = &signingKey{id: kid, method: jwt.SigningMethodRS256, private: rsaKey}
+		ks.verification[kid] = &VerificationKey{ID: kid, Method: jwt.SigningMethodRS256, Public: &rsaKey.PublicKey}
+		return nil
+	}
+	if edKey, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+		public := edKey.(ed25519.PrivateKey).Public()
+		ks.signing = &signingKey{id: kid, method: jwt.SigningMethodEdDSA, private: edKey}
+		ks.verification[kid] = &VerificationKey{ID: kid, Method: jwt.SigningMethodEdDSA, Public: public}
+		return nil
+	}
+	return fmt.Errorf("unsupported signing key in %s: expected RSA or Ed25519 PEM", path)
+}
+
+func (ks *KeySet) loadVerificationKeys(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return fmt.Errorf("failed to list verification keys: %w", err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read verification key %s: %w", path, err)
+		}
+		kid := keyIDFromPath(path)
+		if rsaKey, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+			ks.verification[kid] = &VerificationKey{ID: kid, Method: jwt.SigningMethodRS256, Public: rsaKey}
+			continue
+		}
+		if edKey, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+			ks.verification[kid] = &VerificationKey{ID: kid, Method: jwt.SigningMethodEdDSA, Public: edKey}
+			continue
+		}
+		return fmt.Errorf("unsupported verification key in %s: expected RSA or Ed25519 PEM", path)
+	}
+	return nil
+}
+
+func keyIDFromPath(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// Sign issues a token with the active signing key, falling back to the legacy
+// HMAC secret when no asymmetric key is configured.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if ks.signing == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.hmacSecret)
+	}
+	token := jwt.NewWithClaims(ks.signing.method, claims)
+	token.Header["kid"] = ks.signing.id
+	return token.SignedString(ks.signing.private)
+}
+
+// Keyfunc resolves the verification key for a token based on its "kid" and
+// "alg" headers. Tokens without a kid are only accepted as legacy HMAC tokens,
+// and only while HMAC is the signing method or until JWT_LEGACY_HMAC_UNTIL,
+// so that the old secret stops minting valid tokens after a rotation.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok && ks.acceptsHMAC() {
+			return ks.hmacSecret, nil
+		}
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	key, ok := ks.verification[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id: %s", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.Public, nil
+}
+
+func (ks *KeySet) acceptsHMAC() bool {
+	if ks.hmacSecret == nil {
+		return false
+	}
+	return ks.signing == nil || time.Now().Before(ks.legacyUntil)
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public verification keys in RFC 7517 format. HMAC
+// secrets are never exposed.
+func (ks *KeySet) JWKS() JWKS {
+	ids := make([]string, 0, len(ks.verification))
+	for kid := range ks.verification {
+		ids = append(ids, kid)
+	}
+	sort.Strings(ids)
+
+	jwks := JWKS{Keys: []JWK{}}
+	for _, kid := range ids {
+		key := ks.verification[kid]
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.Method.Alg()}
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
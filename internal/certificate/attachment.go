@@ -0,0 +1,133 @@
+package certificate
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/storage"
+	"escala-fds-api/pkg/ierr"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gabriel-vasile/mimetype"
+	"gorm.io/gorm"
+)
+
+const defaultMaxAttachmentSizeMB = 5
+
+var allowedAttachmentTypes = []string{"application/pdf", "image/jpeg", "image/png"}
+
+// Attachment is an uploaded document received alongside a new certificate.
+type Attachment struct {
+	Filename string
+	Size     int64
+	Content  io.Reader
+}
+
+// AttachmentFile is a stored document ready to be streamed back.
+type AttachmentFile struct {
+	Name        string
+	ContentType string
+	Size        int64
+	Content     io.ReadCloser
+}
+
+func maxAttachmentSizeFromEnv() int64 {
+	sizeMB, err := strconv.Atoi(os.Getenv("CERTIFICATE_MAX_FILE_SIZE_MB"))
+	if err != nil || sizeMB <= 0 {
+		sizeMB = defaultMaxAttachmentSizeMB
+	}
+	return int64(sizeMB) << 20
+}
+
+// storeAttachment checks the upload size and sniffed content type (the
+// client-provided type is ignored) and writes it to storage.
+func (s *service) storeAttachment(certificate *entity.Certificate, attachment *Attachment) *ierr.RestErr {
+	if attachment.Size > s.maxAttachmentSize {
+		return ierr.NewBadRequestValidationError("invalid attachment", []ierr.Causes{
+			{Field: "file", Message: fmt.Sprintf("must be at most %d MB", s.maxAttachmentSize>>20)},
+		})
+	}
+	data, err := io.ReadAll(io.LimitReader(attachment.Content, s.maxAttachmentSize+1))
+	if err != nil {
+		return ierr.NewBadRequestError("could not read uploaded file")
+	}
+	if int64(len(data)) > s.maxAttachmentSize {
+		return ierr.NewBadRequestValidationError("invalid attachment", []ierr.Causes{
+			{Field: "file", Message: fmt.Sprintf("must be at most %d MB", s.maxAttachmentSize>>20)},
+		})
+	}
+	if len(data) == 0 {
+		return ierr.NewBadRequestValidationError("invalid attachment", []ierr.Causes{
+			{Field: "file", Message: "must not be empty"},
+		})
+	}
+
+	detected := mimetype.Detect(data)
+	if !mimetype.EqualsAny(detected.String(), allowedAttachmentTypes...) {
+		return ierr.NewBadRequestValidationError("invalid attachment", []ierr.Causes{
+			{Field: "file", Message: "must be a PDF, JPEG or PNG document"},
+		})
+	}
+
+	suffix := make([]byte, 16)
+	if _, err := rand.Read(suffix); err != nil {
+		return ierr.NewInternalServerError("error storing attachment")
+	}
+	key := fmt.Sprintf("certificates/%d/%s%s", certificate.CollaboratorID, hex.EncodeToString(suffix), detected.Extension())
+
+	if err := s.storage.Put(key, bytes.NewReader(data), detected.String()); err != nil {
+		return ierr.NewInternalServerError("error storing attachment")
+	}
+
+	certificate.AttachmentKey = key
+	certificate.AttachmentName = filepath.Base(attachment.Filename)
+	certificate.AttachmentContentType = detected.String()
+	certificate.AttachmentSize = int64(len(data))
+	return nil
+}
+
+// OpenAttachment returns the certificate document if the requestor is its
+// owner, the owner's direct superior or a master.
+func (s *service) OpenAttachment(id, requestorID uint, requestorType entity.UserType) (*AttachmentFile, *ierr.RestErr) {
+	cert, err := s.repo.FindByID(id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ierr.NewNotFoundError("certificate not found")
+		}
+		return nil, ierr.NewInternalServerError("error finding certificate")
+	}
+
+	if requestorType != entity.UserTypeMaster && cert.CollaboratorID != requestorID {
+		collaborator, err := s.userRepo.FindUserByID(cert.CollaboratorID)
+		if err != nil {
+			return nil, ierr.NewInternalServerError("error finding certificate owner")
+		}
+		if collaborator.SuperiorID == nil || *collaborator.SuperiorID != requestorID {
+			return nil, ierr.NewForbiddenError("you do not have permission to view this certificate file")
+		}
+	}
+
+	if cert.AttachmentKey == "" {
+		return nil, ierr.NewNotFoundError("certificate has no attachment")
+	}
+	content, err := s.storage.Get(cert.AttachmentKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ierr.NewNotFoundError("attachment not found in storage")
+		}
+		return nil, ierr.NewInternalServerError("error reading attachment")
+	}
+
+	return &AttachmentFile{
+		Name:        cert.AttachmentName,
+		ContentType: cert.AttachmentContentType,
+		Size:        cert.AttachmentSize,
+		Content:     content,
+	}, nil
+}
@@ -2,13 +2,16 @@ package certificate
 
 import (
 	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/rules"
 	"escala-fds-api/internal/user"
+	"strconv"
 )
 
 type CreateCertificateRequest struct {
-	StartDate string `json:"startDate" binding:"required"`
-	EndDate   string `json:"endDate" binding:"required"`
-	Reason    string `json:"reason" binding:"required"`
+	Type      entity.CertificateType `json:"type" form:"type" binding:"omitempty,oneof=medical vacation training bereavement unpaid_leave"`
+	StartDate string                 `json:"startDate" form:"startDate" binding:"required,apidate"`
+	EndDate   string                 `json:"endDate" form:"endDate" binding:"required,apidate,gtedatefield=StartDate"`
+	Reason    string                 `json:"reason" form:"reason" binding:"required"`
 }
 
 type UpdateStatusRequest struct {
@@ -16,13 +19,65 @@ type UpdateStatusRequest struct {
 }
 
 type CertificateResponse struct {
-	ID           uint                     `json:"id"`
-	Collaborator user.UserResponse        `json:"collaborator"`
-	StartDate    string                   `json:"startDate"`
-	EndDate      string                   `json:"endDate"`
-	Reason       string                   `json:"reason"`
-	Status       entity.CertificateStatus `json:"status"`
-	ApprovedBy   *user.UserResponse       `json:"approvedBy,omitempty"`
-	CreatedAt    string                   `json:"createdAt"`
-	ApprovedAt   *string                  `json:"approvedAt,omitempty"`
+	ID            uint                     `json:"id"`
+	Collaborator  user.UserResponse        `json:"collaborator"`
+	Type          entity.CertificateType   `json:"type"`
+	StartDate     string                   `json:"startDate"`
+	EndDate       string                   `json:"endDate"`
+	Reason        string                   `json:"reason"`
+	Status        entity.CertificateStatus `json:"status"`
+	ApprovedBy    *user.UserResponse       `json:"approvedBy,omitempty"`
+	CreatedAt     string                   `json:"createdAt"`
+	ApprovedAt    *string                  `json:"approvedAt,omitempty"`
+	Attachment    *AttachmentResponse      `json:"attachment,omitempty"`
+	CoveredShifts []CoveredShiftResponse   `json:"coveredShifts"`
+	Warnings      []rules.Violation        `json:"warnings,omitempty"`
+}
+
+type AttachmentResponse struct {
+	Name        string `json:"name"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	URL         string `json:"url"`
+}
+
+type Filters struct {
+	Type   string
+	Status string
+}
+
+type CoveredShiftResponse struct {
+	Date  string           `json:"date"`
+	Shift entity.ShiftName `json:"shift"`
+}
+
+var certificateExportHeader = []string{"id", "collaboratorId", "collaborator", "type", "startDate", "endDate",
+	"reason", "status", "approvedBy", "createdAt", "approvedAt", "coveredShifts", "attachment"}
+
+func (r *CertificateResponse) exportRow() []string {
+	var approvedBy, approvedAt, attachment string
+	if r.ApprovedBy != nil {
+		approvedBy = r.ApprovedBy.FirstName + " " + r.ApprovedBy.LastName
+	}
+	if r.ApprovedAt != nil {
+		approvedAt = *r.ApprovedAt
+	}
+	if r.Attachment != nil {
+		attachment = r.Attachment.Name
+	}
+	return []string{
+		strconv.FormatUint(uint64(r.ID), 10),
+		strconv.FormatUint(uint64(r.Collaborator.ID), 10),
+		r.Collaborator.FirstName + " " + r.Collaborator.LastName,
+		string(r.Type),
+		r.StartDate,
+		r.EndDate,
+		r.Reason,
+		string(r.Status),
+		approvedBy,
+		r.CreatedAt,
+		approvedAt,
+		strconv.Itoa(len(r.CoveredShifts)),
+		attachment,
+	}
 }
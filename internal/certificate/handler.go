@@ -3,10 +3,13 @@ package certificate
 import (
 	"escala-fds-api/internal/auth"
 	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/export"
+	"escala-fds-api/internal/validation"
 	"escala-fds-api/pkg/ierr"
+	"mime"
 	"net/http"
 	"strconv"
-	"time"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,19 +24,22 @@ func NewHandler(service Service) *Handler {
 
 func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 	routes := router.Group("/certificates")
-	routes.Use(auth.Middleware())
+	routes.Use(auth.Middleware(), auth.RequireScope("certificates"))
 	{
 		routes.POST("", h.Create)
 		routes.GET("", h.FindAll)
+		routes.GET("/types", h.FindTypes)
 		routes.GET("/user/:id", h.FindByUser)
+		routes.GET("/:id/file", h.DownloadFile)
 		routes.PATCH("/:id/status", h.UpdateStatus)
+		routes.POST("/:id/cancel", h.Cancel)
 	}
 }
 
 func (h *Handler) Create(c *gin.Context) {
 	var req CreateCertificateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ierr.NewBadRequestError(err.Error()))
+	if errBind := validation.Bind(c, &req); errBind != nil {
+		c.JSON(errBind.Code, errBind)
 		return
 	}
 
@@ -43,25 +49,44 @@ func (h *Handler) Create(c *gin.Context) {
 		return
 	}
 
-	startDate, err := time.Parse("2006-01-02", req.StartDate)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ierr.NewBadRequestError("invalid startDate format"))
+	startDate, errDate := validation.ParseDate("startDate", req.StartDate)
+	if errDate != nil {
+		c.JSON(errDate.Code, errDate)
 		return
 	}
-	endDate, err := time.Parse("2006-01-02", req.EndDate)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ierr.NewBadRequestError("invalid endDate format"))
+	endDate, errDate := validation.ParseDate("endDate", req.EndDate)
+	if errDate != nil {
+		c.JSON(errDate.Code, errDate)
 		return
 	}
 
 	cert := entity.Certificate{
 		CollaboratorID: collaboratorID,
+		Type:           req.Type,
 		StartDate:      startDate,
 		EndDate:        endDate,
 		Reason:         req.Reason,
 	}
 
-	newCert, errSvc := h.service.CreateCertificate(cert)
+	var attachment *Attachment
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil && err != http.ErrMissingFile {
+			c.JSON(http.StatusBadRequest, ierr.NewBadRequestError("invalid file upload"))
+			return
+		}
+		if fileHeader != nil {
+			file, err := fileHeader.Open()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, ierr.NewBadRequestError("could not read uploaded file"))
+				return
+			}
+			defer file.Close()
+			attachment = &Attachment{Filename: fileHeader.Filename, Size: fileHeader.Size, Content: file}
+		}
+	}
+
+	newCert, errSvc := h.service.CreateCertificate(cert, attachment)
 	if errSvc != nil {
 		c.JSON(errSvc.Code, errSvc)
 		return
@@ -70,6 +95,31 @@ func (h *Handler) Create(c *gin.Context) {
 	c.JSON(http.StatusCreated, newCert)
 }
 
+func (h *Handler) DownloadFile(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	requestorID, errAuth := auth.GetUserIDFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+
+	file, errSvc := h.service.OpenAttachment(uint(id), requestorID, entity.UserType(requestorType))
+	if errSvc != nil {
+		c.JSON(errSvc.Code, errSvc)
+		return
+	}
+	defer file.Content.Close()
+
+	c.DataFromReader(http.StatusOK, file.Size, file.ContentType, file.Content, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": file.Name}),
+	})
+}
+
 func (h *Handler) UpdateStatus(c *gin.Context) {
 	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
 
@@ -90,8 +140,8 @@ func (h *Handler) UpdateStatus(c *gin.Context) {
 	}
 
 	var req UpdateStatusRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ierr.NewBadRequestError(err.Error()))
+	if errBind := validation.BindJSON(c, &req); errBind != nil {
+		c.JSON(errBind.Code, errBind)
 		return
 	}
 
@@ -104,6 +154,28 @@ func (h *Handler) UpdateStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, updatedCert)
 }
 
+func (h *Handler) Cancel(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	requestorID, errAuth := auth.GetUserIDFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+
+	cancelledCert, errSvc := h.service.Cancel(uint(id), requestorID, entity.UserType(requestorType))
+	if errSvc != nil {
+		c.JSON(errSvc.Code, errSvc)
+		return
+	}
+
+	c.JSON(http.StatusOK, cancelledCert)
+}
+
 func (h *Handler) FindAll(c *gin.Context) {
 	userType, errAuth := auth.GetUserTypeFromContext(c)
 	if errAuth != nil {
@@ -115,12 +187,29 @@ func (h *Handler) FindAll(c *gin.Context) {
 		return
 	}
 
-	certs, errSvc := h.service.FindAll()
+	format, restErr := export.FormatFromRequest(c)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+
+	certs, errSvc := h.service.FindAll(filtersFromQuery(c))
 	if errSvc != nil {
 		c.JSON(errSvc.Code, errSvc)
 		return
 	}
 
+	if format != export.FormatJSON {
+		export.Write(c, format, "certificates", certificateExportHeader, func(w export.RowWriter) error {
+			for i := range certs {
+				if err := w.WriteRow(certs[i].exportRow()); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		return
+	}
 	c.JSON(http.StatusOK, certs)
 }
 
@@ -134,7 +223,7 @@ func (h *Handler) FindByUser(c *gin.Context) {
 		return
 	}
 
-	certs, errSvc := h.service.FindByCollaborator(uint(collaboratorID))
+	certs, errSvc := h.service.FindByCollaborator(uint(collaboratorID), filtersFromQuery(c))
 	if errSvc != nil {
 		c.JSON(errSvc.Code, errSvc)
 		return
@@ -142,3 +231,14 @@ func (h *Handler) FindByUser(c *gin.Context) {
 
 	c.JSON(http.StatusOK, certs)
 }
+
+func (h *Handler) FindTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.FindTypes())
+}
+
+func filtersFromQuery(c *gin.Context) Filters {
+	return Filters{
+		Type:   c.Query("type"),
+		Status: c.Query("status"),
+	}
+}
@@ -2,6 +2,7 @@ package certificate
 
 import (
 	"escala-fds-api/internal/entity"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -9,9 +10,11 @@ import (
 type Repository interface {
 	Create(certificate *entity.Certificate) error
 	FindByID(id uint) (*entity.Certificate, error)
-	FindAll() ([]entity.Certificate, error)
-	FindByCollaboratorID(collaboratorID uint) ([]entity.Certificate, error)
+	FindAll(filters Filters) ([]entity.Certificate, error)
+	FindByCollaboratorID(collaboratorID uint, filters Filters) ([]entity.Certificate, error)
+	FindOverlapping(collaboratorID uint, startDate, endDate time.Time, statuses []entity.CertificateStatus) ([]entity.Certificate, error)
 	Update(certificate *entity.Certificate) error
+	Delete(id uint) error
 }
 
 type repository struct {
@@ -32,18 +35,45 @@ func (r *repository) FindByID(id uint) (*entity.Certificate, error) {
 	return &certificate, err
 }
 
-func (r *repository) FindAll() ([]entity.Certificate, error) {
+func (r *repository) FindAll(filters Filters) ([]entity.Certificate, error) {
 	var certificates []entity.Certificate
-	err := r.db.Order("created_at desc").Find(&certificates).Error
+	err := applyFilters(r.db, filters).Order("created_at desc").Find(&certificates).Error
 	return certificates, err
 }
 
-func (r *repository) FindByCollaboratorID(collaboratorID uint) ([]entity.Certificate, error) {
+func (r *repository) FindByCollaboratorID(collaboratorID uint, filters Filters) ([]entity.Certificate, error) {
 	var certificates []entity.Certificate
-	err := r.db.Where("collaborator_id = ?", collaboratorID).Order("start_date desc").Find(&certificates).Error
+	err := applyFilters(r.db, filters).
+		Where("collaborator_id = ?", collaboratorID).
+		Order("start_date desc").
+		Find(&certificates).Error
+	return certificates, err
+}
+
+func applyFilters(query *gorm.DB, filters Filters) *gorm.DB {
+	if filters.Type != "" {
+		query = query.Where("type = ?", filters.Type)
+	}
+	if filters.Status != "" {
+		query = query.Where("status = ?", filters.Status)
+	}
+	return query
+}
+
+func (r *repository) FindOverlapping(collaboratorID uint, startDate, endDate time.Time, statuses []entity.CertificateStatus) ([]entity.Certificate, error) {
+	var certificates []entity.Certificate
+	err := r.db.
+		Where("collaborator_id = ? AND status IN ?", collaboratorID, statuses).
+		Where("start_date <= ? AND end_date >= ?", endDate, startDate).
+		Order("start_date asc").
+		Find(&certificates).Error
 	return certificates, err
 }
 
 func (r *repository) Update(certificate *entity.Certificate) error {
 	return r.db.Save(certificate).Error
 }
+
+func (r *repository) Delete(id uint) error {
+	return r.db.Delete(&entity.Certificate{}, id).Error
+}
@@ -3,39 +3,157 @@ package certificate
 import (
 	"escala-fds-api/internal/constants"
 	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/leave"
+	"escala-fds-api/internal/rules"
+	"escala-fds-api/internal/schedule"
+	"escala-fds-api/internal/storage"
 	"escala-fds-api/internal/user"
 	"escala-fds-api/pkg/ierr"
+	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 type Service interface {
-	CreateCertificate(certificate entity.Certificate) (*CertificateResponse, *ierr.RestErr)
+	CreateCertificate(certificate entity.Certificate, attachment *Attachment) (*CertificateResponse, *ierr.RestErr)
+	OpenAttachment(id, requestorID uint, requestorType entity.UserType) (*AttachmentFile, *ierr.RestErr)
 	ApproveOrReject(id, approverID uint, status entity.CertificateStatus) (*CertificateResponse, *ierr.RestErr)
-	FindAll() ([]CertificateResponse, *ierr.RestErr)
-	FindByCollaborator(collaboratorID uint) ([]CertificateResponse, *ierr.RestErr)
+	Cancel(id, requestorID uint, requestorType entity.UserType) (*CertificateResponse, *ierr.RestErr)
+	FindAll(filters Filters) ([]CertificateResponse, *ierr.RestErr)
+	FindByCollaborator(collaboratorID uint, filters Filters) ([]CertificateResponse, *ierr.RestErr)
+	FindTypes() []TypeRule
 }
 
+const maxCertificateDistanceDays = 365
+
 type service struct {
-	repo     Repository
-	userRepo user.Repository
+	repo              Repository
+	userRepo          user.Repository
+	schedule          schedule.Service
+	storage           storage.Storage
+	leave             leave.Service
+	rules             rules.Service
+	maxAttachmentSize int64
 }
 
-func NewService(repo Repository, userRepo user.Repository) Service {
-	return &service{repo: repo, userRepo: userRepo}
+func NewService(repo Repository, userRepo user.Repository, scheduleService schedule.Service, store storage.Storage, leaveService leave.Service, rulesService rules.Service) Service {
+	return &service{
+		repo:              repo,
+		userRepo:          userRepo,
+		schedule:          scheduleService,
+		storage:           store,
+		leave:             leaveService,
+		rules:             rulesService,
+		maxAttachmentSize: maxAttachmentSizeFromEnv(),
+	}
 }
 
-func (s *service) CreateCertificate(certificate entity.Certificate) (*CertificateResponse, *ierr.RestErr) {
+func (s *service) CreateCertificate(certificate entity.Certificate, attachment *Attachment) (*CertificateResponse, *ierr.RestErr) {
+	// Clients that predate absence types send no type: they create medical
+	// certificates, with the same rules as any other medical certificate.
+	if certificate.Type == "" {
+		certificate.Type = entity.CertificateTypeMedical
+	}
+	rule, ok := ruleFor(certificate.Type)
+	if !ok {
+		return nil, ierr.NewBadRequestValidationError("invalid certificate", []ierr.Causes{
+			{Field: "type", Message: fmt.Sprintf("unknown certificate type: %s", certificate.Type)},
+		})
+	}
+	if rule.RequiresAttachment && attachment == nil {
+		return nil, ierr.NewBadRequestValidationError("invalid certificate", []ierr.Causes{
+			{Field: "file", Message: fmt.Sprintf("a supporting document is required for %s absences", certificate.Type)},
+		})
+	}
+
+	certificate.StartDate = schedule.DateOnly(certificate.StartDate)
+	certificate.EndDate = schedule.DateOnly(certificate.EndDate)
+	if err := s.validateCertificate(&certificate, rule); err != nil {
+		return nil, err
+	}
+
 	certificate.Status = entity.CertificateStatusPending
+	if !rule.RequiresApproval {
+		now := time.Now().UTC()
+		certificate.Status = entity.CertificateStatusApproved
+		certificate.ApprovedAt = &now
+	}
+
+	if attachment != nil {
+		if err := s.storeAttachment(&certificate, attachment); err != nil {
+			return nil, err
+		}
+	}
 
 	if err := s.repo.Create(&certificate); err != nil {
+		if certificate.AttachmentKey != "" {
+			if err := s.storage.Delete(certificate.AttachmentKey); err != nil {
+				log.Printf("failed to remove orphan attachment %s: %v", certificate.AttachmentKey, err)
+			}
+		}
 		return nil, ierr.NewInternalServerError("error creating certificate")
 	}
 
+	if certificate.Status == entity.CertificateStatusApproved && rule.ConsumesBalance {
+		if err := s.leave.DebitForCertificate(&certificate); err != nil {
+			if errDelete := s.repo.Delete(certificate.ID); errDelete != nil {
+				log.Printf("failed to remove certificate %d after balance debit failure: %v", certificate.ID, errDelete)
+			}
+			return nil, err
+		}
+	}
+
+	if certificate.Status == entity.CertificateStatusApproved {
+		return s.buildResponseWithWarnings(&certificate, true)
+	}
 	return s.buildSingleResponse(certificate.ID)
 }
 
+func (s *service) validateCertificate(certificate *entity.Certificate, rule TypeRule) *ierr.RestErr {
+	var causes []ierr.Causes
+	if certificate.EndDate.Before(certificate.StartDate) {
+		causes = append(causes, ierr.Causes{Field: "endDate", Message: "must be on or after startDate"})
+	} else if days := daysBetween(certificate.StartDate, certificate.EndDate) + 1; days > rule.MaxDays {
+		causes = append(causes, ierr.Causes{Field: "endDate", Message: fmt.Sprintf("%s absences cannot cover more than %d days", rule.Type, rule.MaxDays)})
+	}
+	today := schedule.DateOnly(time.Now().UTC())
+	if distance := daysBetween(today, certificate.StartDate); distance > maxCertificateDistanceDays || distance < -maxCertificateDistanceDays {
+		causes = append(causes, ierr.Causes{Field: "startDate", Message: fmt.Sprintf("must be within %d days of today", maxCertificateDistanceDays)})
+	}
+	if len(causes) > 0 {
+		return ierr.NewBadRequestValidationError("invalid certificate dates", causes)
+	}
+
+	overlapping, err := s.repo.FindOverlapping(
+		certificate.CollaboratorID,
+		certificate.StartDate,
+		certificate.EndDate,
+		[]entity.CertificateStatus{entity.CertificateStatusPending, entity.CertificateStatusApproved},
+	)
+	if err != nil {
+		return ierr.NewInternalServerError("error checking overlapping certificates")
+	}
+	if len(overlapping) > 0 {
+		var ranges []string
+		for _, other := range overlapping {
+			ranges = append(ranges, fmt.Sprintf("#%d (%s to %s, %s)",
+				other.ID,
+				other.StartDate.Format(constants.ApiDateLayout),
+				other.EndDate.Format(constants.ApiDateLayout),
+				other.Status))
+		}
+		return ierr.NewConflictError("certificate overlaps existing certificates: " + strings.Join(ranges, ", "))
+	}
+	return nil
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(schedule.DateOnly(to).Sub(schedule.DateOnly(from)).Hours() / 24)
+}
+
 func (s *service) ApproveOrReject(id, approverID uint, status entity.CertificateStatus) (*CertificateResponse, *ierr.RestErr) {
 	cert, err := s.repo.FindByID(id)
 	if err != nil {
@@ -45,34 +163,126 @@ func (s *service) ApproveOrReject(id, approverID uint, status entity.Certificate
 		return nil, ierr.NewInternalServerError("error finding certificate")
 	}
 
+	if cert.Status == entity.CertificateStatusCancelled {
+		return nil, ierr.NewConflictError("cannot change the status of a cancelled certificate")
+	}
+
+	previousStatus := cert.Status
+	rule, _ := ruleFor(cert.Type)
+	if rule.ConsumesBalance && status == entity.CertificateStatusApproved && previousStatus != entity.CertificateStatusApproved {
+		if err := s.leave.DebitForCertificate(cert); err != nil {
+			return nil, err
+		}
+	}
+
 	now := time.Now().UTC()
 	cert.Status = status
 	cert.ApprovedByID = &approverID
 	cert.ApprovedAt = &now
 
 	if err := s.repo.Update(cert); err != nil {
+		if rule.ConsumesBalance && status == entity.CertificateStatusApproved && previousStatus != entity.CertificateStatusApproved {
+			if errReverse := s.leave.ReverseForCertificate(cert, "approval failed"); errReverse != nil {
+				log.Printf("failed to reverse leave debit of certificate %d: %v", cert.ID, errReverse)
+			}
+		}
 		return nil, ierr.NewInternalServerError("error updating certificate status")
 	}
 
+	if rule.ConsumesBalance && status == entity.CertificateStatusRejected && previousStatus == entity.CertificateStatusApproved {
+		if err := s.leave.ReverseForCertificate(cert, "rejected"); err != nil {
+			return nil, err
+		}
+	}
+
+	if status == entity.CertificateStatusApproved && previousStatus != entity.CertificateStatusApproved {
+		return s.buildResponseWithWarnings(cert, true)
+	}
+	if status == entity.CertificateStatusRejected && previousStatus == entity.CertificateStatusApproved {
+		return s.buildResponseWithWarnings(cert, false)
+	}
 	return s.buildSingleResponse(id)
 }
 
-func (s *service) FindAll() ([]CertificateResponse, *ierr.RestErr) {
-	certificates, err := s.repo.FindAll()
+// Cancel withdraws a pending or approved certificate, crediting back any
+// leave days it consumed. Only the owner or a master may cancel.
+func (s *service) Cancel(id, requestorID uint, requestorType entity.UserType) (*CertificateResponse, *ierr.RestErr) {
+	cert, err := s.repo.FindByID(id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ierr.NewNotFoundError("certificate not found")
+		}
+		return nil, ierr.NewInternalServerError("error finding certificate")
+	}
+	if requestorType != entity.UserTypeMaster && cert.CollaboratorID != requestorID {
+		return nil, ierr.NewForbiddenError("you can only cancel your own certificates")
+	}
+	if cert.Status != entity.CertificateStatusPending && cert.Status != entity.CertificateStatusApproved {
+		return nil, ierr.NewConflictError(fmt.Sprintf("cannot cancel a %s certificate", cert.Status))
+	}
+
+	wasApproved := cert.Status == entity.CertificateStatusApproved
+	cert.Status = entity.CertificateStatusCancelled
+	if err := s.repo.Update(cert); err != nil {
+		return nil, ierr.NewInternalServerError("error cancelling certificate")
+	}
+
+	if rule, _ := ruleFor(cert.Type); rule.ConsumesBalance && wasApproved {
+		if err := s.leave.ReverseForCertificate(cert, "cancelled"); err != nil {
+			return nil, err
+		}
+	}
+
+	if wasApproved {
+		return s.buildResponseWithWarnings(cert, false)
+	}
+	return s.buildSingleResponse(id)
+}
+
+func (s *service) FindAll(filters Filters) ([]CertificateResponse, *ierr.RestErr) {
+	if err := validateFilters(filters); err != nil {
+		return nil, err
+	}
+	certificates, err := s.repo.FindAll(filters)
 	if err != nil {
 		return nil, ierr.NewInternalServerError("error finding certificates")
 	}
 	return s.buildResponseList(certificates)
 }
 
-func (s *service) FindByCollaborator(collaboratorID uint) ([]CertificateResponse, *ierr.RestErr) {
-	certificates, err := s.repo.FindByCollaboratorID(collaboratorID)
+func (s *service) FindByCollaborator(collaboratorID uint, filters Filters) ([]CertificateResponse, *ierr.RestErr) {
+	if err := validateFilters(filters); err != nil {
+		return nil, err
+	}
+	certificates, err := s.repo.FindByCollaboratorID(collaboratorID, filters)
 	if err != nil {
 		return nil, ierr.NewInternalServerError("error finding certificates for collaborator")
 	}
 	return s.buildResponseList(certificates)
 }
 
+func (s *service) FindTypes() []TypeRule {
+	return typeRules
+}
+
+func validateFilters(filters Filters) *ierr.RestErr {
+	var causes []ierr.Causes
+	if filters.Type != "" {
+		if _, ok := ruleFor(entity.CertificateType(filters.Type)); !ok {
+			causes = append(causes, ierr.Causes{Field: "type", Message: fmt.Sprintf("unknown certificate type: %s", filters.Type)})
+		}
+	}
+	switch entity.CertificateStatus(filters.Status) {
+	case "", entity.CertificateStatusPending, entity.CertificateStatusApproved, entity.CertificateStatusRejected, entity.CertificateStatusCancelled:
+	default:
+		causes = append(causes, ierr.Causes{Field: "status", Message: "must be one of: pending, approved, rejected, cancelled"})
+	}
+	if len(causes) > 0 {
+		return ierr.NewBadRequestValidationError("invalid filters", causes)
+	}
+	return nil
+}
+
 func (s *service) buildSingleResponse(id uint) (*CertificateResponse, *ierr.RestErr) {
 	cert, err := s.repo.FindByID(id)
 	if err != nil {
@@ -89,6 +299,105 @@ func (s *service) buildSingleResponse(id uint) (*CertificateResponse, *ierr.Rest
 	return &list[0], nil
 }
 
+// buildResponseWithWarnings is used when a certificate starts or stops
+// counting as an absence: absent tells which. The labor rules of the team are
+// evaluated on the schedule around the certificate before and after the
+// change, and the breaches the change introduces are reported as warnings,
+// since an absence is never refused or withdrawn on labor rule grounds.
+func (s *service) buildResponseWithWarnings(cert *entity.Certificate, absent bool) (*CertificateResponse, *ierr.RestErr) {
+	response, restErr := s.buildSingleResponse(cert.ID)
+	if restErr != nil {
+		return nil, restErr
+	}
+	warnings, restErr := s.ruleWarnings(cert, absent)
+	if restErr != nil {
+		return nil, restErr
+	}
+	response.Warnings = warnings
+	return response, nil
+}
+
+// ruleWarnings returns the rule breaches that appear around cert when its days
+// become days off (absent) or working days again. The schedule does not know
+// about absences, so the days of the other approved certificates of the
+// collaborator are taken off in both timelines.
+func (s *service) ruleWarnings(cert *entity.Certificate, absent bool) ([]rules.Violation, *ierr.RestErr) {
+	collaborator, err := s.userRepo.FindUserByID(cert.CollaboratorID)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error fetching collaborator of certificate")
+	}
+	config, restErr := s.rules.Config(collaborator.Team)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	// Two weeks around the certificate cover the weekly rest and consecutive
+	// days rules, and whole months the monthly limits.
+	first, last := schedule.DateOnly(cert.StartDate), schedule.DateOnly(cert.EndDate)
+	start := first.AddDate(0, 0, -14)
+	if monthStart := first.AddDate(0, 0, 1-first.Day()); monthStart.Before(start) {
+		start = monthStart
+	}
+	end := last.AddDate(0, 0, 14)
+	if monthEnd := last.AddDate(0, 1, -last.Day()); monthEnd.After(end) {
+		end = monthEnd
+	}
+	days, err := s.schedule.ShiftsInRange(collaborator, start, end)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error computing schedule impact of certificate")
+	}
+	others, err := s.repo.FindOverlapping(collaborator.ID, start, end, []entity.CertificateStatus{entity.CertificateStatusApproved})
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding absences around certificate")
+	}
+
+	evaluate := func(withCert bool) ([]rules.Violation, error) {
+		timeline := &rules.Timeline{Days: make([]schedule.Day, len(days))}
+		copy(timeline.Days, days)
+		for i := range timeline.Days {
+			day := &timeline.Days[i]
+			timeline.Focus = append(timeline.Focus, day.Date)
+			if withCert && covers(cert, day.Date) {
+				day.Working = false
+			}
+			for j := range others {
+				if others[j].ID != cert.ID && covers(&others[j], day.Date) {
+					day.Working = false
+				}
+			}
+		}
+		spans, err := rules.SpansOf(timeline.Days, s.schedule.ShiftWindow)
+		if err != nil {
+			return nil, err
+		}
+		timeline.Spans = spans
+		return rules.Evaluate(timeline, config, ""), nil
+	}
+	before, errBefore := evaluate(!absent)
+	after, errAfter := evaluate(absent)
+	if errBefore != nil || errAfter != nil {
+		return nil, ierr.NewInternalServerError("error computing schedule impact of certificate")
+	}
+
+	existing := make(map[string]bool, len(before))
+	for _, v := range before {
+		existing[string(v.Rule)+v.Message] = true
+	}
+	var introduced []rules.Violation
+	for _, v := range after {
+		if !existing[string(v.Rule)+v.Message] {
+			introduced = append(introduced, v)
+		}
+	}
+	return introduced, nil
+}
+
+// covers reports whether date falls within the certificate.
+func covers(cert *entity.Certificate, date time.Time) bool {
+	day := schedule.DateOnly(date)
+	return !day.Before(schedule.DateOnly(cert.StartDate)) && !day.After(schedule.DateOnly(cert.EndDate))
+}
+
 func (s *service) buildResponseList(certificates []entity.Certificate) ([]CertificateResponse, *ierr.RestErr) {
 	var userIDs []uint
 	userIDsSet := make(map[uint]bool)
@@ -114,6 +423,11 @@ func (s *service) buildResponseList(certificates []entity.Certificate) ([]Certif
 		userMap[users[i].ID] = &users[i]
 	}
 
+	schedules, restErr := s.schedulesFor(certificates, userMap)
+	if restErr != nil {
+		return nil, restErr
+	}
+
 	var responses []CertificateResponse
 	for _, cert := range certificates {
 		collaborator := userMap[cert.CollaboratorID]
@@ -122,12 +436,63 @@ func (s *service) buildResponseList(certificates []entity.Certificate) ([]Certif
 			approver = userMap[*cert.ApprovedByID]
 		}
 		if collaborator != nil {
-			responses = append(responses, s.toResponse(&cert, collaborator, approver))
+			response := s.toResponse(&cert, collaborator, approver)
+			response.CoveredShifts = coveredShifts(&cert, schedules[cert.CollaboratorID])
+			responses = append(responses, response)
 		}
 	}
 	return responses, nil
 }
 
+// schedulesFor resolves, once per collaborator, the schedule from the start
+// of their first certificate to the end of their last one.
+func (s *service) schedulesFor(certificates []entity.Certificate, users map[uint]*entity.User) (map[uint][]schedule.Day, *ierr.RestErr) {
+	type period struct{ start, end time.Time }
+	periods := make(map[uint]*period)
+	for _, cert := range certificates {
+		p, ok := periods[cert.CollaboratorID]
+		if !ok {
+			periods[cert.CollaboratorID] = &period{start: cert.StartDate, end: cert.EndDate}
+			continue
+		}
+		if cert.StartDate.Before(p.start) {
+			p.start = cert.StartDate
+		}
+		if cert.EndDate.After(p.end) {
+			p.end = cert.EndDate
+		}
+	}
+
+	schedules := make(map[uint][]schedule.Day, len(periods))
+	for id, p := range periods {
+		collaborator, ok := users[id]
+		if !ok {
+			continue
+		}
+		days, err := s.schedule.ShiftsInRange(collaborator, p.start, p.end)
+		if err != nil {
+			return nil, ierr.NewInternalServerError("error computing schedule impact of certificate")
+		}
+		schedules[id] = days
+	}
+	return schedules, nil
+}
+
+// coveredShifts lists the shifts the collaborator was scheduled to work during
+// the certificate, i.e. what the absence actually removes from the escala.
+func coveredShifts(cert *entity.Certificate, days []schedule.Day) []CoveredShiftResponse {
+	covered := []CoveredShiftResponse{}
+	for _, day := range days {
+		if day.Working && covers(cert, day.Date) {
+			covered = append(covered, CoveredShiftResponse{
+				Date:  day.Date.Format(constants.ApiDateLayout),
+				Shift: day.Shift,
+			})
+		}
+	}
+	return covered
+}
+
 func (s *service) toResponse(cert *entity.Certificate, collaborator *entity.User, approvedBy *entity.User) CertificateResponse {
 	var approvedByResponse *user.UserResponse
 	if approvedBy != nil {
@@ -141,9 +506,20 @@ func (s *service) toResponse(cert *entity.Certificate, collaborator *entity.User
 		approvedAt = &formatted
 	}
 
+	var attachment *AttachmentResponse
+	if cert.AttachmentKey != "" {
+		attachment = &AttachmentResponse{
+			Name:        cert.AttachmentName,
+			ContentType: cert.AttachmentContentType,
+			Size:        cert.AttachmentSize,
+			URL:         fmt.Sprintf("/api/certificates/%d/file", cert.ID),
+		}
+	}
+
 	return CertificateResponse{
 		ID:           cert.ID,
 		Collaborator: user.ToUserResponse(collaborator),
+		Type:         cert.Type,
 		StartDate:    cert.StartDate.Format(constants.ApiDateLayout),
 		EndDate:      cert.EndDate.Format(constants.ApiDateLayout),
 		Reason:       cert.Reason,
@@ -151,5 +527,6 @@ func (s *service) toResponse(cert *entity.Certificate, collaborator *entity.User
 		ApprovedBy:   approvedByResponse,
 		CreatedAt:    cert.CreatedAt.Format(constants.ApiTimestampLayout),
 		ApprovedAt:   approvedAt,
+		Attachment:   attachment,
 	}
 }
@@ -0,0 +1,31 @@
+package certificate
+
+import "escala-fds-api/internal/entity"
+
+// TypeRule describes how an absence type is handled: whether a document must
+// be uploaded, whether a superior has to approve it, whether it is debited
+// from the collaborator's leave balance and how long it may last.
+type TypeRule struct {
+	Type               entity.CertificateType `json:"type"`
+	RequiresAttachment bool                   `json:"requiresAttachment"`
+	RequiresApproval   bool                   `json:"requiresApproval"`
+	ConsumesBalance    bool                   `json:"consumesBalance"`
+	MaxDays            int                    `json:"maxDays"`
+}
+
+var typeRules = []TypeRule{
+	{Type: entity.CertificateTypeMedical, RequiresAttachment: true, RequiresApproval: true, MaxDays: 180},
+	{Type: entity.CertificateTypeVacation, RequiresApproval: true, ConsumesBalance: true, MaxDays: 30},
+	{Type: entity.CertificateTypeTraining, RequiresApproval: true, MaxDays: 30},
+	{Type: entity.CertificateTypeBereavement, RequiresAttachment: true, MaxDays: 2},
+	{Type: entity.CertificateTypeUnpaidLeave, RequiresApproval: true, MaxDays: 90},
+}
+
+func ruleFor(certificateType entity.CertificateType) (TypeRule, bool) {
+	for _, rule := range typeRules {
+		if rule.Type == certificateType {
+			return rule, true
+		}
+	}
+	return TypeRule{}, false
+}
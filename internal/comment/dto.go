@@ -2,12 +2,13 @@ package comment
 
 import (
 	"escala-fds-api/internal/user"
+	"strconv"
 )
 
 type CreateCommentRequest struct {
 	CollaboratorID uint   `json:"collaboratorId" binding:"required"`
 	Text           string `json:"text" binding:"required"`
-	Date           string `json:"date" binding:"required"`
+	Date           string `json:"date" binding:"required,apidate"`
 }
 
 type UpdateCommentRequest struct {
@@ -31,3 +32,19 @@ type Filters struct {
 	AuthorID       string
 	Team           string
 }
+
+var commentExportHeader = []string{"id", "date", "collaboratorId", "collaborator", "authorId", "author", "text", "createdAt", "updatedAt"}
+
+func (r *CommentResponse) exportRow() []string {
+	return []string{
+		strconv.FormatUint(uint64(r.ID), 10),
+		r.Date,
+		strconv.FormatUint(uint64(r.Collaborator.ID), 10),
+		r.Collaborator.FirstName + " " + r.Collaborator.LastName,
+		strconv.FormatUint(uint64(r.Author.ID), 10),
+		r.Author.FirstName + " " + r.Author.LastName,
+		r.Text,
+		r.CreatedAt,
+		r.UpdatedAt,
+	}
+}
@@ -3,10 +3,10 @@ package comment
 import (
 	"escala-fds-api/internal/auth"
 	"escala-fds-api/internal/entity"
-	"escala-fds-api/pkg/ierr"
+	"escala-fds-api/internal/export"
+	"escala-fds-api/internal/validation"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,7 +21,7 @@ func NewHandler(service Service) *Handler {
 
 func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 	commentRoutes := router.Group("/comments")
-	commentRoutes.Use(auth.Middleware())
+	commentRoutes.Use(auth.Middleware(), auth.RequireScope("comments"))
 	{
 		commentRoutes.POST("", h.Create)
 		commentRoutes.GET("", h.Find)
@@ -33,8 +33,8 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 
 func (h *Handler) Create(c *gin.Context) {
 	var req CreateCommentRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ierr.NewBadRequestError(err.Error()))
+	if errBind := validation.BindJSON(c, &req); errBind != nil {
+		c.JSON(errBind.Code, errBind)
 		return
 	}
 
@@ -50,9 +50,9 @@ func (h *Handler) Create(c *gin.Context) {
 		return
 	}
 
-	date, err := time.ParseInLocation("2006-01-02", req.Date, time.UTC)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ierr.NewBadRequestError("invalid date format, use yyyy-MM-dd"))
+	date, errDate := validation.ParseDate("date", req.Date)
+	if errDate != nil {
+		c.JSON(errDate.Code, errDate)
 		return
 	}
 
@@ -82,11 +82,38 @@ func (h *Handler) Find(c *gin.Context) {
 		Team:           c.Query("team"),
 	}
 
+	for field, value := range map[string]string{"startDate": filters.StartDate, "endDate": filters.EndDate} {
+		if value == "" {
+			continue
+		}
+		if _, errDate := validation.ParseDate(field, value); errDate != nil {
+			c.JSON(errDate.Code, errDate)
+			return
+		}
+	}
+
+	format, restErr := export.FormatFromRequest(c)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+
 	comments, err := h.service.FindComments(requestorID, entity.UserType(requestorType), filters)
 	if err != nil {
 		c.JSON(err.Code, err)
 		return
 	}
+	if format != export.FormatJSON {
+		export.Write(c, format, "comments", commentExportHeader, func(w export.RowWriter) error {
+			for i := range comments {
+				if err := w.WriteRow(comments[i].exportRow()); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		return
+	}
 	c.JSON(http.StatusOK, comments)
 }
 
@@ -109,8 +136,8 @@ func (h *Handler) Update(c *gin.Context) {
 	}
 
 	var req UpdateCommentRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ierr.NewBadRequestError(err.Error()))
+	if errBind := validation.BindJSON(c, &req); errBind != nil {
+		c.JSON(errBind.Code, errBind)
 		return
 	}
 
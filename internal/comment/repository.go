@@ -1,6 +1,7 @@
 package comment
 
 import (
+	"escala-fds-api/internal/constants"
 	"escala-fds-api/internal/entity"
 	"time"
 
@@ -51,12 +52,12 @@ func (r *repository) Find(filters Filters) ([]entity.Comment, error) {
 		query = query.Where("collaborator.team = ?", filters.Team)
 	}
 	if filters.StartDate != "" {
-		if date, err := time.Parse("2006-01-02", filters.StartDate); err == nil {
+		if date, err := time.Parse(constants.ApiDateLayout, filters.StartDate); err == nil {
 			query = query.Where("comments.date >= ?", date)
 		}
 	}
 	if filters.EndDate != "" {
-		if date, err := time.Parse("2006-01-02", filters.EndDate); err == nil {
+		if date, err := time.Parse(constants.ApiDateLayout, filters.EndDate); err == nil {
 			query = query.Where("comments.date <= ?", date)
 		}
 	}
@@ -3,8 +3,10 @@ package constants
 const (
 	ApiTimestampLayout = "2006-01-02 15:04:05"
 	ApiDateLayout      = "2006-01-02"
+	ApiMonthLayout     = "2006-01"
 
 	JwtUserIdKey   = "userId"
 	JwtUserTypeKey = "userType"
 	JwtTeamKey     = "team"
+	TokenScopesKey = "tokenScopes"
 )
@@ -0,0 +1,66 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type APITokenScope string
+
+const (
+	ScopeUsersRead         APITokenScope = "users:read"
+	ScopeUsersWrite        APITokenScope = "users:write"
+	ScopeSwapsRead         APITokenScope = "swaps:read"
+	ScopeSwapsWrite        APITokenScope = "swaps:write"
+	ScopeCertificatesRead  APITokenScope = "certificates:read"
+	ScopeCertificatesWrite APITokenScope = "certificates:write"
+	ScopeHolidaysRead      APITokenScope = "holidays:read"
+	ScopeHolidaysWrite     APITokenScope = "holidays:write"
+	ScopeCommentsRead      APITokenScope = "comments:read"
+	ScopeCommentsWrite     APITokenScope = "comments:write"
+	ScopeShiftsRead        APITokenScope = "shifts:read"
+	ScopeShiftsWrite       APITokenScope = "shifts:write"
+	ScopeEscalaRead        APITokenScope = "escala:read"
+	ScopeEscalaWrite       APITokenScope = "escala:write"
+	ScopeReportsRead       APITokenScope = "reports:read"
+	ScopeSitesRead         APITokenScope = "sites:read"
+	ScopeSitesWrite        APITokenScope = "sites:write"
+	ScopeRulesRead         APITokenScope = "rules:read"
+	ScopeRulesWrite        APITokenScope = "rules:write"
+	ScopeAuditRead         APITokenScope = "audit:read"
+)
+
+var AllAPITokenScopes = []APITokenScope{
+	ScopeUsersRead, ScopeUsersWrite,
+	ScopeSwapsRead, ScopeSwapsWrite,
+	ScopeCertificatesRead, ScopeCertificatesWrite,
+	ScopeHolidaysRead, ScopeHolidaysWrite,
+	ScopeCommentsRead, ScopeCommentsWrite,
+	ScopeShiftsRead, ScopeShiftsWrite,
+	ScopeEscalaRead, ScopeEscalaWrite,
+	ScopeReportsRead,
+	ScopeSitesRead, ScopeSitesWrite,
+	ScopeRulesRead, ScopeRulesWrite,
+	ScopeAuditRead,
+}
+
+func (s APITokenScope) IsValid() bool {
+	for _, scope := range AllAPITokenScopes {
+		if scope == s {
+			return true
+		}
+	}
+	return false
+}
+
+type APIToken struct {
+	gorm.Model
+	UserID     uint   `gorm:"not null;index"`
+	Name       string `gorm:"type:varchar(100);not null"`
+	Prefix     string `gorm:"type:varchar(32);not null"`
+	SecretHash string `gorm:"type:varchar(64);uniqueIndex;not null"`
+	Scopes     string `gorm:"type:varchar(500);not null"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+}
@@ -0,0 +1,21 @@
+package entity
+
+import "gorm.io/gorm"
+
+type AuditAction string
+
+const (
+	AuditActionRuleOverride AuditAction = "rule_override"
+)
+
+// AuditEntry records an action that must be accountable later: who did it,
+// to which record, why, and what it involved.
+type AuditEntry struct {
+	gorm.Model
+	ActorID    uint        `gorm:"not null;index"`
+	Action     AuditAction `gorm:"type:varchar(50);not null;index"`
+	EntityType string      `gorm:"type:varchar(50);not null;index:idx_audit_entity"`
+	EntityID   uint        `gorm:"not null;index:idx_audit_entity"`
+	Reason     string      `gorm:"type:text"`
+	Details    string      `gorm:"type:text"`
+}
@@ -9,18 +9,35 @@ import (
 type CertificateStatus string
 
 const (
-	CertificateStatusPending  CertificateStatus = "pending"
-	CertificateStatusApproved CertificateStatus = "approved"
-	CertificateStatusRejected CertificateStatus = "rejected"
+	CertificateStatusPending   CertificateStatus = "pending"
+	CertificateStatusApproved  CertificateStatus = "approved"
+	CertificateStatusRejected  CertificateStatus = "rejected"
+	CertificateStatusCancelled CertificateStatus = "cancelled"
+)
+
+type CertificateType string
+
+const (
+	CertificateTypeMedical     CertificateType = "medical"
+	CertificateTypeVacation    CertificateType = "vacation"
+	CertificateTypeTraining    CertificateType = "training"
+	CertificateTypeBereavement CertificateType = "bereavement"
+	CertificateTypeUnpaidLeave CertificateType = "unpaid_leave"
 )
 
 type Certificate struct {
 	gorm.Model
 	CollaboratorID uint              `gorm:"not null;index"`
+	Type           CertificateType   `gorm:"type:varchar(30);default:'medical';not null;index"`
 	StartDate      time.Time         `gorm:"type:date;not null"`
 	EndDate        time.Time         `gorm:"type:date;not null"`
 	Reason         string            `gorm:"type:text;not null"`
 	Status         CertificateStatus `gorm:"type:varchar(20);default:'pending';not null;index"`
 	ApprovedByID   *uint
 	ApprovedAt     *time.Time
+
+	AttachmentKey         string `gorm:"type:varchar(255)"`
+	AttachmentName        string `gorm:"type:varchar(255)"`
+	AttachmentContentType string `gorm:"type:varchar(100)"`
+	AttachmentSize        int64
 }
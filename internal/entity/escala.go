@@ -0,0 +1,60 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type EscalaStatus string
+
+const (
+	EscalaStatusDraft     EscalaStatus = "draft"
+	EscalaStatusPublished EscalaStatus = "published"
+	EscalaStatusArchived  EscalaStatus = "archived"
+)
+
+type EscalaEntryKind string
+
+const (
+	EscalaEntryWork    EscalaEntryKind = "work"
+	EscalaEntryDayOff  EscalaEntryKind = "day_off"
+	EscalaEntryHoliday EscalaEntryKind = "holiday"
+	EscalaEntryAbsence EscalaEntryKind = "absence"
+)
+
+// EscalaVersion is one revision of a team's schedule for a month, e.g.
+// "2026-11". Versions are numbered per team and month. Only drafts can be
+// edited; once a version is published the month is locked and changes go
+// through swap requests.
+type EscalaVersion struct {
+	gorm.Model
+	Team          TeamName     `gorm:"type:varchar(50);not null;uniqueIndex:idx_escala_team_month_version"`
+	Month         string       `gorm:"type:char(7);not null;uniqueIndex:idx_escala_team_month_version"`
+	Version       int          `gorm:"not null;uniqueIndex:idx_escala_team_month_version"`
+	Status        EscalaStatus `gorm:"type:varchar(20);default:'draft';not null;index"`
+	CreatedByID   uint         `gorm:"not null"`
+	PublishedByID *uint
+	PublishedAt   *time.Time
+	Warnings      string        `gorm:"type:text"`
+	Entries       []EscalaEntry `gorm:"foreignKey:VersionID"`
+}
+
+// EscalaEntry is the assignment of one collaborator on one day. Shift is
+// only set for work entries.
+type EscalaEntry struct {
+	gorm.Model
+	VersionID uint            `gorm:"not null;index"`
+	UserID    uint            `gorm:"not null;index"`
+	Date      time.Time       `gorm:"type:date;not null"`
+	Kind      EscalaEntryKind `gorm:"type:varchar(20);not null"`
+	Shift     ShiftName       `gorm:"type:varchar(20)"`
+}
+
+func (k EscalaEntryKind) IsValid() bool {
+	switch k {
+	case EscalaEntryWork, EscalaEntryDayOff, EscalaEntryHoliday, EscalaEntryAbsence:
+		return true
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -14,9 +15,144 @@ const (
 	HolidayTypeCity     HolidayType = "city"
 )
 
+// HolidayRecurrence tells how a holiday repeats: never, on the same day and
+// month every year, or at a fixed distance from Easter Sunday.
+type HolidayRecurrence string
+
+const (
+	HolidayRecurrenceNone   HolidayRecurrence = ""
+	HolidayRecurrenceYearly HolidayRecurrence = "yearly"
+	HolidayRecurrenceEaster HolidayRecurrence = "easter"
+)
+
+func (r HolidayRecurrence) IsValid() bool {
+	switch r {
+	case HolidayRecurrenceNone, HolidayRecurrenceYearly, HolidayRecurrenceEaster:
+		return true
+	}
+	return false
+}
+
+// HolidayPolicy says whether collaborators work on holidays: off gives
+// everyone the day off, worked_normal keeps the regular schedule and
+// worked_premium keeps it but pays holiday hours at the premium rate.
+type HolidayPolicy string
+
+const (
+	HolidayPolicyOff           HolidayPolicy = "off"
+	HolidayPolicyWorkedNormal  HolidayPolicy = "worked_normal"
+	HolidayPolicyWorkedPremium HolidayPolicy = "worked_premium"
+)
+
+func (p HolidayPolicy) IsValid() bool {
+	switch p {
+	case HolidayPolicyOff, HolidayPolicyWorkedNormal, HolidayPolicyWorkedPremium:
+		return true
+	}
+	return false
+}
+
+// TeamHolidayPolicy is the holiday policy of a team; shift templates may
+// override Policy. With the off policy and a DutyCount above zero, that
+// many collaborators take turns working each holiday on their own shift.
+type TeamHolidayPolicy struct {
+	Team      TeamName      `gorm:"type:varchar(50);primaryKey"`
+	Policy    HolidayPolicy `gorm:"type:varchar(20);not null"`
+	DutyCount int           `gorm:"not null;default:0"`
+	UpdatedAt time.Time
+}
+
+// DefaultTeamHolidayPolicy is used for teams without a stored policy.
+func DefaultTeamHolidayPolicy(team TeamName) TeamHolidayPolicy {
+	return TeamHolidayPolicy{Team: team, Policy: HolidayPolicyOff}
+}
+
+func (t HolidayType) IsValid() bool {
+	switch t {
+	case HolidayTypeNational, HolidayTypeState, HolidayTypeCity:
+		return true
+	}
+	return false
+}
+
+// Holiday is unique per date, type and location. State holidays name their
+// State and city holidays their State and City; national holidays have
+// neither. A recurring holiday is stored once, with Date as its first
+// occurrence; EasterOffset is the number of days from Easter Sunday of easter
+// recurrences. Optional holidays ("ponto facultativo") are only observed by
+// the teams that opted into them.
 type Holiday struct {
 	gorm.Model
-	Name string      `gorm:"type:varchar(100);not null"`
-	Date time.Time   `gorm:"type:date;not null;uniqueIndex"`
-	Type HolidayType `gorm:"type:varchar(20);not null"`
+	Name         string            `gorm:"type:varchar(100);not null"`
+	Date         time.Time         `gorm:"type:date;not null;uniqueIndex:idx_holidays_date_location"`
+	Type         HolidayType       `gorm:"type:varchar(20);not null;uniqueIndex:idx_holidays_date_location"`
+	State        string            `gorm:"type:char(2);not null;default:'';uniqueIndex:idx_holidays_date_location"`
+	City         string            `gorm:"type:varchar(100);not null;default:'';uniqueIndex:idx_holidays_date_location"`
+	Recurrence   HolidayRecurrence `gorm:"type:varchar(20);not null;default:''"`
+	EasterOffset int               `gorm:"not null;default:0"`
+	Optional     bool              `gorm:"not null;default:false"`
+}
+
+// TeamOptionalHoliday records that a team observes an optional holiday.
+type TeamOptionalHoliday struct {
+	Team      TeamName `gorm:"type:varchar(50);primaryKey"`
+	HolidayID uint     `gorm:"primaryKey"`
+	CreatedAt time.Time
+}
+
+// OccurrenceIn returns the date of the holiday in year, and false when it
+// does not happen that year: before its first occurrence, in another year
+// for holidays that don't recur, or on February 29 outside leap years.
+func (h *Holiday) OccurrenceIn(year int) (time.Time, bool) {
+	first := time.Date(h.Date.Year(), h.Date.Month(), h.Date.Day(), 0, 0, 0, 0, time.UTC)
+	if year < first.Year() {
+		return time.Time{}, false
+	}
+	switch h.Recurrence {
+	case HolidayRecurrenceYearly:
+		date := time.Date(year, first.Month(), first.Day(), 0, 0, 0, 0, time.UTC)
+		if date.Month() != first.Month() {
+			return time.Time{}, false
+		}
+		return date, true
+	case HolidayRecurrenceEaster:
+		return Easter(year).AddDate(0, 0, h.EasterOffset), true
+	}
+	if year != first.Year() {
+		return time.Time{}, false
+	}
+	return first, true
+}
+
+// Easter returns Easter Sunday of the given year in the Gregorian calendar
+// (anonymous Gregorian algorithm).
+func Easter(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// AppliesTo reports whether the holiday is observed at site. Holidays without
+// a state, national ones and those created before locations existed, apply
+// everywhere; the others only at a site in their state and city.
+func (h *Holiday) AppliesTo(site *Site) bool {
+	if h.State == "" {
+		return true
+	}
+	if site == nil || !strings.EqualFold(site.State, h.State) {
+		return false
+	}
+	return h.City == "" || strings.EqualFold(site.City, h.City)
 }
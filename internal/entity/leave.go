@@ -0,0 +1,41 @@
+package entity
+
+import "gorm.io/gorm"
+
+type LeaveTransactionKind string
+
+const (
+	LeaveTransactionAccrual  LeaveTransactionKind = "accrual"
+	LeaveTransactionDebit    LeaveTransactionKind = "debit"
+	LeaveTransactionReversal LeaveTransactionKind = "reversal"
+)
+
+// LeaveAccrualRule sets how many leave days a year collaborators of a team
+// accrue. An empty Position applies to every position of the team.
+type LeaveAccrualRule struct {
+	gorm.Model
+	Team        TeamName     `gorm:"type:varchar(50);not null;uniqueIndex:idx_accrual_team_position"`
+	Position    PositionName `gorm:"type:varchar(50);not null;default:'';uniqueIndex:idx_accrual_team_position"`
+	DaysPerYear int          `gorm:"not null"`
+}
+
+type LeaveBalance struct {
+	gorm.Model
+	UserID  uint `gorm:"not null;uniqueIndex:idx_balance_user_year"`
+	Year    int  `gorm:"not null;uniqueIndex:idx_balance_user_year"`
+	Accrued int  `gorm:"not null"`
+	Used    int  `gorm:"not null"`
+	Balance int  `gorm:"not null"`
+}
+
+// LeaveTransaction is a ledger entry; Days is positive for credits and
+// negative for debits.
+type LeaveTransaction struct {
+	gorm.Model
+	UserID        uint                 `gorm:"not null;index"`
+	Year          int                  `gorm:"not null;index"`
+	Kind          LeaveTransactionKind `gorm:"type:varchar(20);not null"`
+	Days          int                  `gorm:"not null"`
+	CertificateID *uint                `gorm:"index"`
+	Description   string               `gorm:"type:varchar(255)"`
+}
@@ -0,0 +1,43 @@
+package entity
+
+import "time"
+
+// RuleName identifies a labor rule the schedule is checked against.
+type RuleName string
+
+const (
+	RuleMinRest            RuleName = "min_rest"
+	RuleWeeklyRest         RuleName = "weekly_rest"
+	RuleMaxConsecutiveDays RuleName = "max_consecutive_days"
+	RuleMaxNightShifts     RuleName = "max_night_shifts"
+	RuleMaxSwaps           RuleName = "max_swaps"
+)
+
+// RuleSeverity tells what a violation does: block rejects the change, warn
+// lets it through with a warning and off disables the rule.
+type RuleSeverity string
+
+const (
+	RuleSeverityBlock RuleSeverity = "block"
+	RuleSeverityWarn  RuleSeverity = "warn"
+	RuleSeverityOff   RuleSeverity = "off"
+)
+
+func (s RuleSeverity) IsValid() bool {
+	switch s {
+	case RuleSeverityBlock, RuleSeverityWarn, RuleSeverityOff:
+		return true
+	}
+	return false
+}
+
+// TeamRuleConfig overrides the default severity and threshold of a rule for
+// a team. The threshold is in the unit of the rule: hours for the rest rules,
+// days, night shifts or swaps for the others.
+type TeamRuleConfig struct {
+	Team      TeamName     `gorm:"type:varchar(50);primaryKey"`
+	Rule      RuleName     `gorm:"type:varchar(40);primaryKey"`
+	Severity  RuleSeverity `gorm:"type:varchar(10);not null"`
+	Threshold int          `gorm:"not null"`
+	UpdatedAt time.Time
+}
@@ -0,0 +1,114 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	RotationWorkDay = 'W'
+	RotationDayOff  = 'O'
+)
+
+// ShiftTemplate defines the hours of a named shift. Users, swaps and escalas
+// reference templates by Name. A nil Team makes the template available to
+// every team. An empty HolidayPolicy follows the team's policy.
+type ShiftTemplate struct {
+	gorm.Model
+	Name            ShiftName     `gorm:"type:varchar(20);uniqueIndex;not null"`
+	StartTime       string        `gorm:"type:char(5);not null"`
+	EndTime         string        `gorm:"type:char(5);not null"`
+	CrossesMidnight bool          `gorm:"not null"`
+	Team            *TeamName     `gorm:"type:varchar(50);index"`
+	HolidayPolicy   HolidayPolicy `gorm:"type:varchar(20);not null;default:''"`
+}
+
+// RotationPattern is a repeating cycle of work days ('W') and days off ('O'),
+// e.g. "WO" for 12x36 or "WWWWWOO" for 5x2.
+type RotationPattern struct {
+	gorm.Model
+	Name        string `gorm:"type:varchar(50);uniqueIndex;not null"`
+	Sequence    string `gorm:"type:varchar(64);not null"`
+	Description string `gorm:"type:varchar(255)"`
+}
+
+// Window returns the start and end instants of the shift worked on date.
+func (t *ShiftTemplate) Window(date time.Time) (time.Time, time.Time) {
+	start, _ := ParseClock(t.StartTime)
+	end, _ := ParseClock(t.EndTime)
+	if t.CrossesMidnight {
+		end += 24 * time.Hour
+	}
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	return day.Add(start), day.Add(end)
+}
+
+func (t *ShiftTemplate) Duration() time.Duration {
+	start, end := t.Window(time.Time{})
+	return end.Sub(start)
+}
+
+func (t *ShiftTemplate) AvailableTo(team TeamName) bool {
+	return t.Team == nil || *t.Team == team
+}
+
+// IsWorkDay reports whether the pattern, started on anchor, is a work day on
+// date. Dates before the anchor continue the cycle backwards.
+func (p *RotationPattern) IsWorkDay(anchor, date time.Time) bool {
+	if len(p.Sequence) == 0 {
+		return true
+	}
+	anchorDay := time.Date(anchor.Year(), anchor.Month(), anchor.Day(), 0, 0, 0, 0, time.UTC)
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	offset := int(day.Sub(anchorDay).Hours() / 24)
+	n := len(p.Sequence)
+	return p.Sequence[((offset%n)+n)%n] == RotationWorkDay
+}
+
+func (p *RotationPattern) Validate() error {
+	if len(p.Sequence) == 0 {
+		return fmt.Errorf("sequence must not be empty")
+	}
+	if strings.Trim(p.Sequence, string([]rune{RotationWorkDay, RotationDayOff})) != "" {
+		return fmt.Errorf("sequence must only contain '%c' and '%c'", RotationWorkDay, RotationDayOff)
+	}
+	if !strings.ContainsRune(p.Sequence, RotationWorkDay) {
+		return fmt.Errorf("sequence must contain at least one work day")
+	}
+	return nil
+}
+
+// ParseClock parses an "HH:MM" time of day.
+func ParseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, use HH:MM", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// NewShiftTemplateFromName builds a template from a legacy "HH:MM-HH:MM"
+// shift name such as ShiftMorning.
+func NewShiftTemplateFromName(name ShiftName) (*ShiftTemplate, error) {
+	parts := strings.SplitN(string(name), "-", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("shift %q is not in HH:MM-HH:MM format", name)
+	}
+	start, err := ParseClock(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	end, err := ParseClock(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	return &ShiftTemplate{
+		Name:            name,
+		StartTime:       parts[0],
+		EndTime:         parts[1],
+		CrossesMidnight: end <= start,
+	}, nil
+}
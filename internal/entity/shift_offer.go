@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ShiftOfferStatus string
+
+const (
+	ShiftOfferOpen      ShiftOfferStatus = "open"
+	ShiftOfferClaimed   ShiftOfferStatus = "claimed"
+	ShiftOfferCancelled ShiftOfferStatus = "cancelled"
+)
+
+// ShiftOffer is a shift a collaborator gives away on the marketplace. A
+// colleague claiming it opens a pending handover swap (SwapID); the offer
+// opens again if that swap is rejected or withdrawn.
+type ShiftOffer struct {
+	gorm.Model
+	OffererID   uint             `gorm:"not null;index"`
+	Team        TeamName         `gorm:"type:varchar(50);not null;index"`
+	Date        time.Time        `gorm:"type:date;not null;index"`
+	Shift       ShiftName        `gorm:"type:varchar(20);not null"`
+	Note        string           `gorm:"type:text"`
+	Status      ShiftOfferStatus `gorm:"type:varchar(20);default:'open';not null;index"`
+	ClaimedByID *uint
+	SwapID      *uint `gorm:"index"`
+}
@@ -0,0 +1,24 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Site is a workplace location. State and city holidays apply to the
+// collaborators working at a site in that state or city.
+type Site struct {
+	gorm.Model
+	Name  string `gorm:"type:varchar(100);uniqueIndex;not null"`
+	State string `gorm:"type:char(2);not null"`
+	City  string `gorm:"type:varchar(100);not null"`
+}
+
+// TeamSite is the site of the collaborators of a team who have no site of
+// their own.
+type TeamSite struct {
+	Team      TeamName `gorm:"type:varchar(50);primaryKey"`
+	SiteID    uint     `gorm:"not null;index"`
+	UpdatedAt time.Time
+}
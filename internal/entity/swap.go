@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -14,6 +15,14 @@ const (
 	StatusRejected SwapStatus = "rejected"
 )
 
+// Swap changes the days of the requester and, optionally, an involved
+// collaborator. The requester is off on NewDate and works NewShift on
+// OriginalDate; the involved collaborator works NewShift on NewDate and is off
+// on OriginalDate. A swap with ShiftOfferID is a handover from a claimed shift
+// offer: the requester is off on OriginalDate and the involved collaborator
+// works OriginalShift in their place, with nothing in return. OverriddenRules
+// lists, comma separated, the blocking labor rules a master overrode to
+// approve the swap, for the reason in OverrideReason.
 type Swap struct {
 	gorm.Model
 	RequesterID            uint       `gorm:"not null;index"`
@@ -26,4 +35,61 @@ type Swap struct {
 	Status                 SwapStatus `gorm:"type:varchar(20);default:'pending';not null;index"`
 	ApprovedByID           *uint
 	ApprovedAt             *time.Time
+	ShiftOfferID           *uint  `gorm:"index"`
+	OverriddenRules        string `gorm:"type:varchar(255);not null;default:''"`
+	OverrideReason         string `gorm:"type:text"`
+}
+
+// Effect tells how the swap changes the day of userID on date: whether it
+// decides the day at all and, if so, the shift worked, if any.
+func (s *Swap) Effect(userID uint, date time.Time) (ShiftName, bool, bool) {
+	onOriginal := sameDate(s.OriginalDate, date)
+	onNew := sameDate(s.NewDate, date)
+	involved := s.InvolvedCollaboratorID != nil && *s.InvolvedCollaboratorID == userID
+	switch {
+	case s.IsHandover():
+		if onOriginal && userID == s.RequesterID {
+			return "", false, true
+		}
+		if onOriginal && involved {
+			return s.OriginalShift, true, true
+		}
+	case userID == s.RequesterID:
+		if onNew {
+			return "", false, true
+		}
+		if onOriginal {
+			return s.NewShift, true, true
+		}
+	case involved:
+		if onNew {
+			return s.NewShift, true, true
+		}
+		if onOriginal {
+			return "", false, true
+		}
+	}
+	return "", false, false
+}
+
+func sameDate(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.Month() == b.Month() && a.Day() == b.Day()
+}
+
+// Overrides returns the labor rules overridden to approve the swap.
+func (s *Swap) Overrides() []RuleName {
+	if s.OverriddenRules == "" {
+		return nil
+	}
+	var names []RuleName
+	for _, name := range strings.Split(s.OverriddenRules, ",") {
+		names = append(names, RuleName(name))
+	}
+	return names
+}
+
+// IsHandover reports whether the swap hands a shift over without a shift in
+// return.
+func (s *Swap) IsHandover() bool {
+	return s.ShiftOfferID != nil
 }
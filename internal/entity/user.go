@@ -30,6 +30,8 @@ const (
 	PositionAttendant    PositionName = "Attendant"
 	PositionMaster       PositionName = "Master"
 
+	// Default shift templates seeded on startup; other shifts are defined in
+	// the shift_templates table.
 	ShiftMorning   ShiftName   = "06:00-14:00"
 	ShiftAfternoon ShiftName   = "14:00-22:00"
 	ShiftNight     ShiftName   = "22:00-06:00"
@@ -58,6 +60,17 @@ type User struct {
 	WeekdayOff        WeekdayName    `gorm:"type:varchar(20)"`
 	InitialWeekendOff WeekendDayName `gorm:"type:varchar(20)"`
 	SuperiorID        *uint          `gorm:"index"`
+	RotationPatternID *uint          `gorm:"index"`
+	SiteID            *uint          `gorm:"index"`
+	RotationAnchor    *time.Time     `gorm:"type:date"`
+	// WeekendRotationAnchor is a weekend day the user has off; weekends an
+	// even number of weeks away have the same day off, the others the
+	// opposite one.
+	WeekendRotationAnchor *time.Time `gorm:"type:date"`
+	// LegacyWeekendCount marks anchors backfilled from the creation date.
+	// They keep counting weeks from the anchor day itself, as the rotation
+	// did before anchors were stored.
+	LegacyWeekendCount bool `gorm:"not null;default:false"`
 }
 
 func (u *User) HashPassword() error {
@@ -73,3 +86,88 @@ func (u *User) CheckPasswordHash(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
 	return err == nil
 }
+
+func (t UserType) IsValid() bool {
+	return t == UserTypeMaster || t == UserTypeCollaborator
+}
+
+func (t TeamName) IsValid() bool {
+	switch t {
+	case TeamSecurity, TeamSupport, TeamCustomerService:
+		return true
+	}
+	return false
+}
+
+func (w WeekdayName) IsValid() bool {
+	switch w {
+	case WeekdayMonday, WeekdayTuesday, WeekdayWednesday, WeekdayThursday, WeekdayFriday:
+		return true
+	}
+	return false
+}
+
+func (w WeekendDayName) IsValid() bool {
+	return w == WeekendSaturday || w == WeekendSunday
+}
+
+// FirstWeekendOff returns the first occurrence of day on or after from.
+func FirstWeekendOff(from time.Time, day WeekendDayName) time.Time {
+	target := time.Sunday
+	if day == WeekendSaturday {
+		target = time.Saturday
+	}
+	date := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	for date.Weekday() != target {
+		date = date.AddDate(0, 0, 1)
+	}
+	return date
+}
+
+// IsWeekendDayOff reports whether date is the user's day off in the
+// alternating weekend rotation. Users without an explicit anchor count from
+// their creation date and have no weekend off before it, as do anchors with
+// LegacyWeekendCount.
+func (u *User) IsWeekendDayOff(date time.Time) bool {
+	if date.Weekday() != time.Saturday && date.Weekday() != time.Sunday {
+		return false
+	}
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	var anchor time.Time
+	legacy := u.LegacyWeekendCount
+	switch {
+	case u.WeekendRotationAnchor != nil:
+		anchor = *u.WeekendRotationAnchor
+	case u.InitialWeekendOff != "":
+		anchor = FirstWeekendOff(u.CreatedAt, u.InitialWeekendOff)
+		legacy = true
+	default:
+		return false
+	}
+
+	var weeks int
+	if legacy {
+		// The legacy rotation counts whole weeks from the anchor day, so a
+		// sunday anchor pairs each sunday with the following saturday.
+		anchor = time.Date(anchor.Year(), anchor.Month(), anchor.Day(), 0, 0, 0, 0, time.UTC)
+		if day.Before(anchor) {
+			return false
+		}
+		weeks = int(day.Sub(anchor).Hours()/24) / 7
+	} else {
+		weeks = int(weekendStart(day).Sub(weekendStart(anchor)).Hours() / 24 / 7)
+	}
+	if weeks%2 == 0 {
+		return day.Weekday() == anchor.Weekday()
+	}
+	return day.Weekday() != anchor.Weekday()
+}
+
+// weekendStart returns the Saturday of the weekend date belongs to.
+func weekendStart(date time.Time) time.Time {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	if day.Weekday() == time.Sunday {
+		return day.AddDate(0, 0, -1)
+	}
+	return day
+}
@@ -0,0 +1,72 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WorkAssignment records the work data of a user for a period. ValidTo is
+// nil for the assignment currently in effect. The matching fields on User
+// always mirror the current assignment.
+type WorkAssignment struct {
+	gorm.Model
+	UserID                uint           `gorm:"not null;index:idx_assignment_user_period"`
+	Team                  TeamName       `gorm:"type:varchar(50)"`
+	Position              PositionName   `gorm:"type:varchar(50)"`
+	Shift                 ShiftName      `gorm:"type:varchar(20)"`
+	WeekdayOff            WeekdayName    `gorm:"type:varchar(20)"`
+	InitialWeekendOff     WeekendDayName `gorm:"type:varchar(20)"`
+	RotationPatternID     *uint
+	RotationAnchor        *time.Time `gorm:"type:date"`
+	WeekendRotationAnchor *time.Time `gorm:"type:date"`
+	LegacyWeekendCount    bool       `gorm:"not null;default:false"`
+	SuperiorID            *uint
+	ValidFrom             time.Time  `gorm:"type:date;not null;index:idx_assignment_user_period"`
+	ValidTo               *time.Time `gorm:"type:date"`
+	CreatedByID           *uint
+}
+
+// NewWorkAssignment captures the current work data of user starting on
+// validFrom.
+func NewWorkAssignment(user *User, validFrom time.Time) WorkAssignment {
+	return WorkAssignment{
+		UserID:                user.ID,
+		Team:                  user.Team,
+		Position:              user.Position,
+		Shift:                 user.Shift,
+		WeekdayOff:            user.WeekdayOff,
+		InitialWeekendOff:     user.InitialWeekendOff,
+		RotationPatternID:     user.RotationPatternID,
+		RotationAnchor:        user.RotationAnchor,
+		WeekendRotationAnchor: user.WeekendRotationAnchor,
+		LegacyWeekendCount:    user.LegacyWeekendCount,
+		SuperiorID:            user.SuperiorID,
+		ValidFrom:             validFrom,
+	}
+}
+
+// Covers reports whether the assignment is in effect on date.
+func (a *WorkAssignment) Covers(date time.Time) bool {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	if day.Before(a.ValidFrom) {
+		return false
+	}
+	return a.ValidTo == nil || !day.After(*a.ValidTo)
+}
+
+// ApplyTo returns a copy of user carrying the work data of the assignment.
+func (a *WorkAssignment) ApplyTo(user *User) *User {
+	applied := *user
+	applied.Team = a.Team
+	applied.Position = a.Position
+	applied.Shift = a.Shift
+	applied.WeekdayOff = a.WeekdayOff
+	applied.InitialWeekendOff = a.InitialWeekendOff
+	applied.RotationPatternID = a.RotationPatternID
+	applied.RotationAnchor = a.RotationAnchor
+	applied.WeekendRotationAnchor = a.WeekendRotationAnchor
+	applied.LegacyWeekendCount = a.LegacyWeekendCount
+	applied.SuperiorID = a.SuperiorID
+	return &applied
+}
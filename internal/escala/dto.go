@@ -0,0 +1,188 @@
+package escala
+
+import (
+	"escala-fds-api/internal/constants"
+	"escala-fds-api/internal/entity"
+	"strconv"
+	"strings"
+)
+
+type GenerateRequest struct {
+	// MinimumStaffing is the number of collaborators required on each shift
+	// every day, keyed by shift template name.
+	MinimumStaffing map[entity.ShiftName]int `json:"minimumStaffing" binding:"omitempty,dive,min=0,max=100"`
+}
+
+type DayResponse struct {
+	Date  string                 `json:"date"`
+	Kind  entity.EscalaEntryKind `json:"kind"`
+	Shift entity.ShiftName       `json:"shift,omitempty"`
+}
+
+type RowResponse struct {
+	UserID      uint                `json:"userId"`
+	Name        string              `json:"name"`
+	Position    entity.PositionName `json:"position"`
+	WorkingDays int                 `json:"workingDays"`
+	Days        []DayResponse       `json:"days"`
+}
+
+type EscalaResponse struct {
+	ID          uint                `json:"id"`
+	Team        entity.TeamName     `json:"team"`
+	Month       string              `json:"month"`
+	Version     int                 `json:"version"`
+	Status      entity.EscalaStatus `json:"status"`
+	CreatedByID uint                `json:"createdById"`
+	CreatedAt   string              `json:"createdAt"`
+	PublishedAt string              `json:"publishedAt,omitempty"`
+	Warnings    []string            `json:"warnings"`
+	Rows        []RowResponse       `json:"rows"`
+}
+
+// ToEscalaResponse groups the version entries per collaborator, in the order
+// of users.
+func ToEscalaResponse(version *entity.EscalaVersion, users []entity.User) EscalaResponse {
+	rows := make(map[uint]*RowResponse, len(users))
+	ordered := make([]*RowResponse, 0, len(users))
+	for _, u := range users {
+		row := &RowResponse{UserID: u.ID, Name: u.FirstName + " " + u.LastName, Position: u.Position, Days: []DayResponse{}}
+		rows[u.ID] = row
+		ordered = append(ordered, row)
+	}
+	for _, entry := range version.Entries {
+		row, ok := rows[entry.UserID]
+		if !ok {
+			row = &RowResponse{UserID: entry.UserID, Days: []DayResponse{}}
+			rows[entry.UserID] = row
+			ordered = append(ordered, row)
+		}
+		if entry.Kind == entity.EscalaEntryWork {
+			row.WorkingDays++
+		}
+		row.Days = append(row.Days, DayResponse{
+			Date:  entry.Date.Format(constants.ApiDateLayout),
+			Kind:  entry.Kind,
+			Shift: entry.Shift,
+		})
+	}
+
+	var publishedAt string
+	if version.PublishedAt != nil {
+		publishedAt = version.PublishedAt.Format(constants.ApiTimestampLayout)
+	}
+	response := EscalaResponse{
+		ID:          version.ID,
+		Team:        version.Team,
+		Month:       version.Month,
+		Version:     version.Version,
+		Status:      version.Status,
+		CreatedByID: version.CreatedByID,
+		CreatedAt:   version.CreatedAt.Format(constants.ApiTimestampLayout),
+		PublishedAt: publishedAt,
+		Warnings:    splitWarnings(version.Warnings),
+		Rows:        make([]RowResponse, 0, len(ordered)),
+	}
+	for _, row := range ordered {
+		response.Rows = append(response.Rows, *row)
+	}
+	return response
+}
+
+func splitWarnings(warnings string) []string {
+	if warnings == "" {
+		return []string{}
+	}
+	return strings.Split(warnings, "\n")
+}
+
+type EntryRequest struct {
+	UserID uint                   `json:"userId" binding:"required"`
+	Date   string                 `json:"date" binding:"required,apidate"`
+	Kind   entity.EscalaEntryKind `json:"kind" binding:"required,oneof=work day_off holiday absence"`
+	Shift  entity.ShiftName       `json:"shift" binding:"omitempty,shift"`
+}
+
+type UpdateEntriesRequest struct {
+	Entries []EntryRequest `json:"entries" binding:"required,min=1,dive"`
+}
+
+type VersionSummaryResponse struct {
+	ID            uint                `json:"id"`
+	Version       int                 `json:"version"`
+	Status        entity.EscalaStatus `json:"status"`
+	CreatedByID   uint                `json:"createdById"`
+	CreatedAt     string              `json:"createdAt"`
+	UpdatedAt     string              `json:"updatedAt"`
+	PublishedByID *uint               `json:"publishedById,omitempty"`
+	PublishedAt   string              `json:"publishedAt,omitempty"`
+}
+
+type DayState struct {
+	Kind  entity.EscalaEntryKind `json:"kind,omitempty"`
+	Shift entity.ShiftName       `json:"shift,omitempty"`
+}
+
+type ChangeResponse struct {
+	UserID uint     `json:"userId"`
+	Name   string   `json:"name"`
+	Date   string   `json:"date"`
+	From   DayState `json:"from"`
+	To     DayState `json:"to"`
+}
+
+type DiffResponse struct {
+	Team    entity.TeamName  `json:"team"`
+	Month   string           `json:"month"`
+	From    int              `json:"from"`
+	To      int              `json:"to"`
+	Changes []ChangeResponse `json:"changes"`
+}
+
+func ToVersionSummaryResponse(version *entity.EscalaVersion) VersionSummaryResponse {
+	var publishedAt string
+	if version.PublishedAt != nil {
+		publishedAt = version.PublishedAt.Format(constants.ApiTimestampLayout)
+	}
+	return VersionSummaryResponse{
+		ID:            version.ID,
+		Version:       version.Version,
+		Status:        version.Status,
+		CreatedByID:   version.CreatedByID,
+		CreatedAt:     version.CreatedAt.Format(constants.ApiTimestampLayout),
+		UpdatedAt:     version.UpdatedAt.Format(constants.ApiTimestampLayout),
+		PublishedByID: version.PublishedByID,
+		PublishedAt:   publishedAt,
+	}
+}
+
+// exportTable lays the escala out as a roster: one row per collaborator and
+// one column per day, holding the shift or the kind of day off.
+func (r *EscalaResponse) exportTable() ([]string, [][]string) {
+	header := []string{"userId", "name", "position"}
+	var dates []string
+	if len(r.Rows) > 0 {
+		for _, day := range r.Rows[0].Days {
+			dates = append(dates, day.Date)
+		}
+	}
+	header = append(header, dates...)
+
+	rows := make([][]string, 0, len(r.Rows))
+	for _, row := range r.Rows {
+		cells := make(map[string]string, len(row.Days))
+		for _, day := range row.Days {
+			if day.Kind == entity.EscalaEntryWork {
+				cells[day.Date] = string(day.Shift)
+			} else {
+				cells[day.Date] = string(day.Kind)
+			}
+		}
+		values := []string{strconv.FormatUint(uint64(row.UserID), 10), row.Name, string(row.Position)}
+		for _, date := range dates {
+			values = append(values, cells[date])
+		}
+		rows = append(rows, values)
+	}
+	return header, rows
+}
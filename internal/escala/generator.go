@@ -0,0 +1,241 @@
+package escala
+
+import (
+	"escala-fds-api/internal/constants"
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/rules"
+	"escala-fds-api/internal/schedule"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// member holds a collaborator's inputs and running totals while a month is
+// being generated.
+type member struct {
+	user     *entity.User
+	template *entity.ShiftTemplate
+	regular  map[string]bool
+	absent   map[string]bool
+	holidays map[string]bool
+	target   int
+
+	assigned    int
+	weekends    int
+	nights      int
+	consecutive int
+	lastEnd     time.Time
+}
+
+func (m *member) remaining() int {
+	return m.target - m.assigned
+}
+
+// generator assigns shifts day by day. For every day it first fills the
+// minimum staffing of each shift, picking the eligible collaborators with the
+// fewest weekends or nights worked so far, and then lets everyone else follow
+// their regular schedule until they reach their expected number of days.
+// Nobody is assigned a shift that breaks a blocking labor rule of the team;
+// the other rules are checked once the month is done.
+type generator struct {
+	month     time.Time
+	members   []*member
+	templates map[entity.ShiftName]*entity.ShiftTemplate
+	staffing  map[entity.ShiftName]int
+	config    rules.Config
+
+	entries  []entity.EscalaEntry
+	warnings []string
+}
+
+func (g *generator) run() {
+	end := g.month.AddDate(0, 1, -1)
+	for date := g.month; !date.After(end); date = date.AddDate(0, 0, 1) {
+		key := date.Format(constants.ApiDateLayout)
+		daysLeft := int(end.Sub(date).Hours()/24) + 1
+
+		assigned := make(map[uint]bool, len(g.members))
+		for _, m := range g.members {
+			switch {
+			case m.holidays[key]:
+				g.rest(m, date, entity.EscalaEntryHoliday)
+				assigned[m.user.ID] = true
+			case m.absent[key]:
+				g.rest(m, date, entity.EscalaEntryAbsence)
+				assigned[m.user.ID] = true
+			}
+		}
+		// Nobody is expected at work on a holiday observed by the whole team.
+		if g.teamHoliday(key) {
+			continue
+		}
+
+		for _, name := range g.staffingOrder() {
+			template := g.templates[name]
+			needed := g.staffing[name]
+			candidates := g.candidates(template, date, assigned)
+			for _, m := range candidates {
+				if needed == 0 {
+					break
+				}
+				g.work(m, template, date)
+				assigned[m.user.ID] = true
+				needed--
+			}
+			if needed > 0 {
+				g.warnings = append(g.warnings, fmt.Sprintf("%s %s: %d of %d collaborators scheduled",
+					key, name, g.staffing[name]-needed, g.staffing[name]))
+			}
+		}
+
+		for _, m := range g.members {
+			if assigned[m.user.ID] {
+				continue
+			}
+			wantsWork := m.regular[key] || m.remaining() >= daysLeft
+			if m.template != nil && m.remaining() > 0 && wantsWork && g.canWork(m, m.template, date) {
+				g.work(m, m.template, date)
+			} else {
+				g.rest(m, date, entity.EscalaEntryDayOff)
+			}
+		}
+	}
+
+	for _, m := range g.members {
+		if m.remaining() > 0 {
+			g.warnings = append(g.warnings, fmt.Sprintf("%s %s: %d of %d expected working days scheduled",
+				m.user.FirstName, m.user.LastName, m.assigned, m.target))
+		}
+	}
+	g.checkRules()
+}
+
+// checkRules evaluates the labor rules on the month generated for each
+// member and adds the breaches to the warnings.
+func (g *generator) checkRules() {
+	for _, m := range g.members {
+		timeline := &rules.Timeline{}
+		for _, entry := range g.entries {
+			if entry.UserID != m.user.ID {
+				continue
+			}
+			day := schedule.Day{Date: entry.Date, Shift: entry.Shift, Working: entry.Kind == entity.EscalaEntryWork}
+			timeline.Days = append(timeline.Days, day)
+			if !day.Working {
+				continue
+			}
+			start, end := g.templates[entry.Shift].Window(entry.Date)
+			timeline.Spans = append(timeline.Spans, rules.Span{Date: entry.Date, Start: start, End: end})
+			timeline.Focus = append(timeline.Focus, entry.Date)
+		}
+		for _, v := range rules.Evaluate(timeline, g.config, "") {
+			g.warnings = append(g.warnings, fmt.Sprintf("%s %s: %s", m.user.FirstName, m.user.LastName, v.Message))
+		}
+	}
+}
+
+func (g *generator) teamHoliday(key string) bool {
+	for _, m := range g.members {
+		if !m.holidays[key] {
+			return false
+		}
+	}
+	return len(g.members) > 0
+}
+
+// staffingOrder returns the shifts with a minimum staffing, night shifts
+// first since they have the fewest eligible collaborators.
+func (g *generator) staffingOrder() []entity.ShiftName {
+	names := make([]entity.ShiftName, 0, len(g.staffing))
+	for name, count := range g.staffing {
+		if count > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := g.templates[names[i]], g.templates[names[j]]
+		if a.CrossesMidnight != b.CrossesMidnight {
+			return a.CrossesMidnight
+		}
+		return a.StartTime < b.StartTime
+	})
+	return names
+}
+
+func (g *generator) candidates(template *entity.ShiftTemplate, date time.Time, assigned map[uint]bool) []*member {
+	var candidates []*member
+	for _, m := range g.members {
+		if assigned[m.user.ID] || !template.AvailableTo(m.user.Team) || !g.canWork(m, template, date) {
+			continue
+		}
+		candidates = append(candidates, m)
+	}
+	weekend := isWeekend(date)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if (a.remaining() > 0) != (b.remaining() > 0) {
+			return a.remaining() > 0
+		}
+		if (a.user.Shift == template.Name) != (b.user.Shift == template.Name) {
+			return a.user.Shift == template.Name
+		}
+		if weekend && a.weekends != b.weekends {
+			return a.weekends < b.weekends
+		}
+		if template.CrossesMidnight && a.nights != b.nights {
+			return a.nights < b.nights
+		}
+		if a.assigned != b.assigned {
+			return a.assigned < b.assigned
+		}
+		return a.user.ID < b.user.ID
+	})
+	return candidates
+}
+
+// canWork reports whether assigning the shift keeps the blocking rules.
+func (g *generator) canWork(m *member, template *entity.ShiftTemplate, date time.Time) bool {
+	if g.config.Blocks(entity.RuleMaxConsecutiveDays) && m.consecutive >= g.config.For(entity.RuleMaxConsecutiveDays).Threshold {
+		return false
+	}
+	if template.CrossesMidnight && g.config.Blocks(entity.RuleMaxNightShifts) && m.nights >= g.config.For(entity.RuleMaxNightShifts).Threshold {
+		return false
+	}
+	if !g.config.Blocks(entity.RuleMinRest) || m.lastEnd.IsZero() {
+		return true
+	}
+	start, _ := template.Window(date)
+	return start.Sub(m.lastEnd) >= time.Duration(g.config.For(entity.RuleMinRest).Threshold)*time.Hour
+}
+
+func (g *generator) work(m *member, template *entity.ShiftTemplate, date time.Time) {
+	_, end := template.Window(date)
+	m.assigned++
+	m.consecutive++
+	m.lastEnd = end
+	if isWeekend(date) {
+		m.weekends++
+	}
+	if template.CrossesMidnight {
+		m.nights++
+	}
+	g.entries = append(g.entries, entity.EscalaEntry{
+		UserID: m.user.ID,
+		Date:   date,
+		Kind:   entity.EscalaEntryWork,
+		Shift:  template.Name,
+	})
+}
+
+func (g *generator) rest(m *member, date time.Time, kind entity.EscalaEntryKind) {
+	m.consecutive = 0
+	g.entries = append(g.entries, entity.EscalaEntry{
+		UserID: m.user.ID,
+		Date:   date,
+		Kind:   kind,
+	})
+}
+
+func isWeekend(date time.Time) bool {
+	return date.Weekday() == time.Saturday || date.Weekday() == time.Sunday
+}
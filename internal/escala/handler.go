@@ -0,0 +1,269 @@
+package escala
+
+import (
+	"escala-fds-api/internal/auth"
+	"escala-fds-api/internal/constants"
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/export"
+	"escala-fds-api/internal/validation"
+	"escala-fds-api/pkg/ierr"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	escalaRoutes := router.Group("/teams/:team/escala")
+	escalaRoutes.Use(auth.Middleware(), auth.RequireScope("escala"))
+	{
+		escalaRoutes.POST("/generate", h.Generate)
+		escalaRoutes.GET("/:month", h.FindVersion)
+		escalaRoutes.GET("/:month/versions", h.FindVersions)
+		escalaRoutes.GET("/:month/diff", h.Diff)
+		escalaRoutes.PUT("/:month/versions/:version/entries", h.UpdateEntries)
+		escalaRoutes.POST("/:month/versions/:version/publish", h.Publish)
+	}
+}
+
+func (h *Handler) Generate(c *gin.Context) {
+	requestorID, errAuth := auth.GetUserIDFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	team, restErr := teamFromPath(c)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	month, restErr := validation.ParseMonth("month", c.Query("month"))
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+
+	var req GenerateRequest
+	if c.Request.ContentLength != 0 {
+		if errBind := validation.BindJSON(c, &req); errBind != nil {
+			c.JSON(errBind.Code, errBind)
+			return
+		}
+	}
+
+	escala, err := h.service.Generate(team, month, req.MinimumStaffing, requestorID)
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusCreated, escala)
+}
+
+func (h *Handler) FindVersions(c *gin.Context) {
+	requestorID, team, month, restErr := escalaParams(c)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	versions, err := h.service.FindVersions(team, month, requestorID)
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusOK, versions)
+}
+
+func (h *Handler) FindVersion(c *gin.Context) {
+	// Gin cannot route "/:month.pdf" separately from "/:month".
+	if strings.HasSuffix(c.Param("month"), ".pdf") {
+		h.RenderPDF(c)
+		return
+	}
+	requestorID, team, month, restErr := escalaParams(c)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	number := 0
+	if c.Query("version") != "" {
+		if number, restErr = parseVersion("version", c.Query("version")); restErr != nil {
+			c.JSON(restErr.Code, restErr)
+			return
+		}
+	}
+	format, restErr := export.FormatFromRequest(c)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+
+	escala, err := h.service.FindVersion(team, month, number, requestorID)
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	if format != export.FormatJSON {
+		header, rows := escala.exportTable()
+		filename := fmt.Sprintf("escala-%s-%s-v%d", escala.Team, escala.Month, escala.Version)
+		export.Write(c, format, filename, header, func(w export.RowWriter) error {
+			for _, row := range rows {
+				if err := w.WriteRow(row); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		return
+	}
+	c.JSON(http.StatusOK, escala)
+}
+
+func (h *Handler) RenderPDF(c *gin.Context) {
+	requestorID, errAuth := auth.GetUserIDFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	team, restErr := teamFromPath(c)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	month, restErr := validation.ParseMonth("month", strings.TrimSuffix(c.Param("month"), ".pdf"))
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	number := 0
+	if c.Query("version") != "" {
+		if number, restErr = parseVersion("version", c.Query("version")); restErr != nil {
+			c.JSON(restErr.Code, restErr)
+			return
+		}
+	}
+
+	document, err := h.service.RenderPDF(team, month, number, requestorID)
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	filename := fmt.Sprintf("escala-%s-%s.pdf", team, month.Format(constants.ApiMonthLayout))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, "application/pdf", document.Bytes())
+}
+
+func (h *Handler) UpdateEntries(c *gin.Context) {
+	requestorID, team, month, restErr := escalaParams(c)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	number, restErr := parseVersion("version", c.Param("version"))
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	var req UpdateEntriesRequest
+	if errBind := validation.BindJSON(c, &req); errBind != nil {
+		c.JSON(errBind.Code, errBind)
+		return
+	}
+	escala, err := h.service.UpdateEntries(team, month, number, req.Entries, requestorID)
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusOK, escala)
+}
+
+func (h *Handler) Publish(c *gin.Context) {
+	requestorID, team, month, restErr := escalaParams(c)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	number, restErr := parseVersion("version", c.Param("version"))
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	escala, err := h.service.Publish(team, month, number, requestorID)
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusOK, escala)
+}
+
+func (h *Handler) Diff(c *gin.Context) {
+	requestorID, team, month, restErr := escalaParams(c)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	from, restErr := parseVersion("from", c.Query("from"))
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	to, restErr := parseVersion("to", c.Query("to"))
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	diff, err := h.service.Diff(team, month, from, to, requestorID)
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
+
+// escalaParams reads the requestor and the team and month path parameters
+// shared by every version endpoint.
+func escalaParams(c *gin.Context) (uint, entity.TeamName, time.Time, *ierr.RestErr) {
+	requestorID, errAuth := auth.GetUserIDFromContext(c)
+	if errAuth != nil {
+		return 0, "", time.Time{}, errAuth
+	}
+	team, restErr := teamFromPath(c)
+	if restErr != nil {
+		return 0, "", time.Time{}, restErr
+	}
+	month, restErr := validation.ParseMonth("month", c.Param("month"))
+	if restErr != nil {
+		return 0, "", time.Time{}, restErr
+	}
+	return requestorID, team, month, nil
+}
+
+func parseVersion(field, value string) (int, *ierr.RestErr) {
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 1 {
+		return 0, ierr.NewBadRequestValidationError("some fields are invalid", []ierr.Causes{
+			{Field: field, Message: "must be a positive version number"},
+		})
+	}
+	return number, nil
+}
+
+func teamFromPath(c *gin.Context) (entity.TeamName, *ierr.RestErr) {
+	team := entity.TeamName(c.Param("team"))
+	if !team.IsValid() {
+		return "", ierr.NewBadRequestValidationError("some fields are invalid", []ierr.Causes{
+			{Field: "team", Message: "must be a valid team: Security, Support, CustomerService"},
+		})
+	}
+	return team, nil
+}
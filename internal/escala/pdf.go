@@ -0,0 +1,181 @@
+package escala
+
+import (
+	"escala-fds-api/internal/constants"
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/pdf"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	pdfMargin     = 28.0
+	pdfNameWidth  = 140.0
+	pdfHeaderRow  = 22.0
+	pdfRow        = 14.0
+	pdfGridTop    = 70.0
+	pdfFooterArea = 60.0
+)
+
+// printable is everything drawn on the printed escala.
+type printable struct {
+	escala    *EscalaResponse
+	month     time.Time
+	holidays  map[string]string
+	absences  map[uint]map[string]bool
+	templates []entity.ShiftTemplate
+}
+
+// renderPDF draws the month grid on landscape A4 pages: one row per
+// collaborator, one column per day with a shift code, holidays and weekends
+// shaded, absences highlighted and a legend at the bottom of every page.
+func renderPDF(data printable) *pdf.Document {
+	pageWidth, pageHeight := pdf.A4Height, pdf.A4Width
+	doc := pdf.New(pageWidth, pageHeight)
+
+	var dates []time.Time
+	for date := data.month; date.Month() == data.month.Month(); date = date.AddDate(0, 0, 1) {
+		dates = append(dates, date)
+	}
+	dayWidth := (pageWidth - 2*pdfMargin - pdfNameWidth) / float64(len(dates))
+	codes := shiftCodes(data.templates)
+	rowsPerPage := int((pageHeight - pdfGridTop - pdfHeaderRow - pdfFooterArea) / pdfRow)
+
+	rows := data.escala.Rows
+	for start := 0; start == 0 || start < len(rows); start += rowsPerPage {
+		end := start + rowsPerPage
+		if end > len(rows) {
+			end = len(rows)
+		}
+		page := doc.AddPage()
+		drawTitle(page, data)
+		gridHeight := pdfHeaderRow + float64(end-start)*pdfRow
+
+		for i, date := range dates {
+			x := pdfMargin + pdfNameWidth + float64(i)*dayWidth
+			key := date.Format(constants.ApiDateLayout)
+			switch {
+			case data.holidays[key] != "":
+				page.SetFillRGB(1, 0.82, 0.82)
+				page.Rect(x, pdfGridTop, dayWidth, gridHeight, true)
+			case date.Weekday() == time.Saturday || date.Weekday() == time.Sunday:
+				page.SetFillGray(0.9)
+				page.Rect(x, pdfGridTop, dayWidth, gridHeight, true)
+			}
+			page.SetFillGray(0)
+			page.TextCentered(x+dayWidth/2, pdfGridTop+9, 7, true, fmt.Sprintf("%02d", date.Day()))
+			page.TextCentered(x+dayWidth/2, pdfGridTop+18, 6, false, date.Weekday().String()[:2])
+		}
+		page.Text(pdfMargin+2, pdfGridTop+14, 8, true, "Collaborator")
+
+		for r, row := range rows[start:end] {
+			y := pdfGridTop + pdfHeaderRow + float64(r)*pdfRow
+			page.SetFillGray(0)
+			page.Text(pdfMargin+2, y+10, 7, false, truncate(row.Name, pdfNameWidth-4, 7))
+			for _, day := range row.Days {
+				date, _ := time.Parse(constants.ApiDateLayout, day.Date)
+				x := pdfMargin + pdfNameWidth + float64(date.Day()-1)*dayWidth
+				code := dayCode(day, codes)
+				if data.absences[row.UserID][day.Date] {
+					code = "ABS"
+				}
+				if code == "ABS" {
+					page.SetFillRGB(1, 0.93, 0.6)
+					page.Rect(x, y, dayWidth, pdfRow, true)
+				}
+				page.SetFillGray(0)
+				page.TextCentered(x+dayWidth/2, y+10, 6.5, false, code)
+			}
+		}
+
+		page.SetStrokeGray(0.4)
+		page.SetLineWidth(0.3)
+		page.Rect(pdfMargin, pdfGridTop, pageWidth-2*pdfMargin, gridHeight, false)
+		for i := 0; i <= len(dates); i++ {
+			x := pdfMargin + pdfNameWidth + float64(i)*dayWidth
+			page.Line(x, pdfGridTop, x, pdfGridTop+gridHeight)
+		}
+		for r := 0; r <= end-start; r++ {
+			y := pdfGridTop + pdfHeaderRow + float64(r)*pdfRow
+			page.Line(pdfMargin, y, pageWidth-pdfMargin, y)
+		}
+		drawLegend(page, data, codes, pdfGridTop+gridHeight+16)
+	}
+	return doc
+}
+
+func drawTitle(page *pdf.Page, data printable) {
+	page.SetFillGray(0)
+	page.Text(pdfMargin, 38, 14, true, fmt.Sprintf("Escala %s - %s", data.escala.Team, data.month.Format("January 2006")))
+	subtitle := fmt.Sprintf("Version %d (%s)", data.escala.Version, data.escala.Status)
+	if data.escala.PublishedAt != "" {
+		subtitle += " - published " + data.escala.PublishedAt
+	}
+	page.Text(pdfMargin, 54, 8, false, subtitle)
+}
+
+func drawLegend(page *pdf.Page, data printable, codes map[entity.ShiftName]string, y float64) {
+	var parts []string
+	for _, template := range data.templates {
+		if code, ok := codes[template.Name]; ok {
+			parts = append(parts, fmt.Sprintf("%s = %s (%s-%s)", code, template.Name, template.StartTime, template.EndTime))
+		}
+	}
+	parts = append(parts, "OFF = day off", "HOL = holiday", "ABS = absence")
+	page.SetFillGray(0)
+	page.Text(pdfMargin, y, 7, false, strings.Join(parts, "    "))
+
+	if len(data.holidays) > 0 {
+		keys := make([]string, 0, len(data.holidays))
+		for key := range data.holidays {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		holidays := make([]string, 0, len(keys))
+		for _, key := range keys {
+			holidays = append(holidays, fmt.Sprintf("%s %s", key[8:], data.holidays[key]))
+		}
+		page.Text(pdfMargin, y+12, 7, false, "Holidays: "+strings.Join(holidays, "; "))
+	}
+}
+
+// shiftCodes gives each template a one-letter code in start time order.
+func shiftCodes(templates []entity.ShiftTemplate) map[entity.ShiftName]string {
+	codes := make(map[entity.ShiftName]string, len(templates))
+	for i, template := range templates {
+		if i >= 26 {
+			codes[template.Name] = fmt.Sprintf("S%d", i+1)
+			continue
+		}
+		codes[template.Name] = string(rune('A' + i))
+	}
+	return codes
+}
+
+func dayCode(day DayResponse, codes map[entity.ShiftName]string) string {
+	switch day.Kind {
+	case entity.EscalaEntryWork:
+		if code, ok := codes[day.Shift]; ok {
+			return code
+		}
+		return string(day.Shift)
+	case entity.EscalaEntryHoliday:
+		return "HOL"
+	case entity.EscalaEntryAbsence:
+		return "ABS"
+	}
+	return "OFF"
+}
+
+func truncate(s string, width, size float64) string {
+	if pdf.TextWidth(s, size) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && pdf.TextWidth(string(runes)+"...", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
@@ -0,0 +1,132 @@
+package escala
+
+import (
+	"escala-fds-api/internal/entity"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	CreateVersion(version *entity.EscalaVersion) error
+	FindVersionByID(id uint) (*entity.EscalaVersion, error)
+	FindVersion(team entity.TeamName, month string, number int) (*entity.EscalaVersion, error)
+	FindVersions(team entity.TeamName, month string) ([]entity.EscalaVersion, error)
+	FindPublishedVersion(team entity.TeamName, month string) (*entity.EscalaVersion, error)
+	NextVersionNumber(team entity.TeamName, month string) (int, error)
+	SaveEntries(versionID uint, entries []entity.EscalaEntry) error
+	Publish(version *entity.EscalaVersion) error
+	FindPublishedEntries(userID uint, startDate, endDate time.Time) ([]entity.EscalaEntry, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// CreateVersion stores the version together with its entries.
+func (r *repository) CreateVersion(version *entity.EscalaVersion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(version).Error
+	})
+}
+
+func (r *repository) FindVersionByID(id uint) (*entity.EscalaVersion, error) {
+	var version entity.EscalaVersion
+	err := r.db.Preload("Entries", orderEntries).First(&version, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+func (r *repository) FindVersion(team entity.TeamName, month string, number int) (*entity.EscalaVersion, error) {
+	var version entity.EscalaVersion
+	err := r.db.Preload("Entries", orderEntries).
+		Where("team = ? AND month = ? AND version = ?", team, month, number).
+		First(&version).Error
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+func (r *repository) FindVersions(team entity.TeamName, month string) ([]entity.EscalaVersion, error) {
+	var versions []entity.EscalaVersion
+	err := r.db.Where("team = ? AND month = ?", team, month).Order("version desc").Find(&versions).Error
+	return versions, err
+}
+
+func (r *repository) FindPublishedVersion(team entity.TeamName, month string) (*entity.EscalaVersion, error) {
+	var version entity.EscalaVersion
+	err := r.db.Preload("Entries", orderEntries).
+		Where("team = ? AND month = ? AND status = ?", team, month, entity.EscalaStatusPublished).
+		First(&version).Error
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+func (r *repository) NextVersionNumber(team entity.TeamName, month string) (int, error) {
+	var current int
+	err := r.db.Model(&entity.EscalaVersion{}).
+		Where("team = ? AND month = ?", team, month).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&current).Error
+	return current + 1, err
+}
+
+// SaveEntries replaces the entries of the given users and dates in a version.
+func (r *repository) SaveEntries(versionID uint, entries []entity.EscalaEntry) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, entry := range entries {
+			err := tx.Unscoped().
+				Where("version_id = ? AND user_id = ? AND date = ?", versionID, entry.UserID, entry.Date).
+				Delete(&entity.EscalaEntry{}).Error
+			if err != nil {
+				return err
+			}
+			entry.VersionID = versionID
+			if err := tx.Create(&entry).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&entity.EscalaVersion{}).Where("id = ?", versionID).Update("updated_at", time.Now()).Error
+	})
+}
+
+// Publish marks the version as published and archives every other version of
+// the same team and month.
+func (r *repository) Publish(version *entity.EscalaVersion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&entity.EscalaVersion{}).
+			Where("team = ? AND month = ? AND id <> ?", version.Team, version.Month, version.ID).
+			Update("status", entity.EscalaStatusArchived).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(version).Updates(map[string]interface{}{
+			"status":          version.Status,
+			"published_by_id": version.PublishedByID,
+			"published_at":    version.PublishedAt,
+		}).Error
+	})
+}
+
+func (r *repository) FindPublishedEntries(userID uint, startDate, endDate time.Time) ([]entity.EscalaEntry, error) {
+	var entries []entity.EscalaEntry
+	err := r.db.Joins("JOIN escala_versions ON escala_versions.id = escala_entries.version_id AND escala_versions.deleted_at IS NULL").
+		Where("escala_versions.status = ?", entity.EscalaStatusPublished).
+		Where("escala_entries.user_id = ? AND escala_entries.date BETWEEN ? AND ?", userID, startDate, endDate).
+		Order("escala_entries.date asc").
+		Find(&entries).Error
+	return entries, err
+}
+
+func orderEntries(db *gorm.DB) *gorm.DB {
+	return db.Order("user_id asc, date asc")
+}
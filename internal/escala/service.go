@@ -0,0 +1,552 @@
+package escala
+
+import (
+	"bytes"
+	"escala-fds-api/internal/certificate"
+	"escala-fds-api/internal/constants"
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/holiday"
+	"escala-fds-api/internal/rules"
+	"escala-fds-api/internal/schedule"
+	"escala-fds-api/internal/shift"
+	"escala-fds-api/internal/user"
+	"escala-fds-api/pkg/ierr"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Service interface {
+	Generate(team entity.TeamName, month time.Time, staffing map[entity.ShiftName]int, requestorID uint) (*EscalaResponse, *ierr.RestErr)
+	FindVersions(team entity.TeamName, month time.Time, requestorID uint) ([]VersionSummaryResponse, *ierr.RestErr)
+	FindVersion(team entity.TeamName, month time.Time, number int, requestorID uint) (*EscalaResponse, *ierr.RestErr)
+	UpdateEntries(team entity.TeamName, month time.Time, number int, entries []EntryRequest, requestorID uint) (*EscalaResponse, *ierr.RestErr)
+	Publish(team entity.TeamName, month time.Time, number int, requestorID uint) (*EscalaResponse, *ierr.RestErr)
+	Diff(team entity.TeamName, month time.Time, from, to int, requestorID uint) (*DiffResponse, *ierr.RestErr)
+	RenderPDF(team entity.TeamName, month time.Time, number int, requestorID uint) (*bytes.Buffer, *ierr.RestErr)
+}
+
+type service struct {
+	repo            Repository
+	userRepo        user.Repository
+	shiftRepo       shift.Repository
+	holidayRepo     holiday.Repository
+	certificateRepo certificate.Repository
+	schedule        schedule.Service
+	rules           rules.Service
+}
+
+func NewService(repo Repository, userRepo user.Repository, shiftRepo shift.Repository, holidayRepo holiday.Repository, certificateRepo certificate.Repository, scheduleService schedule.Service, rulesService rules.Service) Service {
+	return &service{
+		repo:            repo,
+		userRepo:        userRepo,
+		shiftRepo:       shiftRepo,
+		holidayRepo:     holidayRepo,
+		certificateRepo: certificateRepo,
+		schedule:        scheduleService,
+		rules:           rulesService,
+	}
+}
+
+// Generate builds a draft escala for every collaborator of the team. The
+// result is stored as a new version; warnings list the days where the minimum
+// staffing could not be met, the collaborators left short of days and the
+// labor rules of the team the month breaks.
+func (s *service) Generate(team entity.TeamName, month time.Time, staffing map[entity.ShiftName]int, requestorID uint) (*EscalaResponse, *ierr.RestErr) {
+	if err := s.checkTeamManager(team, requestorID); err != nil {
+		return nil, err
+	}
+	if err := s.checkNotPublished(team, month); err != nil {
+		return nil, err
+	}
+
+	templates, err := s.shiftRepo.FindTemplatesForTeam(team)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding shift templates")
+	}
+	templatesByName := make(map[entity.ShiftName]*entity.ShiftTemplate, len(templates))
+	for i := range templates {
+		templatesByName[templates[i].Name] = &templates[i]
+	}
+
+	users, restErr := s.collaborators(team)
+	if restErr != nil {
+		return nil, restErr
+	}
+	if len(users) == 0 {
+		return nil, ierr.NewBadRequestError(fmt.Sprintf("team %s has no collaborators", team))
+	}
+
+	if len(staffing) == 0 {
+		staffing = defaultStaffing(users)
+	}
+	var causes []ierr.Causes
+	for name := range staffing {
+		if _, ok := templatesByName[name]; !ok {
+			causes = append(causes, ierr.Causes{Field: "minimumStaffing." + string(name), Message: "shift template not available to this team"})
+		}
+	}
+	if len(causes) > 0 {
+		return nil, ierr.NewBadRequestValidationError("invalid minimum staffing", causes)
+	}
+
+	config, restErr := s.rules.Config(team)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	start := schedule.DateOnly(month)
+	end := start.AddDate(0, 1, -1)
+	gen := &generator{
+		month:     start,
+		templates: templatesByName,
+		staffing:  staffing,
+		config:    config,
+	}
+	lookBack := config.For(entity.RuleMaxConsecutiveDays).Threshold
+	for i := range users {
+		m, restErr := s.buildMember(&users[i], templatesByName, start, end, lookBack)
+		if restErr != nil {
+			return nil, restErr
+		}
+		gen.members = append(gen.members, m)
+	}
+	gen.run()
+
+	number, err := s.repo.NextVersionNumber(team, start.Format(constants.ApiMonthLayout))
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error numbering escala version")
+	}
+	version := &entity.EscalaVersion{
+		Team:        team,
+		Month:       start.Format(constants.ApiMonthLayout),
+		Version:     number,
+		Status:      entity.EscalaStatusDraft,
+		CreatedByID: requestorID,
+		Warnings:    strings.Join(gen.warnings, "\n"),
+		Entries:     gen.entries,
+	}
+	if err := s.repo.CreateVersion(version); err != nil {
+		return nil, ierr.NewInternalServerError("error saving generated escala")
+	}
+
+	response := ToEscalaResponse(version, users)
+	return &response, nil
+}
+
+func (s *service) FindVersions(team entity.TeamName, month time.Time, requestorID uint) ([]VersionSummaryResponse, *ierr.RestErr) {
+	if err := s.checkTeamMember(team, requestorID); err != nil {
+		return nil, err
+	}
+	versions, err := s.repo.FindVersions(team, month.Format(constants.ApiMonthLayout))
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding escala versions")
+	}
+	response := make([]VersionSummaryResponse, 0, len(versions))
+	for i := range versions {
+		response = append(response, ToVersionSummaryResponse(&versions[i]))
+	}
+	return response, nil
+}
+
+// FindVersion returns the given version, or when number is 0 the published
+// version of the month, falling back to the latest draft.
+func (s *service) FindVersion(team entity.TeamName, month time.Time, number int, requestorID uint) (*EscalaResponse, *ierr.RestErr) {
+	if err := s.checkTeamMember(team, requestorID); err != nil {
+		return nil, err
+	}
+	version, restErr := s.findVersion(team, month, number)
+	if restErr != nil {
+		return nil, restErr
+	}
+	return s.buildResponse(version)
+}
+
+// UpdateEntries edits individual days of a draft. Published and archived
+// versions are read-only.
+func (s *service) UpdateEntries(team entity.TeamName, month time.Time, number int, requests []EntryRequest, requestorID uint) (*EscalaResponse, *ierr.RestErr) {
+	if err := s.checkTeamManager(team, requestorID); err != nil {
+		return nil, err
+	}
+	if err := s.checkNotPublished(team, month); err != nil {
+		return nil, err
+	}
+	version, restErr := s.findVersion(team, month, number)
+	if restErr != nil {
+		return nil, restErr
+	}
+	if version.Status != entity.EscalaStatusDraft {
+		return nil, ierr.NewConflictError(fmt.Sprintf("version %d is %s and can no longer be edited", version.Version, version.Status))
+	}
+
+	entries, restErr := s.validateEntries(team, month, requests)
+	if restErr != nil {
+		return nil, restErr
+	}
+	if err := s.repo.SaveEntries(version.ID, entries); err != nil {
+		return nil, ierr.NewInternalServerError("error updating escala entries")
+	}
+	updated, err := s.repo.FindVersionByID(version.ID)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding escala version")
+	}
+	return s.buildResponse(updated)
+}
+
+// Publish makes a draft the official escala of the month and archives the
+// other versions. The month is locked afterwards.
+func (s *service) Publish(team entity.TeamName, month time.Time, number int, requestorID uint) (*EscalaResponse, *ierr.RestErr) {
+	if err := s.checkTeamManager(team, requestorID); err != nil {
+		return nil, err
+	}
+	if err := s.checkNotPublished(team, month); err != nil {
+		return nil, err
+	}
+	version, restErr := s.findVersion(team, month, number)
+	if restErr != nil {
+		return nil, restErr
+	}
+	if version.Status != entity.EscalaStatusDraft {
+		return nil, ierr.NewConflictError(fmt.Sprintf("version %d is %s and cannot be published", version.Version, version.Status))
+	}
+
+	now := time.Now().UTC()
+	version.Status = entity.EscalaStatusPublished
+	version.PublishedByID = &requestorID
+	version.PublishedAt = &now
+	if err := s.repo.Publish(version); err != nil {
+		return nil, ierr.NewInternalServerError("error publishing escala")
+	}
+	return s.buildResponse(version)
+}
+
+// Diff lists, per collaborator and day, the entries that differ between two
+// versions of the month.
+func (s *service) Diff(team entity.TeamName, month time.Time, from, to int, requestorID uint) (*DiffResponse, *ierr.RestErr) {
+	if err := s.checkTeamMember(team, requestorID); err != nil {
+		return nil, err
+	}
+	fromVersion, restErr := s.findVersion(team, month, from)
+	if restErr != nil {
+		return nil, restErr
+	}
+	toVersion, restErr := s.findVersion(team, month, to)
+	if restErr != nil {
+		return nil, restErr
+	}
+	users, restErr := s.collaborators(team)
+	if restErr != nil {
+		return nil, restErr
+	}
+	names := make(map[uint]string, len(users))
+	for _, u := range users {
+		names[u.ID] = u.FirstName + " " + u.LastName
+	}
+
+	type cell struct {
+		userID uint
+		date   string
+	}
+	before := make(map[cell]DayState, len(fromVersion.Entries))
+	for _, entry := range fromVersion.Entries {
+		before[cell{entry.UserID, entry.Date.Format(constants.ApiDateLayout)}] = DayState{Kind: entry.Kind, Shift: entry.Shift}
+	}
+	response := &DiffResponse{
+		Team:    team,
+		Month:   month.Format(constants.ApiMonthLayout),
+		From:    fromVersion.Version,
+		To:      toVersion.Version,
+		Changes: []ChangeResponse{},
+	}
+	for _, entry := range toVersion.Entries {
+		key := cell{entry.UserID, entry.Date.Format(constants.ApiDateLayout)}
+		after := DayState{Kind: entry.Kind, Shift: entry.Shift}
+		previous, found := before[key]
+		delete(before, key)
+		if found && previous == after {
+			continue
+		}
+		response.Changes = append(response.Changes, ChangeResponse{
+			UserID: entry.UserID, Name: names[entry.UserID], Date: key.date, From: previous, To: after,
+		})
+	}
+	for key, previous := range before {
+		response.Changes = append(response.Changes, ChangeResponse{
+			UserID: key.userID, Name: names[key.userID], Date: key.date, From: previous,
+		})
+	}
+	sort.Slice(response.Changes, func(i, j int) bool {
+		a, b := response.Changes[i], response.Changes[j]
+		if a.UserID != b.UserID {
+			return a.UserID < b.UserID
+		}
+		return a.Date < b.Date
+	})
+	return response, nil
+}
+
+// RenderPDF prints the same version FindVersion returns as a one-page-wide
+// monthly grid, overlaying holidays and approved absences.
+func (s *service) RenderPDF(team entity.TeamName, month time.Time, number int, requestorID uint) (*bytes.Buffer, *ierr.RestErr) {
+	escala, restErr := s.FindVersion(team, month, number, requestorID)
+	if restErr != nil {
+		return nil, restErr
+	}
+	end := month.AddDate(0, 1, -1)
+
+	holidays, err := s.holidayRepo.FindHolidaysForTeam(team, month, end)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding holidays")
+	}
+	data := printable{
+		escala:   escala,
+		month:    month,
+		holidays: make(map[string]string, len(holidays)),
+		absences: make(map[uint]map[string]bool),
+	}
+	for _, h := range holidays {
+		data.holidays[h.Date.Format(constants.ApiDateLayout)] = h.Name
+	}
+	for _, row := range escala.Rows {
+		certificates, err := s.certificateRepo.FindOverlapping(row.UserID, month, end, []entity.CertificateStatus{entity.CertificateStatusApproved})
+		if err != nil {
+			return nil, ierr.NewInternalServerError("error finding absences")
+		}
+		for _, c := range certificates {
+			for date := c.StartDate; !date.After(c.EndDate); date = date.AddDate(0, 0, 1) {
+				if data.absences[row.UserID] == nil {
+					data.absences[row.UserID] = make(map[string]bool)
+				}
+				data.absences[row.UserID][date.Format(constants.ApiDateLayout)] = true
+			}
+		}
+	}
+
+	templates, err := s.shiftRepo.FindTemplatesForTeam(team)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding shift templates")
+	}
+	sort.SliceStable(templates, func(i, j int) bool {
+		return templates[i].StartTime < templates[j].StartTime
+	})
+	data.templates = templates
+
+	var buf bytes.Buffer
+	if _, err := renderPDF(data).WriteTo(&buf); err != nil {
+		return nil, ierr.NewInternalServerError("error rendering escala")
+	}
+	return &buf, nil
+}
+
+func (s *service) findVersion(team entity.TeamName, month time.Time, number int) (*entity.EscalaVersion, *ierr.RestErr) {
+	monthKey := month.Format(constants.ApiMonthLayout)
+	var version *entity.EscalaVersion
+	var err error
+	if number > 0 {
+		version, err = s.repo.FindVersion(team, monthKey, number)
+	} else {
+		version, err = s.repo.FindPublishedVersion(team, monthKey)
+		if err == gorm.ErrRecordNotFound {
+			var latest int
+			latest, err = s.repo.NextVersionNumber(team, monthKey)
+			if err == nil {
+				version, err = s.repo.FindVersion(team, monthKey, latest-1)
+			}
+		}
+	}
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ierr.NewNotFoundError(fmt.Sprintf("escala not found for team %s in %s", team, monthKey))
+		}
+		return nil, ierr.NewInternalServerError("error finding escala version")
+	}
+	return version, nil
+}
+
+func (s *service) validateEntries(team entity.TeamName, month time.Time, requests []EntryRequest) ([]entity.EscalaEntry, *ierr.RestErr) {
+	users, restErr := s.collaborators(team)
+	if restErr != nil {
+		return nil, restErr
+	}
+	members := make(map[uint]bool, len(users))
+	for _, u := range users {
+		members[u.ID] = true
+	}
+
+	var causes []ierr.Causes
+	entries := make([]entity.EscalaEntry, 0, len(requests))
+	for i, req := range requests {
+		field := fmt.Sprintf("entries[%d]", i)
+		date, _ := time.ParseInLocation(constants.ApiDateLayout, req.Date, time.UTC)
+		if date.Format(constants.ApiMonthLayout) != month.Format(constants.ApiMonthLayout) {
+			causes = append(causes, ierr.Causes{Field: field + ".date", Message: "must be within the escala month"})
+		}
+		if !members[req.UserID] {
+			causes = append(causes, ierr.Causes{Field: field + ".userId", Message: "is not a collaborator of this team"})
+		}
+		switch {
+		case req.Kind == entity.EscalaEntryWork && req.Shift == "":
+			causes = append(causes, ierr.Causes{Field: field + ".shift", Message: "is required for work days"})
+		case req.Kind != entity.EscalaEntryWork && req.Shift != "":
+			causes = append(causes, ierr.Causes{Field: field + ".shift", Message: "must be empty unless kind is work"})
+		case req.Shift != "":
+			template, err := s.shiftRepo.FindTemplateByName(req.Shift)
+			if err != nil && err != gorm.ErrRecordNotFound {
+				return nil, ierr.NewInternalServerError("error finding shift template")
+			}
+			if err == gorm.ErrRecordNotFound || !template.AvailableTo(team) {
+				causes = append(causes, ierr.Causes{Field: field + ".shift", Message: "shift template not available to this team"})
+			}
+		}
+		entries = append(entries, entity.EscalaEntry{UserID: req.UserID, Date: date, Kind: req.Kind, Shift: req.Shift})
+	}
+	if len(causes) > 0 {
+		return nil, ierr.NewBadRequestValidationError("invalid escala entries", causes)
+	}
+	return entries, nil
+}
+
+func (s *service) buildResponse(version *entity.EscalaVersion) (*EscalaResponse, *ierr.RestErr) {
+	users, restErr := s.collaborators(version.Team)
+	if restErr != nil {
+		return nil, restErr
+	}
+	response := ToEscalaResponse(version, users)
+	return &response, nil
+}
+
+// checkNotPublished rejects changes to a month whose escala was published;
+// from then on the schedule only changes through swap requests.
+func (s *service) checkNotPublished(team entity.TeamName, month time.Time) *ierr.RestErr {
+	_, err := s.repo.FindPublishedVersion(team, month.Format(constants.ApiMonthLayout))
+	if err == nil {
+		return ierr.NewConflictError("the escala for this month is published; changes must go through swap requests")
+	}
+	if err != gorm.ErrRecordNotFound {
+		return ierr.NewInternalServerError("error finding published escala")
+	}
+	return nil
+}
+
+// buildMember loads what the generator needs to know about a collaborator:
+// the regular schedule for the month, the holidays they have off, approved
+// absences, and the shifts worked just before the month so rest and
+// consecutive-day limits carry over. lookBack is the number of days before
+// the month to look at.
+func (s *service) buildMember(u *entity.User, templates map[entity.ShiftName]*entity.ShiftTemplate, start, end time.Time, lookBack int) (*member, *ierr.RestErr) {
+	m := &member{
+		user:     u,
+		template: templates[u.Shift],
+		regular:  make(map[string]bool),
+		absent:   make(map[string]bool),
+		holidays: make(map[string]bool),
+	}
+
+	days, err := s.schedule.ShiftsInRange(u, start, end)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error resolving regular schedule")
+	}
+	for _, day := range days {
+		key := day.Date.Format(constants.ApiDateLayout)
+		if day.Working {
+			m.regular[key] = true
+		}
+		if day.HolidayOff {
+			m.holidays[key] = true
+		}
+	}
+
+	certificates, err := s.certificateRepo.FindOverlapping(u.ID, start, end, []entity.CertificateStatus{entity.CertificateStatusApproved})
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding absences")
+	}
+	for _, cert := range certificates {
+		for date := schedule.DateOnly(cert.StartDate); !date.After(cert.EndDate); date = date.AddDate(0, 0, 1) {
+			m.absent[date.Format(constants.ApiDateLayout)] = true
+		}
+	}
+	for key := range m.regular {
+		if !m.absent[key] {
+			m.target++
+		}
+	}
+
+	previous, err := s.schedule.ShiftsInRange(u, start.AddDate(0, 0, -max(lookBack, 1)), start.AddDate(0, 0, -1))
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error resolving previous schedule")
+	}
+	for _, day := range previous {
+		if !day.Working {
+			m.consecutive = 0
+			continue
+		}
+		m.consecutive++
+		if _, shiftEnd, err := s.schedule.ShiftWindow(day.Shift, day.Date); err == nil {
+			m.lastEnd = shiftEnd
+		}
+	}
+	return m, nil
+}
+
+func (s *service) collaborators(team entity.TeamName) ([]entity.User, *ierr.RestErr) {
+	users, err := s.userRepo.FindUsersByTeam(team)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding team members")
+	}
+	collaborators := make([]entity.User, 0, len(users))
+	for _, u := range users {
+		if u.UserType == entity.UserTypeCollaborator {
+			collaborators = append(collaborators, u)
+		}
+	}
+	return collaborators, nil
+}
+
+// checkTeamMember allows masters and members of the team.
+func (s *service) checkTeamMember(team entity.TeamName, requestorID uint) *ierr.RestErr {
+	requestor, err := s.userRepo.FindUserByID(requestorID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ierr.NewUnauthorizedError("requestor not found")
+		}
+		return ierr.NewInternalServerError("error finding requestor")
+	}
+	if requestor.UserType != entity.UserTypeMaster && requestor.Team != team {
+		return ierr.NewForbiddenError("you can only view the escala of your own team")
+	}
+	return nil
+}
+
+// checkTeamManager allows masters and the team's supervisors.
+func (s *service) checkTeamManager(team entity.TeamName, requestorID uint) *ierr.RestErr {
+	requestor, err := s.userRepo.FindUserByID(requestorID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ierr.NewUnauthorizedError("requestor not found")
+		}
+		return ierr.NewInternalServerError("error finding requestor")
+	}
+	if requestor.UserType == entity.UserTypeMaster {
+		return nil
+	}
+	isSupervisor := requestor.Position == entity.PositionSupervisorI || requestor.Position == entity.PositionSupervisorII
+	if requestor.Team != team || !isSupervisor {
+		return ierr.NewForbiddenError("only masters and the team's supervisors can manage its escala")
+	}
+	return nil
+}
+
+// defaultStaffing requires one collaborator on every shift someone in the
+// team is assigned to.
+func defaultStaffing(users []entity.User) map[entity.ShiftName]int {
+	staffing := make(map[entity.ShiftName]int)
+	for _, u := range users {
+		if u.Shift != "" {
+			staffing[u.Shift] = 1
+		}
+	}
+	return staffing
+}
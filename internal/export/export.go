@@ -0,0 +1,118 @@
+// Package export writes tabular API responses as CSV or XLSX downloads.
+package export
+
+import (
+	"encoding/csv"
+	"escala-fds-api/pkg/ierr"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+
+	csvContentType  = "text/csv"
+	xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// RowWriter receives the rows of a table one at a time.
+type RowWriter interface {
+	WriteRow(values []string) error
+}
+
+// FormatFromRequest picks the response format from the "format" query
+// parameter, falling back to the Accept header and then to JSON.
+func FormatFromRequest(c *gin.Context) (Format, *ierr.RestErr) {
+	switch Format(strings.ToLower(c.Query("format"))) {
+	case "":
+	case FormatJSON:
+		return FormatJSON, nil
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatXLSX:
+		return FormatXLSX, nil
+	default:
+		return "", ierr.NewBadRequestValidationError("some fields are invalid", []ierr.Causes{
+			{Field: "format", Message: "must be one of: json, csv, xlsx"},
+		})
+	}
+
+	accept := c.GetHeader("Accept")
+	switch {
+	case strings.Contains(accept, csvContentType):
+		return FormatCSV, nil
+	case strings.Contains(accept, xlsxContentType):
+		return FormatXLSX, nil
+	}
+	return FormatJSON, nil
+}
+
+// Write sends a table as an attachment named filename plus the format's
+// extension. rows is called once and streams its rows into the response.
+func Write(c *gin.Context, format Format, filename string, header []string, rows func(w RowWriter) error) {
+	switch format {
+	case FormatCSV:
+		c.Header("Content-Type", csvContentType+"; charset=utf-8")
+	case FormatXLSX:
+		c.Header("Content-Type", xlsxContentType)
+	default:
+		restErr := ierr.NewBadRequestError(fmt.Sprintf("unsupported export format: %s", format))
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format))
+	c.Status(http.StatusOK)
+
+	var err error
+	if format == FormatCSV {
+		err = writeCSV(c.Writer, header, rows)
+	} else {
+		err = writeXLSX(c.Writer, header, rows)
+	}
+	if err != nil {
+		// Headers are already sent; all that is left is to abort the stream.
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func (r csvRowWriter) WriteRow(values []string) error {
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		escaped[i] = escapeFormula(value)
+	}
+	return r.w.Write(escaped)
+}
+
+// escapeFormula keeps spreadsheets from evaluating free text as a formula
+// (CSV injection) by prefixing cells that would start one with a quote.
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func writeCSV(out io.Writer, header []string, rows func(w RowWriter) error) error {
+	w := csv.NewWriter(out)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	if err := rows(csvRowWriter{w: w}); err != nil {
+		return err
+	}
+	w.Flush()
+	return w.Error()
+}
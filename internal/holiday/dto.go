@@ -6,31 +6,86 @@ import (
 )
 
 type CreateHolidayRequest struct {
-	Name string             `json:"name" binding:"required"`
-	Date string             `json:"date" binding:"required"`
-	Type entity.HolidayType `json:"type" binding:"required"`
+	Name       string                   `json:"name" binding:"required,max=100"`
+	Date       string                   `json:"date" binding:"required,apidate"`
+	Type       entity.HolidayType       `json:"type" binding:"required,oneof=national state city"`
+	State      string                   `json:"state,omitempty" binding:"omitempty,len=2,alpha"`
+	City       string                   `json:"city,omitempty" binding:"omitempty,max=100"`
+	Recurrence entity.HolidayRecurrence `json:"recurrence,omitempty" binding:"omitempty,oneof=yearly easter"`
+	Optional   bool                     `json:"optional"`
 }
 
 type UpdateHolidayRequest struct {
-	Name string             `json:"name" binding:"required"`
-	Date string             `json:"date" binding:"required"`
-	Type entity.HolidayType `json:"type" binding:"required"`
+	Name       string                   `json:"name" binding:"required,max=100"`
+	Date       string                   `json:"date" binding:"required,apidate"`
+	Type       entity.HolidayType       `json:"type" binding:"required,oneof=national state city"`
+	State      string                   `json:"state,omitempty" binding:"omitempty,len=2,alpha"`
+	City       string                   `json:"city,omitempty" binding:"omitempty,max=100"`
+	Recurrence entity.HolidayRecurrence `json:"recurrence,omitempty" binding:"omitempty,oneof=yearly easter"`
+	Optional   bool                     `json:"optional"`
 }
 
 type HolidayResponse struct {
-	ID        uint               `json:"id"`
-	Name      string             `json:"name"`
-	Date      string             `json:"date"`
-	Type      entity.HolidayType `json:"type"`
-	CreatedAt string             `json:"createdAt"`
+	ID         uint                     `json:"id"`
+	Name       string                   `json:"name"`
+	Date       string                   `json:"date"`
+	Type       entity.HolidayType       `json:"type"`
+	State      string                   `json:"state,omitempty"`
+	City       string                   `json:"city,omitempty"`
+	Recurrence entity.HolidayRecurrence `json:"recurrence,omitempty"`
+	Optional   bool                     `json:"optional"`
+	CreatedAt  string                   `json:"createdAt"`
+}
+
+type ImportHolidaysResponse struct {
+	Created   int               `json:"created"`
+	Updated   int               `json:"updated"`
+	Unchanged int               `json:"unchanged"`
+	Holidays  []HolidayResponse `json:"holidays"`
+}
+
+type TeamPolicyRequest struct {
+	Policy    entity.HolidayPolicy `json:"policy" binding:"required,oneof=off worked_normal worked_premium"`
+	DutyCount int                  `json:"dutyCount" binding:"min=0,max=100"`
+}
+
+type TeamPolicyResponse struct {
+	Team      entity.TeamName      `json:"team"`
+	Policy    entity.HolidayPolicy `json:"policy"`
+	DutyCount int                  `json:"dutyCount"`
+}
+
+func ToTeamPolicyResponse(policy *entity.TeamHolidayPolicy) TeamPolicyResponse {
+	return TeamPolicyResponse{
+		Team:      policy.Team,
+		Policy:    policy.Policy,
+		DutyCount: policy.DutyCount,
+	}
+}
+
+func ToImportHolidaysResponse(result UpsertResult, holidays []entity.Holiday) ImportHolidaysResponse {
+	response := ImportHolidaysResponse{
+		Created:   result.Created,
+		Updated:   result.Updated,
+		Unchanged: result.Unchanged,
+		Holidays:  make([]HolidayResponse, 0, len(holidays)),
+	}
+	for i := range holidays {
+		response.Holidays = append(response.Holidays, ToHolidayResponse(&holidays[i]))
+	}
+	return response
 }
 
 func ToHolidayResponse(holiday *entity.Holiday) HolidayResponse {
 	return HolidayResponse{
-		ID:        holiday.ID,
-		Name:      holiday.Name,
-		Date:      holiday.Date.Format(constants.ApiDateLayout),
-		Type:      holiday.Type,
-		CreatedAt: holiday.CreatedAt.Format(constants.ApiTimestampLayout),
+		ID:         holiday.ID,
+		Name:       holiday.Name,
+		Date:       holiday.Date.Format(constants.ApiDateLayout),
+		Type:       holiday.Type,
+		State:      holiday.State,
+		City:       holiday.City,
+		Recurrence: holiday.Recurrence,
+		Optional:   holiday.Optional,
+		CreatedAt:  holiday.CreatedAt.Format(constants.ApiTimestampLayout),
 	}
 }
@@ -3,10 +3,13 @@ package holiday
 import (
 	"escala-fds-api/internal/auth"
 	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/validation"
 	"escala-fds-api/pkg/ierr"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
-	"time"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,30 +24,53 @@ func NewHandler(service Service) *Handler {
 
 func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 	holidayRoutes := router.Group("/holidays")
-	holidayRoutes.Use(auth.Middleware())
+	holidayRoutes.Use(auth.Middleware(), auth.RequireScope("holidays"))
 	{
 		holidayRoutes.POST("", h.Create)
+		holidayRoutes.POST("/import", h.Import)
+		holidayRoutes.POST("/generate", h.GenerateNational)
 		holidayRoutes.GET("", h.FindAll)
 		holidayRoutes.GET("/:id", h.FindByID)
 		holidayRoutes.PUT("/:id", h.Update)
 		holidayRoutes.DELETE("/:id", h.Delete)
 	}
+	policyRoutes := router.Group("/teams/:team/holiday-policy")
+	policyRoutes.Use(auth.Middleware(), auth.RequireScope("holidays"))
+	{
+		policyRoutes.GET("", h.FindTeamPolicy)
+		policyRoutes.PUT("", h.UpdateTeamPolicy)
+	}
+	optionalRoutes := router.Group("/teams/:team/optional-holidays")
+	optionalRoutes.Use(auth.Middleware(), auth.RequireScope("holidays"))
+	{
+		optionalRoutes.GET("", h.FindTeamOptionalHolidays)
+		optionalRoutes.PUT("/:id", h.OptIntoHoliday)
+		optionalRoutes.DELETE("/:id", h.OptOutOfHoliday)
+	}
 }
 
 func (h *Handler) Create(c *gin.Context) {
 	var req CreateHolidayRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ierr.NewBadRequestError(err.Error()))
+	if errBind := validation.BindJSON(c, &req); errBind != nil {
+		c.JSON(errBind.Code, errBind)
 		return
 	}
 
-	date, err := time.Parse("2006-01-02", req.Date)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ierr.NewBadRequestError("invalid date format, use YYYY-MM-DD"))
+	date, errDate := validation.ParseDate("date", req.Date)
+	if errDate != nil {
+		c.JSON(errDate.Code, errDate)
 		return
 	}
 
-	holiday := entity.Holiday{Name: req.Name, Date: date, Type: req.Type}
+	holiday := entity.Holiday{
+		Name:       req.Name,
+		Date:       date,
+		Type:       req.Type,
+		State:      req.State,
+		City:       req.City,
+		Recurrence: req.Recurrence,
+		Optional:   req.Optional,
+	}
 	newHoliday, errSvc := h.service.CreateHoliday(holiday)
 	if errSvc != nil {
 		c.JSON(errSvc.Code, errSvc)
@@ -53,9 +79,91 @@ func (h *Handler) Create(c *gin.Context) {
 	c.JSON(http.StatusCreated, ToHolidayResponse(newHoliday))
 }
 
+// Import reads an iCalendar file, sent as the "file" field of a multipart
+// form or as the raw body, and upserts its events as holidays of the type
+// given in the "type" query parameter (national by default) at the "state"
+// and "city" query parameters.
+func (h *Handler) Import(c *gin.Context) {
+	holidayType := entity.HolidayTypeNational
+	if value := c.Query("type"); value != "" {
+		holidayType = entity.HolidayType(value)
+		if !holidayType.IsValid() {
+			restErr := ierr.NewBadRequestValidationError("some fields are invalid", []ierr.Causes{
+				{Field: "type", Message: "must be one of: national, state, city"},
+			})
+			c.JSON(restErr.Code, restErr)
+			return
+		}
+	}
+
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ierr.NewBadRequestError("the calendar file is required"))
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ierr.NewBadRequestError("could not read uploaded file"))
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	holidays, restErr := ParseICS(body, holidayType)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	for i := range holidays {
+		holidays[i].State = c.Query("state")
+		holidays[i].City = c.Query("city")
+	}
+	res, errSvc := h.service.ImportHolidays(holidays)
+	if errSvc != nil {
+		c.JSON(errSvc.Code, errSvc)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *Handler) GenerateNational(c *gin.Context) {
+	year, err := strconv.Atoi(c.Query("year"))
+	if err != nil || year < 1900 || year > 2199 {
+		restErr := ierr.NewBadRequestValidationError("some fields are invalid", []ierr.Causes{
+			{Field: "year", Message: "must be a year between 1900 and 2199"},
+		})
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	res, errSvc := h.service.GenerateNationalHolidays(year)
+	if errSvc != nil {
+		c.JSON(errSvc.Code, errSvc)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// FindAll lists the stored holidays, or the holidays happening in a year
+// ("year") or date range ("startDate" and "endDate") with recurring holidays
+// expanded into their occurrences.
 func (h *Handler) FindAll(c *gin.Context) {
 	startDateStr := c.Query("startDate")
 	endDateStr := c.Query("endDate")
+	if yearStr := c.Query("year"); yearStr != "" {
+		year, err := strconv.Atoi(yearStr)
+		if err != nil || year < 1900 || year > 2199 {
+			restErr := ierr.NewBadRequestValidationError("some fields are invalid", []ierr.Causes{
+				{Field: "year", Message: "must be a year between 1900 and 2199"},
+			})
+			c.JSON(restErr.Code, restErr)
+			return
+		}
+		startDateStr = fmt.Sprintf("%04d-01-01", year)
+		endDateStr = fmt.Sprintf("%04d-12-31", year)
+	}
 	if startDateStr == "" || endDateStr == "" {
 		holidays, err := h.service.FindAllHolidays()
 		if err != nil {
@@ -70,14 +178,21 @@ func (h *Handler) FindAll(c *gin.Context) {
 		return
 	}
 
-	startDate, err := time.Parse("2006-01-02", startDateStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ierr.NewBadRequestError("invalid startDate format"))
+	startDate, errDate := validation.ParseDate("startDate", startDateStr)
+	if errDate != nil {
+		c.JSON(errDate.Code, errDate)
 		return
 	}
-	endDate, err := time.Parse("2006-01-02", endDateStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ierr.NewBadRequestError("invalid endDate format"))
+	endDate, errDate := validation.ParseDate("endDate", endDateStr)
+	if errDate != nil {
+		c.JSON(errDate.Code, errDate)
+		return
+	}
+	if endDate.Before(startDate) {
+		restErr := ierr.NewBadRequestValidationError("some fields are invalid", []ierr.Causes{
+			{Field: "endDate", Message: "must be on or after startDate"},
+		})
+		c.JSON(restErr.Code, restErr)
 		return
 	}
 
@@ -106,18 +221,26 @@ func (h *Handler) FindByID(c *gin.Context) {
 func (h *Handler) Update(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
 	var req UpdateHolidayRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ierr.NewBadRequestError(err.Error()))
+	if errBind := validation.BindJSON(c, &req); errBind != nil {
+		c.JSON(errBind.Code, errBind)
 		return
 	}
 
-	date, err := time.Parse("2006-01-02", req.Date)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ierr.NewBadRequestError("invalid date format, use YYYY-MM-DD"))
+	date, errDate := validation.ParseDate("date", req.Date)
+	if errDate != nil {
+		c.JSON(errDate.Code, errDate)
 		return
 	}
 
-	holidayData := entity.Holiday{Name: req.Name, Date: date, Type: req.Type}
+	holidayData := entity.Holiday{
+		Name:       req.Name,
+		Date:       date,
+		Type:       req.Type,
+		State:      req.State,
+		City:       req.City,
+		Recurrence: req.Recurrence,
+		Optional:   req.Optional,
+	}
 	updatedHoliday, errSvc := h.service.UpdateHoliday(uint(id), holidayData)
 	if errSvc != nil {
 		c.JSON(errSvc.Code, errSvc)
@@ -135,3 +258,97 @@ func (h *Handler) Delete(c *gin.Context) {
 	}
 	c.Status(http.StatusNoContent)
 }
+
+func (h *Handler) FindTeamPolicy(c *gin.Context) {
+	team, restErr := teamFromPath(c)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	policy, err := h.service.FindTeamPolicy(team)
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusOK, ToTeamPolicyResponse(policy))
+}
+
+func (h *Handler) UpdateTeamPolicy(c *gin.Context) {
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	team, restErr := teamFromPath(c)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	var req TeamPolicyRequest
+	if errBind := validation.BindJSON(c, &req); errBind != nil {
+		c.JSON(errBind.Code, errBind)
+		return
+	}
+	policy := entity.TeamHolidayPolicy{Team: team, Policy: req.Policy, DutyCount: req.DutyCount}
+	updated, err := h.service.UpdateTeamPolicy(policy, entity.UserType(requestorType))
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusOK, ToTeamPolicyResponse(updated))
+}
+
+func (h *Handler) FindTeamOptionalHolidays(c *gin.Context) {
+	team, restErr := teamFromPath(c)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	holidays, err := h.service.FindTeamOptionalHolidays(team)
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	res := make([]HolidayResponse, 0, len(holidays))
+	for i := range holidays {
+		res = append(res, ToHolidayResponse(&holidays[i]))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *Handler) OptIntoHoliday(c *gin.Context) {
+	h.changeOptIn(c, h.service.OptIntoHoliday)
+}
+
+func (h *Handler) OptOutOfHoliday(c *gin.Context) {
+	h.changeOptIn(c, h.service.OptOutOfHoliday)
+}
+
+func (h *Handler) changeOptIn(c *gin.Context, change func(entity.TeamName, uint, entity.UserType) *ierr.RestErr) {
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	team, restErr := teamFromPath(c)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	id, _ := strconv.Atoi(c.Param("id"))
+	if err := change(team, uint(id), entity.UserType(requestorType)); err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func teamFromPath(c *gin.Context) (entity.TeamName, *ierr.RestErr) {
+	team := entity.TeamName(c.Param("team"))
+	if !team.IsValid() {
+		return "", ierr.NewBadRequestValidationError("some fields are invalid", []ierr.Causes{
+			{Field: "team", Message: "must be a valid team: Security, Support, CustomerService"},
+		})
+	}
+	return team, nil
+}
@@ -0,0 +1,157 @@
+package holiday
+
+import (
+	"bufio"
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/pkg/ierr"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// MaxImportDays caps the number of holiday days read from one calendar file.
+const MaxImportDays = 1000
+
+// ParseICS reads the VEVENTs of an iCalendar file as holidays of the given
+// type. Each event becomes one holiday per day between DTSTART and DTEND
+// (exclusive, as in all-day events); SUMMARY is the name. A plain yearly
+// RRULE makes the holidays recur every year; other recurrence rules are not
+// expanded and only the first occurrence is read.
+func ParseICS(r io.Reader, holidayType entity.HolidayType) ([]entity.Holiday, *ierr.RestErr) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, ierr.NewBadRequestError("could not read calendar file")
+	}
+
+	var holidays []entity.Holiday
+	var causes []ierr.Causes
+	var event map[string]string
+	events := 0
+	for number, line := range lines {
+		switch {
+		case strings.EqualFold(line, "BEGIN:VEVENT"):
+			event = make(map[string]string)
+			events++
+		case strings.EqualFold(line, "END:VEVENT"):
+			if event == nil {
+				continue
+			}
+			field := fmt.Sprintf("events[%d]", events)
+			days, cause := eventHolidays(event, holidayType)
+			if cause != "" {
+				causes = append(causes, ierr.Causes{Field: field, Message: cause})
+			}
+			holidays = append(holidays, days...)
+			if len(holidays) > MaxImportDays {
+				return nil, ierr.NewBadRequestError(fmt.Sprintf("a calendar import may contain at most %d holiday days", MaxImportDays))
+			}
+			event = nil
+		case event != nil:
+			name, value, ok := strings.Cut(line, ":")
+			if !ok {
+				causes = append(causes, ierr.Causes{Field: fmt.Sprintf("lines[%d]", number+1), Message: "is not a valid content line"})
+				continue
+			}
+			property, _, _ := strings.Cut(name, ";")
+			event[strings.ToUpper(property)] = value
+		}
+	}
+	if len(causes) > 0 {
+		return nil, ierr.NewBadRequestValidationError("invalid calendar file", causes)
+	}
+	if len(holidays) == 0 {
+		return nil, ierr.NewBadRequestError("the calendar file has no events")
+	}
+	return holidays, nil
+}
+
+// unfoldICS joins folded content lines (RFC 5545, section 3.1).
+func unfoldICS(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+func eventHolidays(event map[string]string, holidayType entity.HolidayType) ([]entity.Holiday, string) {
+	name := unescapeICSText(event["SUMMARY"])
+	if name == "" {
+		return nil, "SUMMARY is required"
+	}
+	if len([]rune(name)) > 100 {
+		return nil, "SUMMARY must have at most 100 characters"
+	}
+	start, err := parseICSDate(event["DTSTART"])
+	if err != nil {
+		return nil, "DTSTART must be a date (YYYYMMDD) or date-time"
+	}
+	end := start.AddDate(0, 0, 1)
+	if value, ok := event["DTEND"]; ok {
+		if end, err = parseICSDate(value); err != nil {
+			return nil, "DTEND must be a date (YYYYMMDD) or date-time"
+		}
+		if !end.After(start) {
+			end = start.AddDate(0, 0, 1)
+		}
+	}
+
+	recurrence := entity.HolidayRecurrenceNone
+	if isYearlyRule(event["RRULE"]) {
+		recurrence = entity.HolidayRecurrenceYearly
+	}
+	var holidays []entity.Holiday
+	for date := start; date.Before(end); date = date.AddDate(0, 0, 1) {
+		holidays = append(holidays, entity.Holiday{Name: name, Date: date, Type: holidayType, Recurrence: recurrence})
+		if len(holidays) > MaxImportDays {
+			return nil, "the event is too long"
+		}
+	}
+	return holidays, ""
+}
+
+// isYearlyRule reports whether an RRULE repeats on the same day and month
+// every year without end, the only rule a recurring holiday can represent.
+func isYearlyRule(rule string) bool {
+	if rule == "" {
+		return false
+	}
+	yearly := false
+	for _, part := range strings.Split(rule, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			yearly = strings.EqualFold(value, "YEARLY")
+		case "INTERVAL":
+			if value != "1" {
+				return false
+			}
+		case "BYMONTH", "BYMONTHDAY", "WKST":
+		default:
+			return false
+		}
+	}
+	return yearly
+}
+
+// parseICSDate reads DATE values and the date part of DATE-TIME values.
+func parseICSDate(value string) (time.Time, error) {
+	if len(value) > 8 && value[8] == 'T' {
+		value = value[:8]
+	}
+	return time.ParseInLocation("20060102", value, time.UTC)
+}
+
+func unescapeICSText(value string) string {
+	replacer := strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return strings.TrimSpace(replacer.Replace(value))
+}
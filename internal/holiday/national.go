@@ -0,0 +1,39 @@
+package holiday
+
+import (
+	"escala-fds-api/internal/entity"
+	"time"
+)
+
+// NationalHolidays lists the Brazilian national holidays of a year, including
+// the ones that move with Easter: Carnaval (Monday and Tuesday), Sexta-feira
+// Santa and Corpus Christi.
+func NationalHolidays(year int) []entity.Holiday {
+	fixed := func(month time.Month, day int, name string) entity.Holiday {
+		return entity.Holiday{Name: name, Date: time.Date(year, month, day, 0, 0, 0, 0, time.UTC), Type: entity.HolidayTypeNational}
+	}
+	easter := entity.Easter(year)
+	movable := func(offset int, name string) entity.Holiday {
+		return entity.Holiday{Name: name, Date: easter.AddDate(0, 0, offset), Type: entity.HolidayTypeNational}
+	}
+
+	holidays := []entity.Holiday{
+		fixed(time.January, 1, "Confraternização Universal"),
+		movable(-48, "Carnaval"),
+		movable(-47, "Carnaval"),
+		movable(-2, "Sexta-feira Santa"),
+		fixed(time.April, 21, "Tiradentes"),
+		fixed(time.May, 1, "Dia do Trabalho"),
+		movable(60, "Corpus Christi"),
+		fixed(time.September, 7, "Independência do Brasil"),
+		fixed(time.October, 12, "Nossa Senhora Aparecida"),
+		fixed(time.November, 2, "Finados"),
+		fixed(time.November, 15, "Proclamação da República"),
+	}
+	// National holiday since Law 14.759/2023.
+	if year >= 2024 {
+		holidays = append(holidays, fixed(time.November, 20, "Dia Nacional de Zumbi e da Consciência Negra"))
+	}
+	holidays = append(holidays, fixed(time.December, 25, "Natal"))
+	return holidays
+}
@@ -1,7 +1,10 @@
 package holiday
 
 import (
+	"errors"
+	"escala-fds-api/internal/constants"
 	"escala-fds-api/internal/entity"
+	"sort"
 	"time"
 
 	"gorm.io/gorm"
@@ -12,11 +15,30 @@ type Repository interface {
 	FindHolidayByID(id uint) (*entity.Holiday, error)
 	FindHolidaysByDateRange(startDate, endDate time.Time) ([]entity.Holiday, error)
 	FindAllHolidays() ([]entity.Holiday, error)
-	IsHoliday(date time.Time) (bool, error)
+	FindHolidaysForUser(user *entity.User, startDate, endDate time.Time) ([]entity.Holiday, error)
+	FindHolidaysForTeam(team entity.TeamName, startDate, endDate time.Time) ([]entity.Holiday, error)
+	IsHoliday(date time.Time, user *entity.User) (bool, error)
 	UpdateHoliday(holiday *entity.Holiday) error
 	DeleteHoliday(id uint) error
+	UpsertHolidays(holidays []entity.Holiday) (UpsertResult, error)
+	FindTeamHolidayPolicy(team entity.TeamName) (*entity.TeamHolidayPolicy, error)
+	SaveTeamHolidayPolicy(policy *entity.TeamHolidayPolicy) error
+	FindTeamOptionalHolidays(team entity.TeamName) ([]entity.Holiday, error)
+	AddTeamOptionalHoliday(team entity.TeamName, holidayID uint) error
+	RemoveTeamOptionalHoliday(team entity.TeamName, holidayID uint) error
 }
 
+// UpsertResult counts what UpsertHolidays did with each holiday.
+type UpsertResult struct {
+	Created   int
+	Updated   int
+	Unchanged int
+}
+
+// ErrHolidayExists is returned by CreateHoliday when a holiday with the same
+// date, type and location is already stored.
+var ErrHolidayExists = errors.New("holiday already exists")
+
 type repository struct {
 	db *gorm.DB
 }
@@ -25,8 +47,34 @@ func NewRepository(db *gorm.DB) Repository {
 	return &repository{db: db}
 }
 
+// CreateHoliday stores a new holiday. A deleted holiday with the same date,
+// type and location still holds its place in the unique index, so it is
+// restored with the new data instead.
 func (r *repository) CreateHoliday(holiday *entity.Holiday) error {
-	return r.db.Create(holiday).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing entity.Holiday
+		err := tx.Unscoped().Where("date = ? AND type = ? AND state = ? AND city = ?",
+			holiday.Date.Format(constants.ApiDateLayout), holiday.Type, holiday.State, holiday.City).
+			First(&existing).Error
+		switch {
+		case err == gorm.ErrRecordNotFound:
+			return tx.Create(holiday).Error
+		case err != nil:
+			return err
+		case !existing.DeletedAt.Valid:
+			return ErrHolidayExists
+		}
+		existing.DeletedAt = gorm.DeletedAt{}
+		existing.Name = holiday.Name
+		existing.Recurrence = holiday.Recurrence
+		existing.EasterOffset = holiday.EasterOffset
+		existing.Optional = holiday.Optional
+		if err := tx.Unscoped().Save(&existing).Error; err != nil {
+			return err
+		}
+		*holiday = existing
+		return nil
+	})
 }
 
 func (r *repository) FindHolidayByID(id uint) (*entity.Holiday, error) {
@@ -37,10 +85,60 @@ func (r *repository) FindHolidayByID(id uint) (*entity.Holiday, error) {
 	return &holiday, nil
 }
 
+// FindHolidaysByDateRange returns the holidays happening between the dates,
+// recurring ones expanded into an occurrence per year that keeps the ID of
+// the stored holiday. A holiday stored for a date wins over an occurrence of
+// a recurring one on the same date, type and location.
 func (r *repository) FindHolidaysByDateRange(startDate, endDate time.Time) ([]entity.Holiday, error) {
+	var stored []entity.Holiday
+	err := r.db.Where("(recurrence = '' AND date BETWEEN ? AND ?) OR (recurrence <> '' AND date <= ?)",
+		startDate.Format(constants.ApiDateLayout), endDate.Format(constants.ApiDateLayout), endDate.Format(constants.ApiDateLayout)).
+		Order("id asc").
+		Find(&stored).Error
+	if err != nil {
+		return nil, err
+	}
+	return expandOccurrences(stored, startDate, endDate), nil
+}
+
+func expandOccurrences(stored []entity.Holiday, startDate, endDate time.Time) []entity.Holiday {
+	first := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.UTC)
+	last := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 0, 0, 0, 0, time.UTC)
+
+	type occurrenceKey struct {
+		date  string
+		kind  entity.HolidayType
+		state string
+		city  string
+	}
+	seen := make(map[occurrenceKey]int)
 	var holidays []entity.Holiday
-	err := r.db.Where("date BETWEEN ? AND ?", startDate, endDate).Order("date asc").Find(&holidays).Error
-	return holidays, err
+	add := func(h entity.Holiday) {
+		key := occurrenceKey{h.Date.Format(constants.ApiDateLayout), h.Type, h.State, h.City}
+		if i, ok := seen[key]; ok {
+			if holidays[i].Recurrence != entity.HolidayRecurrenceNone && h.Recurrence == entity.HolidayRecurrenceNone {
+				holidays[i] = h
+			}
+			return
+		}
+		seen[key] = len(holidays)
+		holidays = append(holidays, h)
+	}
+	for _, h := range stored {
+		for year := first.Year(); year <= last.Year(); year++ {
+			date, ok := h.OccurrenceIn(year)
+			if !ok || date.Before(first) || date.After(last) {
+				continue
+			}
+			occurrence := h
+			occurrence.Date = date
+			add(occurrence)
+		}
+	}
+	sort.SliceStable(holidays, func(i, j int) bool {
+		return holidays[i].Date.Before(holidays[j].Date)
+	})
+	return holidays
 }
 
 func (r *repository) FindAllHolidays() ([]entity.Holiday, error) {
@@ -49,14 +147,81 @@ func (r *repository) FindAllHolidays() ([]entity.Holiday, error) {
 	return holidays, err
 }
 
-func (r *repository) IsHoliday(date time.Time) (bool, error) {
-	var count int64
-	// Compare only the date part, ignoring time
-	err := r.db.Model(&entity.Holiday{}).Where("DATE(date) = ?", date.Format("2006-01-02")).Count(&count).Error
+// FindHolidaysForUser returns the holidays between the dates observed at the
+// user's site, or at the team's site when the user has none, leaving out the
+// optional ones the user's team did not opt into.
+func (r *repository) FindHolidaysForUser(user *entity.User, startDate, endDate time.Time) ([]entity.Holiday, error) {
+	site, err := r.findSite(user.SiteID, user.Team)
+	if err != nil {
+		return nil, err
+	}
+	return r.findHolidaysAt(site, user.Team, startDate, endDate)
+}
+
+// FindHolidaysForTeam returns the holidays between the dates observed at the
+// team's site, leaving out the optional ones the team did not opt into.
+func (r *repository) FindHolidaysForTeam(team entity.TeamName, startDate, endDate time.Time) ([]entity.Holiday, error) {
+	site, err := r.findSite(nil, team)
+	if err != nil {
+		return nil, err
+	}
+	return r.findHolidaysAt(site, team, startDate, endDate)
+}
+
+func (r *repository) IsHoliday(date time.Time, user *entity.User) (bool, error) {
+	holidays, err := r.FindHolidaysForUser(user, date, date)
 	if err != nil {
 		return false, err
 	}
-	return count > 0, nil
+	return len(holidays) > 0, nil
+}
+
+// findSite resolves the site by id, falling back to the team's site. It
+// returns nil when neither is set.
+func (r *repository) findSite(siteID *uint, team entity.TeamName) (*entity.Site, error) {
+	var site entity.Site
+	var err error
+	if siteID != nil {
+		err = r.db.First(&site, *siteID).Error
+	} else if team != "" {
+		err = r.db.Joins("JOIN team_sites ON team_sites.site_id = sites.id").
+			Where("team_sites.team = ?", team).
+			First(&site).Error
+	} else {
+		return nil, nil
+	}
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &site, nil
+}
+
+func (r *repository) findHolidaysAt(site *entity.Site, team entity.TeamName, startDate, endDate time.Time) ([]entity.Holiday, error) {
+	holidays, err := r.FindHolidaysByDateRange(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	var optedIn []uint
+	if team != "" {
+		err = r.db.Model(&entity.TeamOptionalHoliday{}).Where("team = ?", team).Pluck("holiday_id", &optedIn).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	observed := make(map[uint]bool, len(optedIn))
+	for _, id := range optedIn {
+		observed[id] = true
+	}
+	applicable := holidays[:0]
+	for _, h := range holidays {
+		if h.AppliesTo(site) && (!h.Optional || observed[h.ID]) {
+			applicable = append(applicable, h)
+		}
+	}
+	return applicable, nil
 }
 
 func (r *repository) UpdateHoliday(holiday *entity.Holiday) error {
@@ -64,5 +229,95 @@ func (r *repository) UpdateHoliday(holiday *entity.Holiday) error {
 }
 
 func (r *repository) DeleteHoliday(id uint) error {
-	return r.db.Delete(&entity.Holiday{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("holiday_id = ?", id).Delete(&entity.TeamOptionalHoliday{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&entity.Holiday{}, id).Error
+	})
+}
+
+// UpsertHolidays stores every holiday in one transaction, matching existing
+// ones by date, type and location: a different name or recurrence updates the
+// stored holiday, a deleted one is restored and an identical one is left
+// alone, so importing the same calendar twice is a no-op. The stored records
+// are written back into holidays.
+func (r *repository) UpsertHolidays(holidays []entity.Holiday) (UpsertResult, error) {
+	var result UpsertResult
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result = UpsertResult{}
+		for i := range holidays {
+			// Deleted holidays still hold their place in the unique index.
+			var existing entity.Holiday
+			err := tx.Unscoped().Where("date = ? AND type = ? AND state = ? AND city = ?",
+				holidays[i].Date.Format(constants.ApiDateLayout), holidays[i].Type, holidays[i].State, holidays[i].City).
+				First(&existing).Error
+			switch {
+			case err == gorm.ErrRecordNotFound:
+				if err := tx.Create(&holidays[i]).Error; err != nil {
+					return err
+				}
+				result.Created++
+			case err != nil:
+				return err
+			case existing.DeletedAt.Valid:
+				existing.DeletedAt = gorm.DeletedAt{}
+				existing.Name = holidays[i].Name
+				existing.Recurrence = holidays[i].Recurrence
+				existing.EasterOffset = holidays[i].EasterOffset
+				if err := tx.Unscoped().Save(&existing).Error; err != nil {
+					return err
+				}
+				holidays[i] = existing
+				result.Created++
+			case existing.Name != holidays[i].Name || existing.Recurrence != holidays[i].Recurrence ||
+				existing.EasterOffset != holidays[i].EasterOffset:
+				existing.Name = holidays[i].Name
+				existing.Recurrence = holidays[i].Recurrence
+				existing.EasterOffset = holidays[i].EasterOffset
+				if err := tx.Save(&existing).Error; err != nil {
+					return err
+				}
+				holidays[i] = existing
+				result.Updated++
+			default:
+				holidays[i] = existing
+				result.Unchanged++
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (r *repository) FindTeamHolidayPolicy(team entity.TeamName) (*entity.TeamHolidayPolicy, error) {
+	var policy entity.TeamHolidayPolicy
+	if err := r.db.Where("team = ?", team).First(&policy).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (r *repository) SaveTeamHolidayPolicy(policy *entity.TeamHolidayPolicy) error {
+	return r.db.Save(policy).Error
+}
+
+func (r *repository) FindTeamOptionalHolidays(team entity.TeamName) ([]entity.Holiday, error) {
+	var holidays []entity.Holiday
+	err := r.db.Joins("JOIN team_optional_holidays ON team_optional_holidays.holiday_id = holidays.id").
+		Where("team_optional_holidays.team = ?", team).
+		Order("holidays.date asc").
+		Find(&holidays).Error
+	return holidays, err
+}
+
+// AddTeamOptionalHoliday opts the team into an optional holiday; doing it
+// twice is a no-op.
+func (r *repository) AddTeamOptionalHoliday(team entity.TeamName, holidayID uint) error {
+	optIn := entity.TeamOptionalHoliday{Team: team, HolidayID: holidayID}
+	return r.db.Where(optIn).FirstOrCreate(&optIn).Error
+}
+
+func (r *repository) RemoveTeamOptionalHoliday(team entity.TeamName, holidayID uint) error {
+	return r.db.Where("team = ? AND holiday_id = ?", team, holidayID).Delete(&entity.TeamOptionalHoliday{}).Error
 }
@@ -3,6 +3,7 @@ package holiday
 import (
 	"escala-fds-api/internal/entity"
 	"escala-fds-api/pkg/ierr"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -15,6 +16,13 @@ type Service interface {
 	FindHolidaysByDateRange(startDate, endDate time.Time) ([]entity.Holiday, *ierr.RestErr) // Novo método
 	UpdateHoliday(id uint, holidayData entity.Holiday) (*entity.Holiday, *ierr.RestErr)
 	DeleteHoliday(id uint) *ierr.RestErr
+	ImportHolidays(holidays []entity.Holiday) (*ImportHolidaysResponse, *ierr.RestErr)
+	GenerateNationalHolidays(year int) (*ImportHolidaysResponse, *ierr.RestErr)
+	FindTeamPolicy(team entity.TeamName) (*entity.TeamHolidayPolicy, *ierr.RestErr)
+	UpdateTeamPolicy(policy entity.TeamHolidayPolicy, requestorType entity.UserType) (*entity.TeamHolidayPolicy, *ierr.RestErr)
+	FindTeamOptionalHolidays(team entity.TeamName) ([]entity.Holiday, *ierr.RestErr)
+	OptIntoHoliday(team entity.TeamName, holidayID uint, requestorType entity.UserType) *ierr.RestErr
+	OptOutOfHoliday(team entity.TeamName, holidayID uint, requestorType entity.UserType) *ierr.RestErr
 }
 
 type service struct {
@@ -26,10 +34,14 @@ func NewService(repo Repository) Service {
 }
 
 func (s *service) CreateHoliday(holiday entity.Holiday) (*entity.Holiday, *ierr.RestErr) {
+	if err := validateLocation(&holiday); err != nil {
+		return nil, err
+	}
+	setEasterOffset(&holiday)
 	// A lógica de adicionar 12 horas foi movida para o DTO de request
 	if err := s.repo.CreateHoliday(&holiday); err != nil {
 		// Checar por erro de duplicidade
-		if err.Error() == "Error 1062: Duplicate entry" {
+		if err == ErrHolidayExists {
 			return nil, ierr.NewConflictError("Holiday on this date already exists")
 		}
 		return nil, ierr.NewInternalServerError("error creating holiday")
@@ -70,9 +82,17 @@ func (s *service) UpdateHoliday(id uint, holidayData entity.Holiday) (*entity.Ho
 		return nil, restErr
 	}
 
+	if err := validateLocation(&holidayData); err != nil {
+		return nil, err
+	}
 	holiday.Name = holidayData.Name
 	holiday.Date = holidayData.Date
 	holiday.Type = holidayData.Type
+	holiday.State = holidayData.State
+	holiday.City = holidayData.City
+	holiday.Recurrence = holidayData.Recurrence
+	holiday.Optional = holidayData.Optional
+	setEasterOffset(holiday)
 
 	if err := s.repo.UpdateHoliday(holiday); err != nil {
 		return nil, ierr.NewInternalServerError("error updating holiday")
@@ -89,3 +109,134 @@ func (s *service) DeleteHoliday(id uint) *ierr.RestErr {
 	}
 	return nil
 }
+
+// ImportHolidays upserts the holidays read from a calendar file.
+func (s *service) ImportHolidays(holidays []entity.Holiday) (*ImportHolidaysResponse, *ierr.RestErr) {
+	for i := range holidays {
+		if err := validateLocation(&holidays[i]); err != nil {
+			return nil, err
+		}
+		setEasterOffset(&holidays[i])
+	}
+	result, err := s.repo.UpsertHolidays(holidays)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error importing holidays")
+	}
+	response := ToImportHolidaysResponse(result, holidays)
+	return &response, nil
+}
+
+// GenerateNationalHolidays upserts the Brazilian national holidays of year.
+func (s *service) GenerateNationalHolidays(year int) (*ImportHolidaysResponse, *ierr.RestErr) {
+	return s.ImportHolidays(NationalHolidays(year))
+}
+
+// FindTeamPolicy returns the team's holiday policy, or the default (off,
+// without duty rotation) when none was set.
+func (s *service) FindTeamPolicy(team entity.TeamName) (*entity.TeamHolidayPolicy, *ierr.RestErr) {
+	policy, err := s.repo.FindTeamHolidayPolicy(team)
+	if err == gorm.ErrRecordNotFound {
+		defaultPolicy := entity.DefaultTeamHolidayPolicy(team)
+		return &defaultPolicy, nil
+	}
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding holiday policy")
+	}
+	return policy, nil
+}
+
+func (s *service) UpdateTeamPolicy(policy entity.TeamHolidayPolicy, requestorType entity.UserType) (*entity.TeamHolidayPolicy, *ierr.RestErr) {
+	if requestorType != entity.UserTypeMaster {
+		return nil, ierr.NewForbiddenError("only masters can change holiday policies")
+	}
+	if policy.DutyCount > 0 && policy.Policy != entity.HolidayPolicyOff {
+		return nil, ierr.NewBadRequestValidationError("invalid holiday policy", []ierr.Causes{
+			{Field: "dutyCount", Message: "a duty rotation only applies to teams whose holidays are off"},
+		})
+	}
+	if err := s.repo.SaveTeamHolidayPolicy(&policy); err != nil {
+		return nil, ierr.NewInternalServerError("error saving holiday policy")
+	}
+	return &policy, nil
+}
+
+// FindTeamOptionalHolidays lists the optional holidays the team observes.
+func (s *service) FindTeamOptionalHolidays(team entity.TeamName) ([]entity.Holiday, *ierr.RestErr) {
+	holidays, err := s.repo.FindTeamOptionalHolidays(team)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding optional holidays")
+	}
+	return holidays, nil
+}
+
+func (s *service) OptIntoHoliday(team entity.TeamName, holidayID uint, requestorType entity.UserType) *ierr.RestErr {
+	if requestorType != entity.UserTypeMaster {
+		return ierr.NewForbiddenError("only masters can change the optional holidays of a team")
+	}
+	holiday, restErr := s.FindHolidayByID(holidayID)
+	if restErr != nil {
+		return restErr
+	}
+	if !holiday.Optional {
+		return ierr.NewBadRequestError("only optional holidays can be opted into")
+	}
+	if err := s.repo.AddTeamOptionalHoliday(team, holidayID); err != nil {
+		return ierr.NewInternalServerError("error saving optional holiday")
+	}
+	return nil
+}
+
+func (s *service) OptOutOfHoliday(team entity.TeamName, holidayID uint, requestorType entity.UserType) *ierr.RestErr {
+	if requestorType != entity.UserTypeMaster {
+		return ierr.NewForbiddenError("only masters can change the optional holidays of a team")
+	}
+	if err := s.repo.RemoveTeamOptionalHoliday(team, holidayID); err != nil {
+		return ierr.NewInternalServerError("error removing optional holiday")
+	}
+	return nil
+}
+
+// setEasterOffset keeps the distance from Easter of the first occurrence of
+// easter recurrences, so that Date alone defines the rule.
+func setEasterOffset(holiday *entity.Holiday) {
+	holiday.EasterOffset = 0
+	if holiday.Recurrence == entity.HolidayRecurrenceEaster {
+		easter := entity.Easter(holiday.Date.Year())
+		first := time.Date(holiday.Date.Year(), holiday.Date.Month(), holiday.Date.Day(), 0, 0, 0, 0, time.UTC)
+		holiday.EasterOffset = int(first.Sub(easter).Hours() / 24)
+	}
+}
+
+// validateLocation normalizes the location of a holiday and checks it
+// matches its type: national holidays have none, state holidays a state and
+// city holidays both a state and a city.
+func validateLocation(holiday *entity.Holiday) *ierr.RestErr {
+	holiday.State = strings.ToUpper(strings.TrimSpace(holiday.State))
+	holiday.City = strings.TrimSpace(holiday.City)
+
+	var causes []ierr.Causes
+	switch holiday.Type {
+	case entity.HolidayTypeNational:
+		if holiday.State != "" || holiday.City != "" {
+			causes = append(causes, ierr.Causes{Field: "state", Message: "must be empty for national holidays"})
+		}
+	case entity.HolidayTypeState:
+		if len(holiday.State) != 2 {
+			causes = append(causes, ierr.Causes{Field: "state", Message: "is required for state holidays"})
+		}
+		if holiday.City != "" {
+			causes = append(causes, ierr.Causes{Field: "city", Message: "must be empty for state holidays"})
+		}
+	case entity.HolidayTypeCity:
+		if len(holiday.State) != 2 {
+			causes = append(causes, ierr.Causes{Field: "state", Message: "is required for city holidays"})
+		}
+		if holiday.City == "" {
+			causes = append(causes, ierr.Causes{Field: "city", Message: "is required for city holidays"})
+		}
+	}
+	if len(causes) > 0 {
+		return ierr.NewBadRequestValidationError("invalid holiday location", causes)
+	}
+	return nil
+}
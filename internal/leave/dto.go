@@ -0,0 +1,57 @@
+package leave
+
+import (
+	"escala-fds-api/internal/constants"
+	"escala-fds-api/internal/entity"
+)
+
+type AccrualRuleRequest struct {
+	Team        entity.TeamName     `json:"team" binding:"required,team"`
+	Position    entity.PositionName `json:"position"`
+	DaysPerYear int                 `json:"daysPerYear" binding:"min=0,max=60"`
+}
+
+type AccrualRuleResponse struct {
+	ID          uint                `json:"id"`
+	Team        entity.TeamName     `json:"team"`
+	Position    entity.PositionName `json:"position,omitempty"`
+	DaysPerYear int                 `json:"daysPerYear"`
+}
+
+type TransactionResponse struct {
+	ID            uint                        `json:"id"`
+	Kind          entity.LeaveTransactionKind `json:"kind"`
+	Days          int                         `json:"days"`
+	CertificateID *uint                       `json:"certificateId,omitempty"`
+	Description   string                      `json:"description"`
+	CreatedAt     string                      `json:"createdAt"`
+}
+
+type BalanceResponse struct {
+	UserID       uint                  `json:"userId"`
+	Year         int                   `json:"year"`
+	Accrued      int                   `json:"accrued"`
+	Used         int                   `json:"used"`
+	Balance      int                   `json:"balance"`
+	Transactions []TransactionResponse `json:"transactions"`
+}
+
+func ToAccrualRuleResponse(rule *entity.LeaveAccrualRule) AccrualRuleResponse {
+	return AccrualRuleResponse{
+		ID:          rule.ID,
+		Team:        rule.Team,
+		Position:    rule.Position,
+		DaysPerYear: rule.DaysPerYear,
+	}
+}
+
+func ToTransactionResponse(tx *entity.LeaveTransaction) TransactionResponse {
+	return TransactionResponse{
+		ID:            tx.ID,
+		Kind:          tx.Kind,
+		Days:          tx.Days,
+		CertificateID: tx.CertificateID,
+		Description:   tx.Description,
+		CreatedAt:     tx.CreatedAt.Format(constants.ApiTimestampLayout),
+	}
+}
@@ -0,0 +1,117 @@
+package leave
+
+import (
+	"escala-fds-api/internal/auth"
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/validation"
+	"escala-fds-api/pkg/ierr"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	userRoutes := router.Group("/users")
+	userRoutes.Use(auth.Middleware(), auth.RequireScope("users"))
+	{
+		userRoutes.GET("/:id/leave-balance", h.GetBalance)
+	}
+
+	ruleRoutes := router.Group("/leave/accrual-rules")
+	ruleRoutes.Use(auth.Middleware(), auth.RequireScope("users"))
+	{
+		ruleRoutes.GET("", h.FindAccrualRules)
+		ruleRoutes.PUT("", h.SaveAccrualRule)
+		ruleRoutes.DELETE("/:id", h.DeleteAccrualRule)
+	}
+}
+
+func (h *Handler) GetBalance(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	requestorID, errAuth := auth.GetUserIDFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+
+	year := time.Now().UTC().Year()
+	if yearStr := c.Query("year"); yearStr != "" {
+		parsed, err := strconv.Atoi(yearStr)
+		if err != nil || parsed < 2000 || parsed > 2100 {
+			restErr := ierr.NewBadRequestValidationError("some fields are invalid", []ierr.Causes{
+				{Field: "year", Message: "must be a valid year"},
+			})
+			c.JSON(restErr.Code, restErr)
+			return
+		}
+		year = parsed
+	}
+
+	balance, err := h.service.GetBalance(uint(id), year, requestorID, entity.UserType(requestorType))
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusOK, balance)
+}
+
+func (h *Handler) FindAccrualRules(c *gin.Context) {
+	rules, err := h.service.FindAccrualRules()
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+func (h *Handler) SaveAccrualRule(c *gin.Context) {
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+
+	var req AccrualRuleRequest
+	if errBind := validation.BindJSON(c, &req); errBind != nil {
+		c.JSON(errBind.Code, errBind)
+		return
+	}
+
+	rule := entity.LeaveAccrualRule{Team: req.Team, Position: req.Position, DaysPerYear: req.DaysPerYear}
+	saved, err := h.service.SaveAccrualRule(rule, entity.UserType(requestorType))
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusOK, saved)
+}
+
+func (h *Handler) DeleteAccrualRule(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+
+	if err := h.service.DeleteAccrualRule(uint(id), entity.UserType(requestorType)); err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
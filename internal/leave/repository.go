@@ -0,0 +1,111 @@
+package leave
+
+import (
+	"escala-fds-api/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	FindAccrualRule(team entity.TeamName, position entity.PositionName) (*entity.LeaveAccrualRule, error)
+	FindAccrualRuleByID(id uint) (*entity.LeaveAccrualRule, error)
+	FindAllAccrualRules() ([]entity.LeaveAccrualRule, error)
+	SaveAccrualRule(rule *entity.LeaveAccrualRule) error
+	DeleteAccrualRule(id uint) error
+	FindBalance(userID uint, year int) (*entity.LeaveBalance, error)
+	CreateBalance(balance *entity.LeaveBalance, accrual *entity.LeaveTransaction) error
+	ApplyTransactions(changes []LedgerChange) error
+	FindTransactions(userID uint, year int) ([]entity.LeaveTransaction, error)
+	FindTransactionsByCertificate(certificateID uint) ([]entity.LeaveTransaction, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) FindAccrualRule(team entity.TeamName, position entity.PositionName) (*entity.LeaveAccrualRule, error) {
+	var rule entity.LeaveAccrualRule
+	err := r.db.Where("team = ? AND position = ?", team, position).First(&rule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *repository) FindAccrualRuleByID(id uint) (*entity.LeaveAccrualRule, error) {
+	var rule entity.LeaveAccrualRule
+	if err := r.db.First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *repository) FindAllAccrualRules() ([]entity.LeaveAccrualRule, error) {
+	var rules []entity.LeaveAccrualRule
+	err := r.db.Order("team asc, position asc").Find(&rules).Error
+	return rules, err
+}
+
+func (r *repository) SaveAccrualRule(rule *entity.LeaveAccrualRule) error {
+	return r.db.Save(rule).Error
+}
+
+func (r *repository) DeleteAccrualRule(id uint) error {
+	return r.db.Unscoped().Delete(&entity.LeaveAccrualRule{}, id).Error
+}
+
+func (r *repository) FindBalance(userID uint, year int) (*entity.LeaveBalance, error) {
+	var balance entity.LeaveBalance
+	if err := r.db.Where("user_id = ? AND year = ?", userID, year).First(&balance).Error; err != nil {
+		return nil, err
+	}
+	return &balance, nil
+}
+
+func (r *repository) CreateBalance(balance *entity.LeaveBalance, accrual *entity.LeaveTransaction) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(balance).Error; err != nil {
+			return err
+		}
+		return tx.Create(accrual).Error
+	})
+}
+
+// LedgerChange is a ledger entry and the balance it results in.
+type LedgerChange struct {
+	Balance *entity.LeaveBalance
+	Entry   *entity.LeaveTransaction
+}
+
+// ApplyTransactions records the ledger entries and the resulting balances in
+// a single database transaction, so that a change spanning several years is
+// stored entirely or not at all.
+func (r *repository) ApplyTransactions(changes []LedgerChange) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, change := range changes {
+			if err := tx.Create(change.Entry).Error; err != nil {
+				return err
+			}
+			if err := tx.Save(change.Balance).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *repository) FindTransactions(userID uint, year int) ([]entity.LeaveTransaction, error) {
+	var transactions []entity.LeaveTransaction
+	err := r.db.Where("user_id = ? AND year = ?", userID, year).Order("created_at asc, id asc").Find(&transactions).Error
+	return transactions, err
+}
+
+func (r *repository) FindTransactionsByCertificate(certificateID uint) ([]entity.LeaveTransaction, error) {
+	var transactions []entity.LeaveTransaction
+	err := r.db.Where("certificate_id = ?", certificateID).Order("id asc").Find(&transactions).Error
+	return transactions, err
+}
@@ -0,0 +1,256 @@
+package leave
+
+import (
+	"escala-fds-api/internal/constants"
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/user"
+	"escala-fds-api/pkg/ierr"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// defaultDaysPerYear is the CLT vacation entitlement, used when no accrual
+// rule is configured for the collaborator's team and position.
+const defaultDaysPerYear = 30
+
+type Service interface {
+	GetBalance(userID uint, year int, requestorID uint, requestorType entity.UserType) (*BalanceResponse, *ierr.RestErr)
+	DebitForCertificate(certificate *entity.Certificate) *ierr.RestErr
+	ReverseForCertificate(certificate *entity.Certificate, reason string) *ierr.RestErr
+	FindAccrualRules() ([]AccrualRuleResponse, *ierr.RestErr)
+	SaveAccrualRule(rule entity.LeaveAccrualRule, requestorType entity.UserType) (*AccrualRuleResponse, *ierr.RestErr)
+	DeleteAccrualRule(id uint, requestorType entity.UserType) *ierr.RestErr
+}
+
+type service struct {
+	repo     Repository
+	userRepo user.Repository
+}
+
+func NewService(repo Repository, userRepo user.Repository) Service {
+	return &service{repo: repo, userRepo: userRepo}
+}
+
+func (s *service) GetBalance(userID uint, year int, requestorID uint, requestorType entity.UserType) (*BalanceResponse, *ierr.RestErr) {
+	owner, err := s.userRepo.FindUserByID(userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ierr.NewNotFoundError("user not found")
+		}
+		return nil, ierr.NewInternalServerError("error finding user")
+	}
+	isSuperior := owner.SuperiorID != nil && *owner.SuperiorID == requestorID
+	if requestorType != entity.UserTypeMaster && userID != requestorID && !isSuperior {
+		return nil, ierr.NewForbiddenError("you do not have permission to view this leave balance")
+	}
+
+	balance, restErr := s.ensureBalance(owner, year)
+	if restErr != nil {
+		return nil, restErr
+	}
+	transactions, err := s.repo.FindTransactions(userID, year)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding leave transactions")
+	}
+
+	response := &BalanceResponse{
+		UserID:       userID,
+		Year:         year,
+		Accrued:      balance.Accrued,
+		Used:         balance.Used,
+		Balance:      balance.Balance,
+		Transactions: make([]TransactionResponse, 0, len(transactions)),
+	}
+	for i := range transactions {
+		response.Transactions = append(response.Transactions, ToTransactionResponse(&transactions[i]))
+	}
+	return response, nil
+}
+
+// DebitForCertificate charges the certificate days against the balance of
+// each calendar year the absence touches. It fails without side effects when
+// any of those balances would become negative.
+func (s *service) DebitForCertificate(certificate *entity.Certificate) *ierr.RestErr {
+	owner, err := s.userRepo.FindUserByID(certificate.CollaboratorID)
+	if err != nil {
+		return ierr.NewInternalServerError("error finding certificate owner")
+	}
+
+	daysByYear := splitDaysByYear(certificate.StartDate, certificate.EndDate)
+	balances := make(map[int]*entity.LeaveBalance, len(daysByYear))
+	for year, days := range daysByYear {
+		balance, restErr := s.ensureBalance(owner, year)
+		if restErr != nil {
+			return restErr
+		}
+		if balance.Balance < days {
+			return ierr.NewConflictError(fmt.Sprintf("insufficient leave balance for %d: %d days available, %d requested", year, balance.Balance, days))
+		}
+		balances[year] = balance
+	}
+
+	var changes []LedgerChange
+	for year, days := range daysByYear {
+		balance := balances[year]
+		balance.Used += days
+		balance.Balance -= days
+		certificateID := certificate.ID
+		ledgerEntry := &entity.LeaveTransaction{
+			UserID:        owner.ID,
+			Year:          year,
+			Kind:          entity.LeaveTransactionDebit,
+			Days:          -days,
+			CertificateID: &certificateID,
+			Description: fmt.Sprintf("%s from %s to %s", certificate.Type,
+				certificate.StartDate.Format(constants.ApiDateLayout),
+				certificate.EndDate.Format(constants.ApiDateLayout)),
+		}
+		changes = append(changes, LedgerChange{Balance: balance, Entry: ledgerEntry})
+	}
+	if err := s.repo.ApplyTransactions(changes); err != nil {
+		return ierr.NewInternalServerError("error debiting leave balance")
+	}
+	return nil
+}
+
+// ReverseForCertificate credits back whatever is still debited for the
+// certificate. It is a no-op when nothing was debited.
+func (s *service) ReverseForCertificate(certificate *entity.Certificate, reason string) *ierr.RestErr {
+	transactions, err := s.repo.FindTransactionsByCertificate(certificate.ID)
+	if err != nil {
+		return ierr.NewInternalServerError("error finding leave transactions")
+	}
+	netByYear := make(map[int]int)
+	for _, tx := range transactions {
+		netByYear[tx.Year] += tx.Days
+	}
+
+	var changes []LedgerChange
+	for year, net := range netByYear {
+		if net >= 0 {
+			continue
+		}
+		balance, err := s.repo.FindBalance(certificate.CollaboratorID, year)
+		if err != nil {
+			return ierr.NewInternalServerError("error finding leave balance")
+		}
+		balance.Used += net
+		balance.Balance -= net
+		certificateID := certificate.ID
+		ledgerEntry := &entity.LeaveTransaction{
+			UserID:        certificate.CollaboratorID,
+			Year:          year,
+			Kind:          entity.LeaveTransactionReversal,
+			Days:          -net,
+			CertificateID: &certificateID,
+			Description:   fmt.Sprintf("certificate #%d %s", certificate.ID, reason),
+		}
+		changes = append(changes, LedgerChange{Balance: balance, Entry: ledgerEntry})
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	if err := s.repo.ApplyTransactions(changes); err != nil {
+		return ierr.NewInternalServerError("error reversing leave debit")
+	}
+	return nil
+}
+
+func (s *service) FindAccrualRules() ([]AccrualRuleResponse, *ierr.RestErr) {
+	rules, err := s.repo.FindAllAccrualRules()
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding accrual rules")
+	}
+	responses := make([]AccrualRuleResponse, 0, len(rules))
+	for i := range rules {
+		responses = append(responses, ToAccrualRuleResponse(&rules[i]))
+	}
+	return responses, nil
+}
+
+func (s *service) SaveAccrualRule(rule entity.LeaveAccrualRule, requestorType entity.UserType) (*AccrualRuleResponse, *ierr.RestErr) {
+	if requestorType != entity.UserTypeMaster {
+		return nil, ierr.NewForbiddenError("only masters can configure leave accrual")
+	}
+	existing, err := s.repo.FindAccrualRule(rule.Team, rule.Position)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, ierr.NewInternalServerError("error finding accrual rule")
+	}
+	if existing != nil {
+		existing.DaysPerYear = rule.DaysPerYear
+		rule = *existing
+	}
+	if err := s.repo.SaveAccrualRule(&rule); err != nil {
+		return nil, ierr.NewInternalServerError("error saving accrual rule")
+	}
+	response := ToAccrualRuleResponse(&rule)
+	return &response, nil
+}
+
+func (s *service) DeleteAccrualRule(id uint, requestorType entity.UserType) *ierr.RestErr {
+	if requestorType != entity.UserTypeMaster {
+		return ierr.NewForbiddenError("only masters can configure leave accrual")
+	}
+	if _, err := s.repo.FindAccrualRuleByID(id); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ierr.NewNotFoundError("accrual rule not found")
+		}
+		return ierr.NewInternalServerError("error finding accrual rule")
+	}
+	if err := s.repo.DeleteAccrualRule(id); err != nil {
+		return ierr.NewInternalServerError("error deleting accrual rule")
+	}
+	return nil
+}
+
+// ensureBalance returns the user's balance for the year, opening it with the
+// yearly accrual the first time it is needed.
+func (s *service) ensureBalance(owner *entity.User, year int) (*entity.LeaveBalance, *ierr.RestErr) {
+	balance, err := s.repo.FindBalance(owner.ID, year)
+	if err == nil {
+		return balance, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, ierr.NewInternalServerError("error finding leave balance")
+	}
+
+	days, restErr := s.accrualFor(owner)
+	if restErr != nil {
+		return nil, restErr
+	}
+	balance = &entity.LeaveBalance{UserID: owner.ID, Year: year, Accrued: days, Balance: days}
+	accrual := &entity.LeaveTransaction{
+		UserID:      owner.ID,
+		Year:        year,
+		Kind:        entity.LeaveTransactionAccrual,
+		Days:        days,
+		Description: fmt.Sprintf("yearly accrual for %d", year),
+	}
+	if err := s.repo.CreateBalance(balance, accrual); err != nil {
+		return nil, ierr.NewInternalServerError("error opening leave balance")
+	}
+	return balance, nil
+}
+
+func (s *service) accrualFor(owner *entity.User) (int, *ierr.RestErr) {
+	for _, position := range []entity.PositionName{owner.Position, ""} {
+		rule, err := s.repo.FindAccrualRule(owner.Team, position)
+		if err == nil {
+			return rule.DaysPerYear, nil
+		}
+		if err != gorm.ErrRecordNotFound {
+			return 0, ierr.NewInternalServerError("error finding accrual rule")
+		}
+	}
+	return defaultDaysPerYear, nil
+}
+
+func splitDaysByYear(startDate, endDate time.Time) map[int]int {
+	days := make(map[int]int)
+	for date := startDate; !date.After(endDate); date = date.AddDate(0, 0, 1) {
+		days[date.Year()]++
+	}
+	return days
+}
@@ -0,0 +1,159 @@
+// Package pdf is a small PDF 1.4 writer for text, lines and filled
+// rectangles using the standard Helvetica fonts, enough to print tables
+// without external tools.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Page sizes in points.
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+type Document struct {
+	width  float64
+	height float64
+	pages  []*Page
+}
+
+// Page collects drawing operations. Coordinates are in points from the
+// top-left corner of the page.
+type Page struct {
+	height  float64
+	content bytes.Buffer
+}
+
+func New(width, height float64) *Document {
+	return &Document{width: width, height: height}
+}
+
+func (d *Document) AddPage() *Page {
+	page := &Page{height: d.height}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// SetFillGray sets the fill color used by Rect and Text, 0 is black and 1
+// white.
+func (p *Page) SetFillGray(gray float64) {
+	fmt.Fprintf(&p.content, "%.3f g\n", gray)
+}
+
+func (p *Page) SetFillRGB(r, g, b float64) {
+	fmt.Fprintf(&p.content, "%.3f %.3f %.3f rg\n", r, g, b)
+}
+
+func (p *Page) SetStrokeGray(gray float64) {
+	fmt.Fprintf(&p.content, "%.3f G\n", gray)
+}
+
+func (p *Page) SetLineWidth(width float64) {
+	fmt.Fprintf(&p.content, "%.2f w\n", width)
+}
+
+// Rect draws a rectangle whose top-left corner is at x, y, filled with the
+// current fill color or stroked.
+func (p *Page) Rect(x, y, w, h float64, fill bool) {
+	op := "S"
+	if fill {
+		op = "f"
+	}
+	fmt.Fprintf(&p.content, "%.2f %.2f %.2f %.2f re %s\n", x, p.height-y-h, w, h, op)
+}
+
+func (p *Page) Line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(&p.content, "%.2f %.2f m %.2f %.2f l S\n", x1, p.height-y1, x2, p.height-y2)
+}
+
+// Text writes s with its baseline at y.
+func (p *Page) Text(x, y, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(&p.content, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, p.height-y, escape(s))
+}
+
+// TextCentered writes s centered horizontally on x.
+func (p *Page) TextCentered(x, y, size float64, bold bool, s string) {
+	p.Text(x-TextWidth(s, size)/2, y, size, bold, s)
+}
+
+// TextWidth estimates the width of s in Helvetica. Digits and upper-case
+// letters are wider than the average, which is good enough for centering.
+func TextWidth(s string, size float64) float64 {
+	width := 0.0
+	for _, r := range s {
+		switch {
+		case r == ' ' || r == '.' || r == ':' || r == 'i' || r == 'l':
+			width += 0.28
+		case r >= 'A' && r <= 'Z':
+			width += 0.67
+		default:
+			width += 0.55
+		}
+	}
+	return width * size
+}
+
+// escape encodes s as a PDF literal string in WinAnsiEncoding. Runes outside
+// Latin-1 are replaced with '?'.
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32:
+			b.WriteByte(' ')
+		case r < 128:
+			b.WriteRune(r)
+		case r < 256:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// WriteTo serializes the document. Objects are numbered as: catalog, page
+// tree, the two fonts, then a page and its content stream for each page.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", d.width, d.height, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.content.Len(), page.content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.WriteTo(w)
+}
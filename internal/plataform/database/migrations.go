@@ -0,0 +1,110 @@
+package database
+
+import (
+	"escala-fds-api/internal/entity"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// runDataMigrations applies the data changes that AutoMigrate cannot express.
+// Every step must be idempotent since it runs on each startup.
+func runDataMigrations(db *gorm.DB) error {
+	steps := []struct {
+		name string
+		run  func(*gorm.DB) error
+	}{
+		{"backfill work assignments", backfillWorkAssignments},
+		{"backfill weekend rotation anchors", backfillWeekendRotationAnchors},
+		{"drop superseded holiday indexes", dropHolidayDateIndexes},
+	}
+	for _, step := range steps {
+		if err := step.run(db); err != nil {
+			return fmt.Errorf("%s: %w", step.name, err)
+		}
+	}
+	return nil
+}
+
+// backfillWorkAssignments opens an assignment from the creation date for
+// every user that has none, using the work data currently on the user.
+func backfillWorkAssignments(db *gorm.DB) error {
+	var users []entity.User
+	err := db.Where("NOT EXISTS (SELECT 1 FROM work_assignments wa WHERE wa.user_id = users.id AND wa.deleted_at IS NULL)").
+		Find(&users).Error
+	if err != nil {
+		return err
+	}
+	for i := range users {
+		created := users[i].CreatedAt.UTC()
+		validFrom := time.Date(created.Year(), created.Month(), created.Day(), 0, 0, 0, 0, time.UTC)
+		assignment := entity.NewWorkAssignment(&users[i], validFrom)
+		if err := db.Create(&assignment).Error; err != nil {
+			return err
+		}
+	}
+	if len(users) > 0 {
+		log.Printf("backfilled work assignments for %d users", len(users))
+	}
+	return nil
+}
+
+// backfillWeekendRotationAnchors pins the alternating weekend of existing
+// users and assignments to the anchor previously derived from the user's
+// creation date, so later re-creation or imports don't move it. The anchors
+// are marked with LegacyWeekendCount so their days off stay the same.
+func backfillWeekendRotationAnchors(db *gorm.DB) error {
+	var users []entity.User
+	err := db.Where("weekend_rotation_anchor IS NULL AND initial_weekend_off <> ''").Find(&users).Error
+	if err != nil {
+		return err
+	}
+	for _, u := range users {
+		anchor := entity.FirstWeekendOff(u.CreatedAt, u.InitialWeekendOff)
+		if err := db.Model(&entity.User{}).Where("id = ?", u.ID).Updates(map[string]interface{}{
+			"weekend_rotation_anchor": anchor,
+			"legacy_weekend_count":    true,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	var assignments []entity.WorkAssignment
+	err = db.Where("weekend_rotation_anchor IS NULL AND initial_weekend_off <> ''").Find(&assignments).Error
+	if err != nil {
+		return err
+	}
+	for _, a := range assignments {
+		var owner entity.User
+		if err := db.Unscoped().Select("id", "created_at").First(&owner, a.UserID).Error; err != nil {
+			return err
+		}
+		anchor := entity.FirstWeekendOff(owner.CreatedAt, a.InitialWeekendOff)
+		if err := db.Model(&entity.WorkAssignment{}).Where("id = ?", a.ID).Updates(map[string]interface{}{
+			"weekend_rotation_anchor": anchor,
+			"legacy_weekend_count":    true,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	if len(users)+len(assignments) > 0 {
+		log.Printf("backfilled weekend rotation anchors for %d users and %d assignments", len(users), len(assignments))
+	}
+	return nil
+}
+
+// dropHolidayDateIndexes removes the former unique indexes on holidays.date
+// and on date and type, replaced by the one on date, type and location.
+func dropHolidayDateIndexes(db *gorm.DB) error {
+	for _, name := range []string{"idx_holidays_date", "idx_holidays_date_type"} {
+		if !db.Migrator().HasIndex(&entity.Holiday{}, name) {
+			continue
+		}
+		if err := db.Migrator().DropIndex(&entity.Holiday{}, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
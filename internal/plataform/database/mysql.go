@@ -47,13 +47,32 @@ func NewMySQLConnection() (*gorm.DB, error) {
 	err = db.AutoMigrate(
 		&entity.User{},
 		&entity.Swap{},
+		&entity.ShiftOffer{},
 		&entity.Comment{},
 		&entity.Holiday{},
 		&entity.Certificate{},
+		&entity.APIToken{},
+		&entity.LeaveAccrualRule{},
+		&entity.LeaveBalance{},
+		&entity.LeaveTransaction{},
+		&entity.ShiftTemplate{},
+		&entity.RotationPattern{},
+		&entity.EscalaVersion{},
+		&entity.EscalaEntry{},
+		&entity.WorkAssignment{},
+		&entity.Site{},
+		&entity.TeamSite{},
+		&entity.TeamHolidayPolicy{},
+		&entity.TeamOptionalHoliday{},
+		&entity.TeamRuleConfig{},
+		&entity.AuditEntry{},
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to auto-migrate database: %w", err)
 	}
+	if err := runDataMigrations(db); err != nil {
+		return nil, fmt.Errorf("failed to run data migrations: %w", err)
+	}
 
 	return db, nil
 }
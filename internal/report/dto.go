@@ -0,0 +1,30 @@
+package report
+
+import (
+	"escala-fds-api/internal/entity"
+)
+
+type HoursRow struct {
+	UserID              uint                `json:"userId"`
+	Name                string              `json:"name"`
+	Position            entity.PositionName `json:"position"`
+	WorkedDays          int                 `json:"workedDays"`
+	AbsenceDays         int                 `json:"absenceDays"`
+	ScheduledHours      float64             `json:"scheduledHours"`
+	HolidayHours        float64             `json:"holidayHours"`
+	HolidayPremiumHours float64             `json:"holidayPremiumHours"`
+	NightHours          float64             `json:"nightHours"`
+	NightHoursReduced   float64             `json:"nightHoursReduced"`
+	WorkedHours         float64             `json:"workedHours"`
+	PaidHours           float64             `json:"paidHours"`
+	ContractHours       float64             `json:"contractHours"`
+	OvertimeHours       float64             `json:"overtimeHours"`
+	RuleOverrides       int                 `json:"ruleOverrides"`
+}
+
+type HoursReport struct {
+	Team                entity.TeamName `json:"team"`
+	Month               string          `json:"month"`
+	ContractWeeklyHours float64         `json:"contractWeeklyHours"`
+	Rows                []HoursRow      `json:"rows"`
+}
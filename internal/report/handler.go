@@ -0,0 +1,104 @@
+package report
+
+import (
+	"escala-fds-api/internal/auth"
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/export"
+	"escala-fds-api/internal/validation"
+	"escala-fds-api/pkg/ierr"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	reportRoutes := router.Group("/reports")
+	reportRoutes.Use(auth.Middleware(), auth.RequireScope("reports"))
+	{
+		reportRoutes.GET("/hours", h.Hours)
+	}
+}
+
+func (h *Handler) Hours(c *gin.Context) {
+	requestorID, errAuth := auth.GetUserIDFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	team := entity.TeamName(c.Query("team"))
+	if !team.IsValid() {
+		restErr := ierr.NewBadRequestValidationError("some fields are invalid", []ierr.Causes{
+			{Field: "team", Message: "must be a valid team: Security, Support, CustomerService"},
+		})
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	month, restErr := validation.ParseMonth("month", c.Query("month"))
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+
+	format, restErr := export.FormatFromRequest(c)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+
+	report, err := h.service.Hours(team, month, requestorID)
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	if format != export.FormatJSON {
+		filename := fmt.Sprintf("hours-%s-%s", report.Team, report.Month)
+		export.Write(c, format, filename, hoursExportHeader, func(w export.RowWriter) error {
+			for i := range report.Rows {
+				if err := w.WriteRow(report.Rows[i].exportRow()); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+var hoursExportHeader = []string{"userId", "name", "position", "workedDays", "absenceDays", "scheduledHours",
+	"holidayHours", "holidayPremiumHours", "nightHours", "nightHoursReduced", "workedHours", "paidHours", "contractHours", "overtimeHours",
+	"ruleOverrides"}
+
+func (r *HoursRow) exportRow() []string {
+	return []string{
+		strconv.FormatUint(uint64(r.UserID), 10),
+		r.Name,
+		string(r.Position),
+		strconv.Itoa(r.WorkedDays),
+		strconv.Itoa(r.AbsenceDays),
+		formatHours(r.ScheduledHours),
+		formatHours(r.HolidayHours),
+		formatHours(r.HolidayPremiumHours),
+		formatHours(r.NightHours),
+		formatHours(r.NightHoursReduced),
+		formatHours(r.WorkedHours),
+		formatHours(r.PaidHours),
+		formatHours(r.ContractHours),
+		formatHours(r.OvertimeHours),
+		strconv.Itoa(r.RuleOverrides),
+	}
+}
+
+func formatHours(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
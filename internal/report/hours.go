@@ -0,0 +1,68 @@
+package report
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// ContractWeeklyHours is the standard CLT working week.
+	ContractWeeklyHours = 44.0
+	// NightHourFactor converts clock night hours to paid hours: a night hour
+	// lasts 52m30s ("hora noturna reduzida"), so 7 clock hours pay 8.
+	NightHourFactor = 60.0 / 52.5
+	// HolidayPremiumFactor pays holiday hours double, unless the holiday
+	// policy has them worked at the normal rate.
+	HolidayPremiumFactor = 2.0
+
+	nightStart = 22 * time.Hour
+	nightEnd   = 29 * time.Hour // 05:00 on the following day
+)
+
+// overlap returns how much of [aStart, aEnd) falls within [bStart, bEnd).
+func overlap(aStart, aEnd, bStart, bEnd time.Time) time.Duration {
+	start := aStart
+	if bStart.After(start) {
+		start = bStart
+	}
+	end := aEnd
+	if bEnd.Before(end) {
+		end = bEnd
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// nightDuration returns the part of a shift worked between 22:00 and 05:00.
+func nightDuration(start, end time.Time) time.Duration {
+	var total time.Duration
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+	for !day.After(end) {
+		total += overlap(start, end, day.Add(nightStart), day.Add(nightEnd))
+		day = day.AddDate(0, 0, 1)
+	}
+	return total
+}
+
+// holidayDuration returns the part of a shift worked on the given holidays.
+func holidayDuration(start, end time.Time, holidays map[time.Time]bool) time.Duration {
+	var total time.Duration
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	for !day.After(end) {
+		if holidays[day] {
+			total += overlap(start, end, day, day.AddDate(0, 0, 1))
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return total
+}
+
+func hours(d time.Duration) float64 {
+	return roundHours(d.Hours())
+}
+
+func roundHours(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,173 @@
+package report
+
+import (
+	"escala-fds-api/internal/certificate"
+	"escala-fds-api/internal/constants"
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/holiday"
+	"escala-fds-api/internal/schedule"
+	"escala-fds-api/internal/swap"
+	"escala-fds-api/internal/user"
+	"escala-fds-api/pkg/ierr"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Service interface {
+	Hours(team entity.TeamName, month time.Time, requestorID uint) (*HoursReport, *ierr.RestErr)
+}
+
+type service struct {
+	userRepo        user.Repository
+	holidayRepo     holiday.Repository
+	certificateRepo certificate.Repository
+	swapRepo        swap.Repository
+	schedule        schedule.Service
+}
+
+func NewService(userRepo user.Repository, holidayRepo holiday.Repository, certificateRepo certificate.Repository, swapRepo swap.Repository, scheduleService schedule.Service) Service {
+	return &service{
+		userRepo:        userRepo,
+		holidayRepo:     holidayRepo,
+		certificateRepo: certificateRepo,
+		swapRepo:        swapRepo,
+		schedule:        scheduleService,
+	}
+}
+
+// Hours computes, for every collaborator of the team, the hours scheduled in
+// the month after swaps and approved absences, how many of them fall on
+// holidays (and how many of those earn the holiday premium under the team's
+// holiday policy) and at night, and the overtime of the worked hours against
+// the contract week prorated to the days the collaborator was not absent. RuleOverrides counts
+// the swaps of the month a master approved despite blocking labor rules.
+func (s *service) Hours(team entity.TeamName, month time.Time, requestorID uint) (*HoursReport, *ierr.RestErr) {
+	if err := s.checkAccess(team, requestorID); err != nil {
+		return nil, err
+	}
+
+	start := schedule.DateOnly(month)
+	end := start.AddDate(0, 1, -1)
+	members, err := s.userRepo.FindUsersByTeam(team)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding team members")
+	}
+	report := &HoursReport{
+		Team:                team,
+		Month:               start.Format(constants.ApiMonthLayout),
+		ContractWeeklyHours: ContractWeeklyHours,
+		Rows:                []HoursRow{},
+	}
+	daysInMonth := end.Day()
+	for i := range members {
+		if members[i].UserType != entity.UserTypeCollaborator {
+			continue
+		}
+		row, restErr := s.hoursFor(&members[i], start, end)
+		if restErr != nil {
+			return nil, restErr
+		}
+		contract := ContractWeeklyHours / 7 * float64(daysInMonth-row.AbsenceDays)
+		row.ContractHours = roundHours(contract)
+		if row.WorkedHours > contract {
+			row.OvertimeHours = roundHours(row.WorkedHours - contract)
+		}
+		report.Rows = append(report.Rows, *row)
+	}
+	return report, nil
+}
+
+func (s *service) hoursFor(u *entity.User, start, end time.Time) (*HoursRow, *ierr.RestErr) {
+	days, err := s.schedule.ShiftsInRange(u, start, end)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error resolving schedule")
+	}
+	// Night shifts of the last day end on the first day of the next month.
+	siteHolidays, err := s.holidayRepo.FindHolidaysForUser(u, start, end.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding holidays")
+	}
+	holidays := make(map[time.Time]bool, len(siteHolidays))
+	for _, h := range siteHolidays {
+		holidays[schedule.DateOnly(h.Date)] = true
+	}
+	certificates, err := s.certificateRepo.FindOverlapping(u.ID, start, end, []entity.CertificateStatus{entity.CertificateStatusApproved})
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding absences")
+	}
+	absent := make(map[time.Time]bool)
+	for _, cert := range certificates {
+		for date := schedule.DateOnly(cert.StartDate); !date.After(cert.EndDate); date = date.AddDate(0, 0, 1) {
+			if !date.Before(start) && !date.After(end) {
+				absent[date] = true
+			}
+		}
+	}
+
+	row := &HoursRow{
+		UserID:      u.ID,
+		Name:        u.FirstName + " " + u.LastName,
+		Position:    u.Position,
+		AbsenceDays: len(absent),
+	}
+	var scheduled, holiday, premium, night time.Duration
+	for _, day := range days {
+		if !day.Working || absent[day.Date] {
+			continue
+		}
+		shiftStart, shiftEnd, err := s.schedule.ShiftWindow(day.Shift, day.Date)
+		if err != nil {
+			return nil, ierr.NewInternalServerError("error resolving shift hours")
+		}
+		row.WorkedDays++
+		scheduled += shiftEnd.Sub(shiftStart)
+		// Only shifts actually worked count. Whether a holiday is worked at
+		// all comes from the holiday policy resolved by the schedule: the day
+		// is off under the off policy unless the collaborator is on duty, and
+		// a night shift from the day before may still run into it.
+		holidayHours := holidayDuration(shiftStart, shiftEnd, holidays)
+		holiday += holidayHours
+		if day.HolidayPolicy != entity.HolidayPolicyWorkedNormal {
+			premium += holidayHours
+		}
+		night += nightDuration(shiftStart, shiftEnd)
+	}
+
+	row.ScheduledHours = hours(scheduled)
+	row.HolidayHours = hours(holiday)
+	row.HolidayPremiumHours = hours(premium)
+	row.NightHours = hours(night)
+	row.NightHoursReduced = roundHours(night.Hours() * NightHourFactor)
+	// Worked hours count the reduced night hour but no premium; overtime is
+	// measured on them so that premiums are not paid twice.
+	worked := scheduled.Hours() + night.Hours()*(NightHourFactor-1)
+	row.WorkedHours = roundHours(worked)
+	row.PaidHours = roundHours(worked + premium.Hours()*(HolidayPremiumFactor-1))
+
+	overrides, err := s.swapRepo.CountOverriddenSwaps(u.ID, start, end)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error counting rule overrides")
+	}
+	row.RuleOverrides = int(overrides)
+	return row, nil
+}
+
+// checkAccess allows masters and the team's supervisors.
+func (s *service) checkAccess(team entity.TeamName, requestorID uint) *ierr.RestErr {
+	requestor, err := s.userRepo.FindUserByID(requestorID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ierr.NewUnauthorizedError("requestor not found")
+		}
+		return ierr.NewInternalServerError("error finding requestor")
+	}
+	if requestor.UserType == entity.UserTypeMaster {
+		return nil
+	}
+	isSupervisor := requestor.Position == entity.PositionSupervisorI || requestor.Position == entity.PositionSupervisorII
+	if requestor.Team != team || !isSupervisor {
+		return ierr.NewForbiddenError("only masters and the team's supervisors can view its reports")
+	}
+	return nil
+}
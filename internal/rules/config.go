@@ -0,0 +1,51 @@
+package rules
+
+import (
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/schedule"
+	"time"
+)
+
+// Config holds the setting of every rule for a team.
+type Config map[entity.RuleName]entity.TeamRuleConfig
+
+var defaults = []struct {
+	rule      entity.RuleName
+	severity  entity.RuleSeverity
+	threshold int
+}{
+	{entity.RuleMinRest, entity.RuleSeverityBlock, int(schedule.MinRestInterval / time.Hour)},
+	{entity.RuleWeeklyRest, entity.RuleSeverityBlock, 24},
+	{entity.RuleMaxConsecutiveDays, entity.RuleSeverityBlock, 6},
+	{entity.RuleMaxNightShifts, entity.RuleSeverityWarn, 10},
+	{entity.RuleMaxSwaps, entity.RuleSeverityWarn, 4},
+}
+
+// DefaultConfig returns the settings of a team that configured no rule.
+func DefaultConfig(team entity.TeamName) Config {
+	config := make(Config, len(defaults))
+	for _, d := range defaults {
+		config[d.rule] = entity.TeamRuleConfig{Team: team, Rule: d.rule, Severity: d.severity, Threshold: d.threshold}
+	}
+	return config
+}
+
+// For returns the setting of a rule.
+func (c Config) For(rule entity.RuleName) entity.TeamRuleConfig {
+	return c[rule]
+}
+
+// Blocks reports whether breaking the rule rejects the change.
+func (c Config) Blocks(rule entity.RuleName) bool {
+	return c.For(rule).Severity == entity.RuleSeverityBlock
+}
+
+// IsKnown reports whether name is one of the rules.
+func IsKnown(name entity.RuleName) bool {
+	for _, rule := range registry {
+		if rule.Name() == name {
+			return true
+		}
+	}
+	return false
+}
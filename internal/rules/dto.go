@@ -0,0 +1,32 @@
+package rules
+
+import "escala-fds-api/internal/entity"
+
+type UpdateRuleRequest struct {
+	Severity  entity.RuleSeverity `json:"severity" binding:"required,oneof=block warn off"`
+	Threshold int                 `json:"threshold" binding:"min=0,max=1000"`
+}
+
+type RuleResponse struct {
+	Rule      entity.RuleName     `json:"rule"`
+	Severity  entity.RuleSeverity `json:"severity"`
+	Threshold int                 `json:"threshold"`
+}
+
+type TeamRulesResponse struct {
+	Team  entity.TeamName `json:"team"`
+	Rules []RuleResponse  `json:"rules"`
+}
+
+func ToTeamRulesResponse(team entity.TeamName, config Config) TeamRulesResponse {
+	response := TeamRulesResponse{Team: team, Rules: make([]RuleResponse, 0, len(registry))}
+	for _, rule := range registry {
+		setting := config.For(rule.Name())
+		response.Rules = append(response.Rules, RuleResponse{
+			Rule:      rule.Name(),
+			Severity:  setting.Severity,
+			Threshold: setting.Threshold,
+		})
+	}
+	return response
+}
@@ -0,0 +1,82 @@
+package rules
+
+import (
+	"escala-fds-api/internal/auth"
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/validation"
+	"escala-fds-api/pkg/ierr"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	ruleRoutes := router.Group("/teams/:team/rules")
+	ruleRoutes.Use(auth.Middleware(), auth.RequireScope("rules"))
+	{
+		ruleRoutes.GET("", h.FindTeamRules)
+		ruleRoutes.PUT("/:rule", h.UpdateTeamRule)
+	}
+}
+
+func (h *Handler) FindTeamRules(c *gin.Context) {
+	team, restErr := teamFromPath(c)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	config, err := h.service.Config(team)
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusOK, ToTeamRulesResponse(team, config))
+}
+
+func (h *Handler) UpdateTeamRule(c *gin.Context) {
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	team, restErr := teamFromPath(c)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	var req UpdateRuleRequest
+	if errBind := validation.BindJSON(c, &req); errBind != nil {
+		c.JSON(errBind.Code, errBind)
+		return
+	}
+	config := entity.TeamRuleConfig{
+		Team:      team,
+		Rule:      entity.RuleName(c.Param("rule")),
+		Severity:  req.Severity,
+		Threshold: req.Threshold,
+	}
+	updated, err := h.service.UpdateTeamRule(config, entity.UserType(requestorType))
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusOK, ToTeamRulesResponse(team, updated))
+}
+
+func teamFromPath(c *gin.Context) (entity.TeamName, *ierr.RestErr) {
+	team := entity.TeamName(c.Param("team"))
+	if !team.IsValid() {
+		return "", ierr.NewBadRequestValidationError("some fields are invalid", []ierr.Causes{
+			{Field: "team", Message: "must be a valid team: Security, Support, CustomerService"},
+		})
+	}
+	return team, nil
+}
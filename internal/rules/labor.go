@@ -0,0 +1,185 @@
+package rules
+
+import (
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/schedule"
+	"fmt"
+	"math"
+	"time"
+)
+
+// minRest requires threshold hours of rest between consecutive shifts.
+type minRest struct{}
+
+func (minRest) Name() entity.RuleName { return entity.RuleMinRest }
+
+func (minRest) Check(t *Timeline, threshold int) []string {
+	minimum := time.Duration(threshold) * time.Hour
+	var messages []string
+	for i := 1; i < len(t.Spans); i++ {
+		previous, next := t.Spans[i-1], t.Spans[i]
+		if !t.isFocus(previous.Date) && !t.isFocus(next.Date) {
+			continue
+		}
+		if rest := next.Start.Sub(previous.End); rest < minimum {
+			messages = append(messages, fmt.Sprintf("only %s of rest between the shifts of %s and %s, the minimum is %s",
+				formatDuration(rest), formatDate(previous.Date), formatDate(next.Date), formatDuration(minimum)))
+		}
+	}
+	return messages
+}
+
+// weeklyRest requires threshold hours of uninterrupted rest in every 7
+// consecutive days (descanso semanal remunerado).
+type weeklyRest struct{}
+
+func (weeklyRest) Name() entity.RuleName { return entity.RuleWeeklyRest }
+
+func (weeklyRest) Check(t *Timeline, threshold int) []string {
+	minimum := time.Duration(threshold) * time.Hour
+	var messages []string
+	reported := make(map[string]bool)
+	for _, date := range t.Focus {
+		if !t.worksOn(date) {
+			continue
+		}
+		for first := schedule.DateOnly(date).AddDate(0, 0, -6); !first.After(date); first = first.AddDate(0, 0, 1) {
+			last := first.AddDate(0, 0, 7)
+			if !t.covers(first, last.AddDate(0, 0, -1)) {
+				continue
+			}
+			if longestRest(t.Spans, first, last) < minimum {
+				if !reported[formatDate(first)] {
+					reported[formatDate(first)] = true
+					messages = append(messages, fmt.Sprintf("no %s of uninterrupted rest between %s and %s",
+						formatDuration(minimum), formatDate(first), formatDate(last.AddDate(0, 0, -1))))
+				}
+				break
+			}
+		}
+	}
+	return messages
+}
+
+// maxConsecutiveDays limits a run of working days to threshold days.
+type maxConsecutiveDays struct{}
+
+func (maxConsecutiveDays) Name() entity.RuleName { return entity.RuleMaxConsecutiveDays }
+
+func (maxConsecutiveDays) Check(t *Timeline, threshold int) []string {
+	var messages []string
+	reported := make(map[string]bool)
+	for _, date := range t.Focus {
+		first, last, ok := t.workingRun(date)
+		if !ok || reported[formatDate(first)] {
+			continue
+		}
+		if run := int(last.Sub(first).Hours()/24) + 1; run > threshold {
+			reported[formatDate(first)] = true
+			messages = append(messages, fmt.Sprintf("%d consecutive working days from %s to %s, the maximum is %d",
+				run, formatDate(first), formatDate(last), threshold))
+		}
+	}
+	return messages
+}
+
+// maxNightShifts limits the night shifts of a calendar month to threshold.
+type maxNightShifts struct{}
+
+func (maxNightShifts) Name() entity.RuleName { return entity.RuleMaxNightShifts }
+
+func (maxNightShifts) Check(t *Timeline, threshold int) []string {
+	var messages []string
+	reported := make(map[string]bool)
+	for _, span := range t.Spans {
+		month := span.Date.Format("2006-01")
+		if !span.Night() || !t.isFocus(span.Date) || reported[month] {
+			continue
+		}
+		nights := 0
+		for _, other := range t.Spans {
+			if other.Night() && other.Date.Format("2006-01") == month {
+				nights++
+			}
+		}
+		if nights > threshold {
+			reported[month] = true
+			messages = append(messages, fmt.Sprintf("%d night shifts in %s, the maximum is %d", nights, month, threshold))
+		}
+	}
+	return messages
+}
+
+// maxSwaps limits the swaps of a collaborator in a month to threshold.
+type maxSwaps struct{}
+
+func (maxSwaps) Name() entity.RuleName { return entity.RuleMaxSwaps }
+
+func (maxSwaps) Check(t *Timeline, threshold int) []string {
+	if t.SwapsInMonth > threshold {
+		return []string{fmt.Sprintf("%d swaps in the month, the maximum is %d", t.SwapsInMonth, threshold)}
+	}
+	return nil
+}
+
+// longestRest is the longest time without a shift that starts between from
+// and to. A rest starting before to lasts until the next shift, even a later
+// one; without a next shift in spans it is taken as long enough.
+func longestRest(spans []Span, from, to time.Time) time.Duration {
+	longest := time.Duration(0)
+	free := from
+	for _, span := range spans {
+		if !span.End.After(from) {
+			continue
+		}
+		if !free.Before(to) {
+			break
+		}
+		if gap := span.Start.Sub(free); gap > longest {
+			longest = gap
+		}
+		if span.End.After(free) {
+			free = span.End
+		}
+	}
+	if free.Before(to) {
+		return math.MaxInt64
+	}
+	return longest
+}
+
+func (t *Timeline) dayIndex(date time.Time) int {
+	for i := range t.Days {
+		if schedule.SameDay(t.Days[i].Date, date) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (t *Timeline) worksOn(date time.Time) bool {
+	i := t.dayIndex(date)
+	return i >= 0 && t.Days[i].Working
+}
+
+// covers reports whether the timeline has every day from first to last.
+func (t *Timeline) covers(first, last time.Time) bool {
+	return t.dayIndex(first) >= 0 && t.dayIndex(last) >= 0
+}
+
+// workingRun returns the first and last day of the run of working days that
+// contains date, and false when date is not a working day.
+func (t *Timeline) workingRun(date time.Time) (time.Time, time.Time, bool) {
+	index := t.dayIndex(date)
+	if index < 0 || !t.Days[index].Working {
+		return time.Time{}, time.Time{}, false
+	}
+	first, last := index, index
+	for first > 0 && t.Days[first-1].Working {
+		first--
+	}
+	for last < len(t.Days)-1 && t.Days[last+1].Working {
+		last++
+	}
+	return t.Days[first].Date, t.Days[last].Date, true
+}
@@ -0,0 +1,30 @@
+package rules
+
+import (
+	"escala-fds-api/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	FindTeamConfigs(team entity.TeamName) ([]entity.TeamRuleConfig, error)
+	SaveTeamConfig(config *entity.TeamRuleConfig) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) FindTeamConfigs(team entity.TeamName) ([]entity.TeamRuleConfig, error) {
+	var configs []entity.TeamRuleConfig
+	err := r.db.Where("team = ?", team).Find(&configs).Error
+	return configs, err
+}
+
+func (r *repository) SaveTeamConfig(config *entity.TeamRuleConfig) error {
+	return r.db.Save(config).Error
+}
@@ -0,0 +1,158 @@
+package rules
+
+import (
+	"escala-fds-api/internal/constants"
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/schedule"
+	"escala-fds-api/pkg/ierr"
+	"fmt"
+	"time"
+)
+
+// Span is a shift worked on Date, from Start to End.
+type Span struct {
+	Date  time.Time
+	Start time.Time
+	End   time.Time
+}
+
+// Night reports whether the shift crosses midnight.
+func (s Span) Night() bool {
+	return s.End.After(schedule.DateOnly(s.Date).AddDate(0, 0, 1))
+}
+
+// Timeline is the schedule of one collaborator the rules look at: Days are
+// consecutive and Spans the shifts worked on them. Only breaches involving a
+// Focus date are reported, so that a change is not blamed for problems it
+// does not cause. When a swap is evaluated, SwapsInMonth counts the swaps of
+// the collaborator in its month, the evaluated one included.
+type Timeline struct {
+	Days         []schedule.Day
+	Spans        []Span
+	Focus        []time.Time
+	SwapsInMonth int
+}
+
+// Rule is a labor rule checked against a timeline.
+type Rule interface {
+	Name() entity.RuleName
+	// Check returns a message for every breach of the rule given the
+	// threshold configured for the team.
+	Check(t *Timeline, threshold int) []string
+}
+
+var registry = []Rule{minRest{}, weeklyRest{}, maxConsecutiveDays{}, maxNightShifts{}, maxSwaps{}}
+
+// All returns the known rules in evaluation order.
+func All() []Rule {
+	return registry
+}
+
+// Violation is a rule breach found in a timeline. Field names whose schedule
+// breaks the rule, e.g. the requester or involved collaborator of a swap.
+type Violation struct {
+	Rule     entity.RuleName     `json:"rule"`
+	Severity entity.RuleSeverity `json:"severity"`
+	Field    string              `json:"field,omitempty"`
+	Message  string              `json:"message"`
+}
+
+// Evaluate runs every enabled rule of config against the timeline.
+func Evaluate(t *Timeline, config Config, field string) []Violation {
+	var violations []Violation
+	for _, rule := range registry {
+		setting := config.For(rule.Name())
+		if setting.Severity == entity.RuleSeverityOff {
+			continue
+		}
+		for _, message := range rule.Check(t, setting.Threshold) {
+			violations = append(violations, Violation{
+				Rule:     rule.Name(),
+				Severity: setting.Severity,
+				Field:    field,
+				Message:  message,
+			})
+		}
+	}
+	return violations
+}
+
+// Split separates blocking violations from warnings.
+func Split(violations []Violation) (blocking, warnings []Violation) {
+	for _, v := range violations {
+		if v.Severity == entity.RuleSeverityBlock {
+			blocking = append(blocking, v)
+		} else {
+			warnings = append(warnings, v)
+		}
+	}
+	return blocking, warnings
+}
+
+// Enforce returns the error for blocking violations, unless a master chose
+// to override them, and the violations to report as warnings.
+func Enforce(violations []Violation, override bool) ([]Violation, *ierr.RestErr) {
+	blocking, warnings := Split(violations)
+	if len(blocking) == 0 {
+		return warnings, nil
+	}
+	if override {
+		return violations, nil
+	}
+	return nil, ierr.NewBadRequestValidationError("the change breaks labor rules", Causes(blocking))
+}
+
+// Causes turns violations into error causes named after field and rule.
+func Causes(violations []Violation) []ierr.Causes {
+	causes := make([]ierr.Causes, 0, len(violations))
+	for _, v := range violations {
+		field := string(v.Rule)
+		if v.Field != "" {
+			field = v.Field + "." + field
+		}
+		causes = append(causes, ierr.Causes{Field: field, Message: v.Message})
+	}
+	return causes
+}
+
+// SpansOf returns the spans of the working days, using window to resolve the
+// hours of each shift.
+func SpansOf(days []schedule.Day, window func(entity.ShiftName, time.Time) (time.Time, time.Time, error)) ([]Span, error) {
+	var spans []Span
+	for _, day := range days {
+		if !day.Working {
+			continue
+		}
+		start, end, err := window(day.Shift, day.Date)
+		if err != nil {
+			return nil, err
+		}
+		spans = append(spans, Span{Date: day.Date, Start: start, End: end})
+	}
+	return spans, nil
+}
+
+func (t *Timeline) isFocus(date time.Time) bool {
+	for _, focus := range t.Focus {
+		if schedule.SameDay(focus, date) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatDate(date time.Time) string {
+	return date.Format(constants.ApiDateLayout)
+}
+
+func formatDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	hours := int(d / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	if minutes == 0 {
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dh%02d", hours, minutes)
+}
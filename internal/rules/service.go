@@ -0,0 +1,48 @@
+package rules
+
+import (
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/pkg/ierr"
+)
+
+type Service interface {
+	Config(team entity.TeamName) (Config, *ierr.RestErr)
+	UpdateTeamRule(config entity.TeamRuleConfig, requestorType entity.UserType) (Config, *ierr.RestErr)
+}
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+// Config returns the rule settings of a team: the defaults, replaced by the
+// ones the team configured.
+func (s *service) Config(team entity.TeamName) (Config, *ierr.RestErr) {
+	stored, err := s.repo.FindTeamConfigs(team)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding labor rules")
+	}
+	config := DefaultConfig(team)
+	for _, c := range stored {
+		if IsKnown(c.Rule) {
+			config[c.Rule] = c
+		}
+	}
+	return config, nil
+}
+
+func (s *service) UpdateTeamRule(config entity.TeamRuleConfig, requestorType entity.UserType) (Config, *ierr.RestErr) {
+	if requestorType != entity.UserTypeMaster {
+		return nil, ierr.NewForbiddenError("only masters can change labor rules")
+	}
+	if !IsKnown(config.Rule) {
+		return nil, ierr.NewNotFoundError("labor rule not found")
+	}
+	if err := s.repo.SaveTeamConfig(&config); err != nil {
+		return nil, ierr.NewInternalServerError("error saving labor rule")
+	}
+	return s.Config(config.Team)
+}
@@ -0,0 +1,140 @@
+package schedule
+
+import (
+	"escala-fds-api/internal/auth"
+	"escala-fds-api/internal/constants"
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/export"
+	"escala-fds-api/internal/validation"
+	"escala-fds-api/pkg/ierr"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxScheduleRangeDays bounds the schedule view to about a year.
+const maxScheduleRangeDays = 366
+
+// UserFinder is satisfied by the user repository.
+type UserFinder interface {
+	FindUserByID(id uint) (*entity.User, error)
+}
+
+type DayResponse struct {
+	Date          string               `json:"date"`
+	Shift         entity.ShiftName     `json:"shift,omitempty"`
+	Working       bool                 `json:"working"`
+	Holiday       bool                 `json:"holiday"`
+	HolidayDuty   bool                 `json:"holidayDuty,omitempty"`
+	HolidayPolicy entity.HolidayPolicy `json:"holidayPolicy,omitempty"`
+}
+
+var dayExportHeader = []string{"date", "weekday", "shift", "working", "holiday"}
+
+type Handler struct {
+	service Service
+	users   UserFinder
+}
+
+func NewHandler(service Service, users UserFinder) *Handler {
+	return &Handler{service: service, users: users}
+}
+
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	userRoutes := router.Group("/users")
+	userRoutes.Use(auth.Middleware(), auth.RequireScope("users"))
+	{
+		userRoutes.GET("/:id/schedule", h.FindUserSchedule)
+	}
+}
+
+// FindUserSchedule resolves a collaborator's day-by-day schedule. It is
+// visible to the collaborator, their teammates and masters.
+func (h *Handler) FindUserSchedule(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	requestorID, errAuth := auth.GetUserIDFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	requestorTeam, _ := auth.GetUserTeamFromContext(c)
+
+	startDate, restErr := validation.ParseDate("startDate", c.Query("startDate"))
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	endDate, restErr := validation.ParseDate("endDate", c.Query("endDate"))
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	if endDate.Before(startDate) || endDate.Sub(startDate).Hours()/24 >= maxScheduleRangeDays {
+		restErr := ierr.NewBadRequestValidationError("some fields are invalid", []ierr.Causes{
+			{Field: "endDate", Message: fmt.Sprintf("must be on or after startDate and within %d days of it", maxScheduleRangeDays)},
+		})
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	format, restErr := export.FormatFromRequest(c)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+
+	user, err := h.users.FindUserByID(uint(id))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, ierr.NewNotFoundError("user not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ierr.NewInternalServerError("error finding user"))
+		return
+	}
+	if entity.UserType(requestorType) != entity.UserTypeMaster && user.ID != requestorID && string(user.Team) != requestorTeam {
+		c.JSON(http.StatusForbidden, ierr.NewForbiddenError("you can only view schedules of your own team"))
+		return
+	}
+
+	days, err := h.service.ShiftsInRange(user, startDate, endDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ierr.NewInternalServerError("error resolving schedule"))
+		return
+	}
+	res := make([]DayResponse, 0, len(days))
+	for _, day := range days {
+		res = append(res, DayResponse{
+			Date:          day.Date.Format(constants.ApiDateLayout),
+			Shift:         day.Shift,
+			Working:       day.Working,
+			Holiday:       day.Holiday,
+			HolidayDuty:   day.HolidayDuty,
+			HolidayPolicy: day.HolidayPolicy,
+		})
+	}
+
+	if format != export.FormatJSON {
+		filename := fmt.Sprintf("schedule-%d-%s-%s", user.ID, c.Query("startDate"), c.Query("endDate"))
+		export.Write(c, format, filename, dayExportHeader, func(w export.RowWriter) error {
+			for i, day := range res {
+				weekday := strings.ToLower(days[i].Date.Weekday().String())
+				row := []string{day.Date, weekday, string(day.Shift), strconv.FormatBool(day.Working), strconv.FormatBool(day.Holiday)}
+				if err := w.WriteRow(row); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
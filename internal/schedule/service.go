@@ -0,0 +1,376 @@
+package schedule
+
+import (
+	"errors"
+	"escala-fds-api/internal/constants"
+	"escala-fds-api/internal/entity"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MinRestInterval is the minimum rest required between the end of one shift
+// and the start of the next.
+const MinRestInterval = 11 * time.Hour
+
+// SwapFinder and HolidayFinder are satisfied by the swap and holiday
+// repositories; they are declared here so those packages can depend on
+// schedule without an import cycle.
+type SwapFinder interface {
+	FindApprovedSwapsForDateRange(userID uint, startDate, endDate time.Time) ([]entity.Swap, error)
+}
+
+type HolidayFinder interface {
+	FindHolidaysForUser(user *entity.User, startDate, endDate time.Time) ([]entity.Holiday, error)
+	FindHolidaysForTeam(team entity.TeamName, startDate, endDate time.Time) ([]entity.Holiday, error)
+	FindTeamHolidayPolicy(team entity.TeamName) (*entity.TeamHolidayPolicy, error)
+}
+
+// PublishedEscalaFinder is satisfied by the escala repository.
+type PublishedEscalaFinder interface {
+	FindPublishedEntries(userID uint, startDate, endDate time.Time) ([]entity.EscalaEntry, error)
+}
+
+// AssignmentFinder is satisfied by the user repository.
+type AssignmentFinder interface {
+	FindAssignmentsInRange(userID uint, startDate, endDate time.Time) ([]entity.WorkAssignment, error)
+	FindUsersByTeam(team entity.TeamName) ([]entity.User, error)
+}
+
+// ShiftCatalog is satisfied by the shift repository.
+type ShiftCatalog interface {
+	FindTemplateByName(name entity.ShiftName) (*entity.ShiftTemplate, error)
+	FindPatternByID(id uint) (*entity.RotationPattern, error)
+}
+
+// Day is the resolved schedule of a user on a single date. HolidayOff is set
+// when the date is a holiday the user has off under the holiday policy, and
+// HolidayDuty when the user works it in the team's duty rotation. On working
+// days HolidayPolicy tells how the holiday hours of the shift are paid.
+type Day struct {
+	Date          time.Time
+	Shift         entity.ShiftName
+	Working       bool
+	Holiday       bool
+	HolidayOff    bool
+	HolidayDuty   bool
+	HolidayPolicy entity.HolidayPolicy
+}
+
+type Service interface {
+	ShiftForDay(user *entity.User, date time.Time) (entity.ShiftName, bool, error)
+	ShiftsInRange(user *entity.User, startDate, endDate time.Time) ([]Day, error)
+	ShiftWindow(shift entity.ShiftName, date time.Time) (time.Time, time.Time, error)
+}
+
+type service struct {
+	swapFinder       SwapFinder
+	holidayFinder    HolidayFinder
+	catalog          ShiftCatalog
+	escalaFinder     PublishedEscalaFinder
+	assignmentFinder AssignmentFinder
+}
+
+func NewService(swapFinder SwapFinder, holidayFinder HolidayFinder, catalog ShiftCatalog, escalaFinder PublishedEscalaFinder, assignmentFinder AssignmentFinder) Service {
+	return &service{
+		swapFinder:       swapFinder,
+		holidayFinder:    holidayFinder,
+		catalog:          catalog,
+		escalaFinder:     escalaFinder,
+		assignmentFinder: assignmentFinder,
+	}
+}
+
+func (s *service) ShiftForDay(user *entity.User, date time.Time) (entity.ShiftName, bool, error) {
+	days, err := s.ShiftsInRange(user, date, date)
+	if err != nil || len(days) == 0 {
+		return "", false, err
+	}
+	return days[0].Shift, days[0].Working, nil
+}
+
+// ShiftsInRange resolves every day between startDate and endDate inclusive.
+// Approved swaps take precedence over the published escala of the month,
+// which takes precedence over the holidays of the user's site and the user's
+// regular days off. Regular days follow the work assignment in effect on each
+// date: users with a rotation pattern follow it from their anchor date, the
+// others keep the weekday off plus alternating weekend. Holidays are days off
+// unless the holiday policy of the shift or team has them worked, or the user
+// is on holiday duty.
+func (s *service) ShiftsInRange(user *entity.User, startDate, endDate time.Time) ([]Day, error) {
+	startDate = DateOnly(startDate)
+	endDate = DateOnly(endDate)
+
+	swaps, err := s.swapFinder.FindApprovedSwapsForDateRange(user.ID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	holidays, err := s.holidayFinder.FindHolidaysForUser(user, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	holidayDates := make(map[string]bool, len(holidays))
+	for _, h := range holidays {
+		holidayDates[h.Date.Format(constants.ApiDateLayout)] = true
+	}
+	published, err := s.escalaFinder.FindPublishedEntries(user.ID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	publishedDays := make(map[string]entity.EscalaEntry, len(published))
+	for _, entry := range published {
+		publishedDays[entry.Date.Format(constants.ApiDateLayout)] = entry
+	}
+	assignments, err := s.assignmentFinder.FindAssignmentsInRange(user.ID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	cache := newLookups()
+
+	var days []Day
+	for date := startDate; !date.After(endDate); date = date.AddDate(0, 0, 1) {
+		key := date.Format(constants.ApiDateLayout)
+		day := Day{Date: date, Holiday: holidayDates[key]}
+		effective := effectiveUser(user, date, assignments)
+		if shift, working, found := shiftFromSwaps(user, date, swaps); found {
+			day.Shift, day.Working = shift, working
+		} else if entry, found := publishedDays[key]; found {
+			if entry.Kind == entity.EscalaEntryWork {
+				day.Shift, day.Working = entry.Shift, true
+			}
+			day.HolidayOff = entry.Kind == entity.EscalaEntryHoliday
+		} else if err := s.resolveRegularDay(&day, effective, cache); err != nil {
+			return nil, err
+		}
+		if day.Working {
+			policy, _, err := s.holidayPolicy(effective.Team, day.Shift, cache)
+			if err != nil {
+				return nil, err
+			}
+			day.HolidayPolicy = policy
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// lookups caches what ShiftsInRange reads more than once.
+type lookups struct {
+	patterns     map[uint]*entity.RotationPattern
+	templates    map[entity.ShiftName]*entity.ShiftTemplate
+	policies     map[entity.TeamName]*entity.TeamHolidayPolicy
+	rosters      map[entity.TeamName][]uint
+	teamHolidays map[string][]entity.Holiday
+}
+
+func newLookups() *lookups {
+	return &lookups{
+		patterns:     make(map[uint]*entity.RotationPattern),
+		templates:    make(map[entity.ShiftName]*entity.ShiftTemplate),
+		policies:     make(map[entity.TeamName]*entity.TeamHolidayPolicy),
+		rosters:      make(map[entity.TeamName][]uint),
+		teamHolidays: make(map[string][]entity.Holiday),
+	}
+}
+
+// resolveRegularDay fills a day that no swap or published escala decides.
+func (s *service) resolveRegularDay(day *Day, user *entity.User, cache *lookups) error {
+	if day.Holiday {
+		policy, dutyCount, err := s.holidayPolicy(user.Team, user.Shift, cache)
+		if err != nil {
+			return err
+		}
+		if policy == entity.HolidayPolicyOff {
+			onDuty := false
+			if dutyCount > 0 && user.Shift != "" {
+				if onDuty, err = s.onHolidayDuty(user, day.Date, dutyCount, cache); err != nil {
+					return err
+				}
+			}
+			day.HolidayOff = !onDuty
+			day.HolidayDuty = onDuty
+			if onDuty {
+				day.Shift, day.Working = user.Shift, true
+			}
+			return nil
+		}
+	}
+	pattern, err := s.rotationPattern(user, cache.patterns)
+	if err != nil {
+		return err
+	}
+	if isWorkDay(day.Date, user, pattern) {
+		day.Shift, day.Working = user.Shift, true
+	}
+	return nil
+}
+
+// holidayPolicy returns the policy that applies to a shift of the team: the
+// template's own policy when set, else the team's. The duty count always
+// comes from the team.
+func (s *service) holidayPolicy(team entity.TeamName, shift entity.ShiftName, cache *lookups) (entity.HolidayPolicy, int, error) {
+	teamPolicy, ok := cache.policies[team]
+	if !ok {
+		found, err := s.holidayFinder.FindTeamHolidayPolicy(team)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			defaultPolicy := entity.DefaultTeamHolidayPolicy(team)
+			found, err = &defaultPolicy, nil
+		}
+		if err != nil {
+			return "", 0, err
+		}
+		cache.policies[team], teamPolicy = found, found
+	}
+
+	template, ok := cache.templates[shift]
+	if !ok && shift != "" {
+		found, err := s.catalog.FindTemplateByName(shift)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", 0, err
+		}
+		cache.templates[shift], template = found, found
+	}
+	if template != nil && template.HolidayPolicy != "" {
+		return template.HolidayPolicy, teamPolicy.DutyCount, nil
+	}
+	return teamPolicy.Policy, teamPolicy.DutyCount, nil
+}
+
+// onHolidayDuty reports whether the user is among the dutyCount collaborators
+// working the holiday on date. The team's collaborators, ordered by id, take
+// turns: the n-th holiday of the year at the team's site starts n*dutyCount
+// places further down the list.
+func (s *service) onHolidayDuty(user *entity.User, date time.Time, dutyCount int, cache *lookups) (bool, error) {
+	roster, ok := cache.rosters[user.Team]
+	if !ok {
+		members, err := s.assignmentFinder.FindUsersByTeam(user.Team)
+		if err != nil {
+			return false, err
+		}
+		for _, member := range members {
+			if member.UserType == entity.UserTypeCollaborator {
+				roster = append(roster, member.ID)
+			}
+		}
+		sort.Slice(roster, func(i, j int) bool { return roster[i] < roster[j] })
+		cache.rosters[user.Team] = roster
+	}
+	position := -1
+	for i, id := range roster {
+		if id == user.ID {
+			position = i
+			break
+		}
+	}
+	if position < 0 {
+		return false, nil
+	}
+	if dutyCount >= len(roster) {
+		return true, nil
+	}
+
+	yearKey := fmt.Sprintf("%s/%d", user.Team, date.Year())
+	holidays, ok := cache.teamHolidays[yearKey]
+	if !ok {
+		var err error
+		firstDay := time.Date(date.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		holidays, err = s.holidayFinder.FindHolidaysForTeam(user.Team, firstDay, firstDay.AddDate(1, 0, -1))
+		if err != nil {
+			return false, err
+		}
+		cache.teamHolidays[yearKey] = holidays
+	}
+	ordinal := 0
+	seen := make(map[string]bool, len(holidays))
+	for _, h := range holidays {
+		key := h.Date.Format(constants.ApiDateLayout)
+		if h.Date.Before(date) && !seen[key] {
+			seen[key] = true
+			ordinal++
+		}
+	}
+
+	start := ordinal * dutyCount % len(roster)
+	return (position-start+len(roster))%len(roster) < dutyCount, nil
+}
+
+func shiftFromSwaps(u *entity.User, date time.Time, swaps []entity.Swap) (entity.ShiftName, bool, bool) {
+	for i := range swaps {
+		if shift, working, found := swaps[i].Effect(u.ID, date); found {
+			return shift, working, true
+		}
+	}
+	return "", false, false
+}
+
+// ShiftWindow returns the start and end instants of a shift worked on date,
+// using its template. Shifts crossing midnight end on the following day.
+// Names without a template are read as legacy "HH:MM-HH:MM" values.
+func (s *service) ShiftWindow(shift entity.ShiftName, date time.Time) (time.Time, time.Time, error) {
+	template, err := s.catalog.FindTemplateByName(shift)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		template, err = entity.NewShiftTemplateFromName(shift)
+	}
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	start, end := template.Window(date)
+	return start, end, nil
+}
+
+// effectiveUser returns the user as described by the assignment covering
+// date, or the user itself when there is no recorded assignment.
+func effectiveUser(user *entity.User, date time.Time, assignments []entity.WorkAssignment) *entity.User {
+	for i := range assignments {
+		if assignments[i].Covers(date) {
+			return assignments[i].ApplyTo(user)
+		}
+	}
+	return user
+}
+
+func (s *service) rotationPattern(user *entity.User, cache map[uint]*entity.RotationPattern) (*entity.RotationPattern, error) {
+	if user.RotationPatternID == nil || user.RotationAnchor == nil {
+		return nil, nil
+	}
+	if pattern, ok := cache[*user.RotationPatternID]; ok {
+		return pattern, nil
+	}
+	pattern, err := s.catalog.FindPatternByID(*user.RotationPatternID)
+	if err != nil {
+		return nil, err
+	}
+	cache[*user.RotationPatternID] = pattern
+	return pattern, nil
+}
+
+func isWorkDay(date time.Time, user *entity.User, pattern *entity.RotationPattern) bool {
+	if pattern != nil {
+		return pattern.IsWorkDay(*user.RotationAnchor, date)
+	}
+	return !isRegularDayOff(date, user)
+}
+
+func DateOnly(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func SameDay(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+func isRegularDayOff(date time.Time, user *entity.User) bool {
+	weekdayMap := map[time.Weekday]entity.WeekdayName{
+		time.Monday:    entity.WeekdayMonday,
+		time.Tuesday:   entity.WeekdayTuesday,
+		time.Wednesday: entity.WeekdayWednesday,
+		time.Thursday:  entity.WeekdayThursday,
+		time.Friday:    entity.WeekdayFriday,
+	}
+	if user.WeekdayOff == weekdayMap[date.Weekday()] {
+		return true
+	}
+	return user.IsWeekendDayOff(date)
+}
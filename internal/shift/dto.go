@@ -0,0 +1,69 @@
+package shift
+
+import (
+	"escala-fds-api/internal/entity"
+)
+
+type TemplateRequest struct {
+	Name          entity.ShiftName     `json:"name" binding:"required,shift"`
+	StartTime     string               `json:"startTime" binding:"required,clock"`
+	EndTime       string               `json:"endTime" binding:"required,clock"`
+	Team          *entity.TeamName     `json:"team" binding:"omitempty,team"`
+	HolidayPolicy entity.HolidayPolicy `json:"holidayPolicy" binding:"omitempty,oneof=off worked_normal worked_premium"`
+}
+
+type PatternRequest struct {
+	Name        string `json:"name" binding:"required,max=50"`
+	Sequence    string `json:"sequence" binding:"required,max=64"`
+	Description string `json:"description" binding:"max=255"`
+}
+
+type TemplateResponse struct {
+	ID              uint                 `json:"id"`
+	Name            entity.ShiftName     `json:"name"`
+	StartTime       string               `json:"startTime"`
+	EndTime         string               `json:"endTime"`
+	CrossesMidnight bool                 `json:"crossesMidnight"`
+	DurationMinutes int                  `json:"durationMinutes"`
+	Team            *entity.TeamName     `json:"team,omitempty"`
+	HolidayPolicy   entity.HolidayPolicy `json:"holidayPolicy,omitempty"`
+}
+
+type PatternResponse struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Sequence    string `json:"sequence"`
+	Description string `json:"description,omitempty"`
+	WorkDays    int    `json:"workDays"`
+	CycleDays   int    `json:"cycleDays"`
+}
+
+func ToTemplateResponse(template *entity.ShiftTemplate) TemplateResponse {
+	return TemplateResponse{
+		ID:              template.ID,
+		Name:            template.Name,
+		StartTime:       template.StartTime,
+		EndTime:         template.EndTime,
+		CrossesMidnight: template.CrossesMidnight,
+		DurationMinutes: int(template.Duration().Minutes()),
+		Team:            template.Team,
+		HolidayPolicy:   template.HolidayPolicy,
+	}
+}
+
+func ToPatternResponse(pattern *entity.RotationPattern) PatternResponse {
+	workDays := 0
+	for _, day := range pattern.Sequence {
+		if day == entity.RotationWorkDay {
+			workDays++
+		}
+	}
+	return PatternResponse{
+		ID:          pattern.ID,
+		Name:        pattern.Name,
+		Sequence:    pattern.Sequence,
+		Description: pattern.Description,
+		WorkDays:    workDays,
+		CycleDays:   len(pattern.Sequence),
+	}
+}
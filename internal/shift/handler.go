@@ -0,0 +1,174 @@
+package shift
+
+import (
+	"escala-fds-api/internal/auth"
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/validation"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	shiftRoutes := router.Group("/shifts")
+	shiftRoutes.Use(auth.Middleware(), auth.RequireScope("shifts"))
+	{
+		shiftRoutes.GET("/templates", h.FindTemplates)
+		shiftRoutes.POST("/templates", h.CreateTemplate)
+		shiftRoutes.PUT("/templates/:id", h.UpdateTemplate)
+		shiftRoutes.DELETE("/templates/:id", h.DeleteTemplate)
+		shiftRoutes.GET("/patterns", h.FindPatterns)
+		shiftRoutes.POST("/patterns", h.CreatePattern)
+		shiftRoutes.PUT("/patterns/:id", h.UpdatePattern)
+		shiftRoutes.DELETE("/patterns/:id", h.DeletePattern)
+	}
+}
+
+func (h *Handler) FindTemplates(c *gin.Context) {
+	templates, err := h.service.FindTemplates(entity.TeamName(c.Query("team")))
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	res := make([]TemplateResponse, 0, len(templates))
+	for i := range templates {
+		res = append(res, ToTemplateResponse(&templates[i]))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *Handler) CreateTemplate(c *gin.Context) {
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	var req TemplateRequest
+	if errBind := validation.BindJSON(c, &req); errBind != nil {
+		c.JSON(errBind.Code, errBind)
+		return
+	}
+
+	template := entity.ShiftTemplate{Name: req.Name, StartTime: req.StartTime, EndTime: req.EndTime, Team: req.Team, HolidayPolicy: req.HolidayPolicy}
+	created, err := h.service.CreateTemplate(template, entity.UserType(requestorType))
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusCreated, ToTemplateResponse(created))
+}
+
+func (h *Handler) UpdateTemplate(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	var req TemplateRequest
+	if errBind := validation.BindJSON(c, &req); errBind != nil {
+		c.JSON(errBind.Code, errBind)
+		return
+	}
+
+	template := entity.ShiftTemplate{Name: req.Name, StartTime: req.StartTime, EndTime: req.EndTime, Team: req.Team, HolidayPolicy: req.HolidayPolicy}
+	updated, err := h.service.UpdateTemplate(uint(id), template, entity.UserType(requestorType))
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusOK, ToTemplateResponse(updated))
+}
+
+func (h *Handler) DeleteTemplate(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	if err := h.service.DeleteTemplate(uint(id), entity.UserType(requestorType)); err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) FindPatterns(c *gin.Context) {
+	patterns, err := h.service.FindPatterns()
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	res := make([]PatternResponse, 0, len(patterns))
+	for i := range patterns {
+		res = append(res, ToPatternResponse(&patterns[i]))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *Handler) CreatePattern(c *gin.Context) {
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	var req PatternRequest
+	if errBind := validation.BindJSON(c, &req); errBind != nil {
+		c.JSON(errBind.Code, errBind)
+		return
+	}
+
+	pattern := entity.RotationPattern{Name: req.Name, Sequence: req.Sequence, Description: req.Description}
+	created, err := h.service.CreatePattern(pattern, entity.UserType(requestorType))
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusCreated, ToPatternResponse(created))
+}
+
+func (h *Handler) UpdatePattern(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	var req PatternRequest
+	if errBind := validation.BindJSON(c, &req); errBind != nil {
+		c.JSON(errBind.Code, errBind)
+		return
+	}
+
+	pattern := entity.RotationPattern{Name: req.Name, Sequence: req.Sequence, Description: req.Description}
+	updated, err := h.service.UpdatePattern(uint(id), pattern, entity.UserType(requestorType))
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusOK, ToPatternResponse(updated))
+}
+
+func (h *Handler) DeletePattern(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	if err := h.service.DeletePattern(uint(id), entity.UserType(requestorType)); err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
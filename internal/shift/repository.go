@@ -0,0 +1,154 @@
+package shift
+
+import (
+	"escala-fds-api/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	CreateTemplate(template *entity.ShiftTemplate) error
+	FindTemplateByID(id uint) (*entity.ShiftTemplate, error)
+	FindTemplateByName(name entity.ShiftName) (*entity.ShiftTemplate, error)
+	FindAllTemplates() ([]entity.ShiftTemplate, error)
+	FindTemplatesForTeam(team entity.TeamName) ([]entity.ShiftTemplate, error)
+	UpdateTemplate(template *entity.ShiftTemplate) error
+	DeleteTemplate(id uint) error
+	CreatePattern(pattern *entity.RotationPattern) error
+	FindPatternByID(id uint) (*entity.RotationPattern, error)
+	FindPatternByName(name string) (*entity.RotationPattern, error)
+	FindAllPatterns() ([]entity.RotationPattern, error)
+	UpdatePattern(pattern *entity.RotationPattern) error
+	DeletePattern(id uint) error
+	CountUsersWithShift(name entity.ShiftName) (int64, error)
+	CountAssignmentsWithShift(name entity.ShiftName) (int64, error)
+	CountSwapsWithShift(name entity.ShiftName) (int64, error)
+	CountOffersWithShift(name entity.ShiftName) (int64, error)
+	CountEscalaEntriesWithShift(name entity.ShiftName) (int64, error)
+	CountUsersWithPattern(id uint) (int64, error)
+	CountAssignmentsWithPattern(id uint) (int64, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) CreateTemplate(template *entity.ShiftTemplate) error {
+	return r.db.Create(template).Error
+}
+
+func (r *repository) FindTemplateByID(id uint) (*entity.ShiftTemplate, error) {
+	var template entity.ShiftTemplate
+	if err := r.db.First(&template, id).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *repository) FindTemplateByName(name entity.ShiftName) (*entity.ShiftTemplate, error) {
+	var template entity.ShiftTemplate
+	if err := r.db.Where("name = ?", name).First(&template).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *repository) FindAllTemplates() ([]entity.ShiftTemplate, error) {
+	var templates []entity.ShiftTemplate
+	err := r.db.Order("start_time asc").Find(&templates).Error
+	return templates, err
+}
+
+func (r *repository) FindTemplatesForTeam(team entity.TeamName) ([]entity.ShiftTemplate, error) {
+	var templates []entity.ShiftTemplate
+	err := r.db.Where("team IS NULL OR team = ?", team).Order("start_time asc").Find(&templates).Error
+	return templates, err
+}
+
+func (r *repository) UpdateTemplate(template *entity.ShiftTemplate) error {
+	return r.db.Save(template).Error
+}
+
+func (r *repository) DeleteTemplate(id uint) error {
+	return r.db.Unscoped().Delete(&entity.ShiftTemplate{}, id).Error
+}
+
+func (r *repository) CreatePattern(pattern *entity.RotationPattern) error {
+	return r.db.Create(pattern).Error
+}
+
+func (r *repository) FindPatternByID(id uint) (*entity.RotationPattern, error) {
+	var pattern entity.RotationPattern
+	if err := r.db.First(&pattern, id).Error; err != nil {
+		return nil, err
+	}
+	return &pattern, nil
+}
+
+func (r *repository) FindPatternByName(name string) (*entity.RotationPattern, error) {
+	var pattern entity.RotationPattern
+	if err := r.db.Where("name = ?", name).First(&pattern).Error; err != nil {
+		return nil, err
+	}
+	return &pattern, nil
+}
+
+func (r *repository) FindAllPatterns() ([]entity.RotationPattern, error) {
+	var patterns []entity.RotationPattern
+	err := r.db.Order("name asc").Find(&patterns).Error
+	return patterns, err
+}
+
+func (r *repository) UpdatePattern(pattern *entity.RotationPattern) error {
+	return r.db.Save(pattern).Error
+}
+
+func (r *repository) DeletePattern(id uint) error {
+	return r.db.Unscoped().Delete(&entity.RotationPattern{}, id).Error
+}
+
+func (r *repository) CountUsersWithShift(name entity.ShiftName) (int64, error) {
+	var count int64
+	err := r.db.Model(&entity.User{}).Where("shift = ?", name).Count(&count).Error
+	return count, err
+}
+
+func (r *repository) CountAssignmentsWithShift(name entity.ShiftName) (int64, error) {
+	var count int64
+	err := r.db.Model(&entity.WorkAssignment{}).Where("shift = ?", name).Count(&count).Error
+	return count, err
+}
+
+func (r *repository) CountSwapsWithShift(name entity.ShiftName) (int64, error) {
+	var count int64
+	err := r.db.Model(&entity.Swap{}).Where("original_shift = ? OR new_shift = ?", name, name).Count(&count).Error
+	return count, err
+}
+
+func (r *repository) CountOffersWithShift(name entity.ShiftName) (int64, error) {
+	var count int64
+	err := r.db.Model(&entity.ShiftOffer{}).Where("shift = ?", name).Count(&count).Error
+	return count, err
+}
+
+func (r *repository) CountEscalaEntriesWithShift(name entity.ShiftName) (int64, error) {
+	var count int64
+	err := r.db.Model(&entity.EscalaEntry{}).Where("shift = ?", name).Count(&count).Error
+	return count, err
+}
+
+func (r *repository) CountUsersWithPattern(id uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&entity.User{}).Where("rotation_pattern_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+func (r *repository) CountAssignmentsWithPattern(id uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&entity.WorkAssignment{}).Where("rotation_pattern_id = ?", id).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,294 @@
+package shift
+
+import (
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/pkg/ierr"
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+type Service interface {
+	SeedDefaults() error
+	CreateTemplate(template entity.ShiftTemplate, requestorType entity.UserType) (*entity.ShiftTemplate, *ierr.RestErr)
+	FindTemplates(team entity.TeamName) ([]entity.ShiftTemplate, *ierr.RestErr)
+	FindTemplateByName(name entity.ShiftName) (*entity.ShiftTemplate, *ierr.RestErr)
+	UpdateTemplate(id uint, template entity.ShiftTemplate, requestorType entity.UserType) (*entity.ShiftTemplate, *ierr.RestErr)
+	DeleteTemplate(id uint, requestorType entity.UserType) *ierr.RestErr
+	CreatePattern(pattern entity.RotationPattern, requestorType entity.UserType) (*entity.RotationPattern, *ierr.RestErr)
+	FindPatterns() ([]entity.RotationPattern, *ierr.RestErr)
+	FindPatternByID(id uint) (*entity.RotationPattern, *ierr.RestErr)
+	UpdatePattern(id uint, pattern entity.RotationPattern, requestorType entity.UserType) (*entity.RotationPattern, *ierr.RestErr)
+	DeletePattern(id uint, requestorType entity.UserType) *ierr.RestErr
+}
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+var defaultPatterns = []entity.RotationPattern{
+	{Name: "12x36", Sequence: "WO", Description: "12 hours on, 36 hours off"},
+	{Name: "6x1", Sequence: "WWWWWWO", Description: "6 days on, 1 day off"},
+	{Name: "5x2", Sequence: "WWWWWOO", Description: "5 days on, 2 days off"},
+	{Name: "4x4", Sequence: "WWWWOOOO", Description: "4 days on, 4 days off"},
+}
+
+// SeedDefaults makes sure the historical shifts and the usual rotation
+// patterns exist, so users created before templates keep a valid schedule.
+func (s *service) SeedDefaults() error {
+	for _, name := range []entity.ShiftName{entity.ShiftMorning, entity.ShiftAfternoon, entity.ShiftNight} {
+		if _, err := s.repo.FindTemplateByName(name); err == nil {
+			continue
+		} else if err != gorm.ErrRecordNotFound {
+			return err
+		}
+		template, err := entity.NewShiftTemplateFromName(name)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.CreateTemplate(template); err != nil {
+			return err
+		}
+		log.Printf("seeded shift template %s", name)
+	}
+	for _, pattern := range defaultPatterns {
+		if _, err := s.repo.FindPatternByName(pattern.Name); err == nil {
+			continue
+		} else if err != gorm.ErrRecordNotFound {
+			return err
+		}
+		pattern := pattern
+		if err := s.repo.CreatePattern(&pattern); err != nil {
+			return err
+		}
+		log.Printf("seeded rotation pattern %s", pattern.Name)
+	}
+	return nil
+}
+
+func (s *service) CreateTemplate(template entity.ShiftTemplate, requestorType entity.UserType) (*entity.ShiftTemplate, *ierr.RestErr) {
+	if requestorType != entity.UserTypeMaster {
+		return nil, ierr.NewForbiddenError("only masters can manage shift templates")
+	}
+	if err := prepareTemplate(&template); err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.FindTemplateByName(template.Name); err == nil {
+		return nil, ierr.NewConflictError("a shift template with this name already exists")
+	}
+	if err := s.repo.CreateTemplate(&template); err != nil {
+		return nil, ierr.NewInternalServerError("error creating shift template")
+	}
+	return &template, nil
+}
+
+func (s *service) FindTemplates(team entity.TeamName) ([]entity.ShiftTemplate, *ierr.RestErr) {
+	var templates []entity.ShiftTemplate
+	var err error
+	if team == "" {
+		templates, err = s.repo.FindAllTemplates()
+	} else {
+		templates, err = s.repo.FindTemplatesForTeam(team)
+	}
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding shift templates")
+	}
+	return templates, nil
+}
+
+func (s *service) FindTemplateByName(name entity.ShiftName) (*entity.ShiftTemplate, *ierr.RestErr) {
+	template, err := s.repo.FindTemplateByName(name)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ierr.NewNotFoundError(fmt.Sprintf("shift template %s not found", name))
+		}
+		return nil, ierr.NewInternalServerError("error finding shift template")
+	}
+	return template, nil
+}
+
+// UpdateTemplate changes the hours or team of a template. Renaming is not
+// allowed because users, swaps and escalas reference templates by name.
+func (s *service) UpdateTemplate(id uint, templateData entity.ShiftTemplate, requestorType entity.UserType) (*entity.ShiftTemplate, *ierr.RestErr) {
+	if requestorType != entity.UserTypeMaster {
+		return nil, ierr.NewForbiddenError("only masters can manage shift templates")
+	}
+	template, err := s.repo.FindTemplateByID(id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ierr.NewNotFoundError("shift template not found")
+		}
+		return nil, ierr.NewInternalServerError("error finding shift template")
+	}
+	if templateData.Name != template.Name {
+		return nil, ierr.NewBadRequestValidationError("invalid shift template", []ierr.Causes{
+			{Field: "name", Message: "cannot be changed"},
+		})
+	}
+	template.StartTime = templateData.StartTime
+	template.EndTime = templateData.EndTime
+	template.Team = templateData.Team
+	template.HolidayPolicy = templateData.HolidayPolicy
+	if err := prepareTemplate(template); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateTemplate(template); err != nil {
+		return nil, ierr.NewInternalServerError("error updating shift template")
+	}
+	return template, nil
+}
+
+func (s *service) DeleteTemplate(id uint, requestorType entity.UserType) *ierr.RestErr {
+	if requestorType != entity.UserTypeMaster {
+		return ierr.NewForbiddenError("only masters can manage shift templates")
+	}
+	template, err := s.repo.FindTemplateByID(id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ierr.NewNotFoundError("shift template not found")
+		}
+		return ierr.NewInternalServerError("error finding shift template")
+	}
+	// Schedules keep resolving shifts by name, so a template stays while any
+	// record still references it.
+	references := []struct {
+		what  string
+		count func(entity.ShiftName) (int64, error)
+	}{
+		{"users", s.repo.CountUsersWithShift},
+		{"work assignments", s.repo.CountAssignmentsWithShift},
+		{"swaps", s.repo.CountSwapsWithShift},
+		{"shift offers", s.repo.CountOffersWithShift},
+		{"escala entries", s.repo.CountEscalaEntriesWithShift},
+	}
+	for _, ref := range references {
+		inUse, err := ref.count(template.Name)
+		if err != nil {
+			return ierr.NewInternalServerError("error checking shift template usage")
+		}
+		if inUse > 0 {
+			return ierr.NewConflictError(fmt.Sprintf("shift template is referenced by %d %s", inUse, ref.what))
+		}
+	}
+	if err := s.repo.DeleteTemplate(id); err != nil {
+		return ierr.NewInternalServerError("error deleting shift template")
+	}
+	return nil
+}
+
+func (s *service) CreatePattern(pattern entity.RotationPattern, requestorType entity.UserType) (*entity.RotationPattern, *ierr.RestErr) {
+	if requestorType != entity.UserTypeMaster {
+		return nil, ierr.NewForbiddenError("only masters can manage rotation patterns")
+	}
+	if err := pattern.Validate(); err != nil {
+		return nil, ierr.NewBadRequestValidationError("invalid rotation pattern", []ierr.Causes{
+			{Field: "sequence", Message: err.Error()},
+		})
+	}
+	if _, err := s.repo.FindPatternByName(pattern.Name); err == nil {
+		return nil, ierr.NewConflictError("a rotation pattern with this name already exists")
+	}
+	if err := s.repo.CreatePattern(&pattern); err != nil {
+		return nil, ierr.NewInternalServerError("error creating rotation pattern")
+	}
+	return &pattern, nil
+}
+
+func (s *service) FindPatterns() ([]entity.RotationPattern, *ierr.RestErr) {
+	patterns, err := s.repo.FindAllPatterns()
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding rotation patterns")
+	}
+	return patterns, nil
+}
+
+func (s *service) FindPatternByID(id uint) (*entity.RotationPattern, *ierr.RestErr) {
+	pattern, err := s.repo.FindPatternByID(id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ierr.NewNotFoundError("rotation pattern not found")
+		}
+		return nil, ierr.NewInternalServerError("error finding rotation pattern")
+	}
+	return pattern, nil
+}
+
+func (s *service) UpdatePattern(id uint, patternData entity.RotationPattern, requestorType entity.UserType) (*entity.RotationPattern, *ierr.RestErr) {
+	if requestorType != entity.UserTypeMaster {
+		return nil, ierr.NewForbiddenError("only masters can manage rotation patterns")
+	}
+	pattern, restErr := s.FindPatternByID(id)
+	if restErr != nil {
+		return nil, restErr
+	}
+	if err := patternData.Validate(); err != nil {
+		return nil, ierr.NewBadRequestValidationError("invalid rotation pattern", []ierr.Causes{
+			{Field: "sequence", Message: err.Error()},
+		})
+	}
+	if other, err := s.repo.FindPatternByName(patternData.Name); err == nil && other.ID != pattern.ID {
+		return nil, ierr.NewConflictError("a rotation pattern with this name already exists")
+	}
+	pattern.Name = patternData.Name
+	pattern.Sequence = patternData.Sequence
+	pattern.Description = patternData.Description
+	if err := s.repo.UpdatePattern(pattern); err != nil {
+		return nil, ierr.NewInternalServerError("error updating rotation pattern")
+	}
+	return pattern, nil
+}
+
+func (s *service) DeletePattern(id uint, requestorType entity.UserType) *ierr.RestErr {
+	if requestorType != entity.UserTypeMaster {
+		return ierr.NewForbiddenError("only masters can manage rotation patterns")
+	}
+	if _, restErr := s.FindPatternByID(id); restErr != nil {
+		return restErr
+	}
+	// Past assignments keep resolving their schedule through the pattern.
+	references := []struct {
+		what  string
+		count func(uint) (int64, error)
+	}{
+		{"users", s.repo.CountUsersWithPattern},
+		{"work assignments", s.repo.CountAssignmentsWithPattern},
+	}
+	for _, ref := range references {
+		inUse, err := ref.count(id)
+		if err != nil {
+			return ierr.NewInternalServerError("error checking rotation pattern usage")
+		}
+		if inUse > 0 {
+			return ierr.NewConflictError(fmt.Sprintf("rotation pattern is assigned to %d %s", inUse, ref.what))
+		}
+	}
+	if err := s.repo.DeletePattern(id); err != nil {
+		return ierr.NewInternalServerError("error deleting rotation pattern")
+	}
+	return nil
+}
+
+func prepareTemplate(template *entity.ShiftTemplate) *ierr.RestErr {
+	start, errStart := entity.ParseClock(template.StartTime)
+	end, errEnd := entity.ParseClock(template.EndTime)
+	var causes []ierr.Causes
+	if errStart != nil {
+		causes = append(causes, ierr.Causes{Field: "startTime", Message: errStart.Error()})
+	}
+	if errEnd != nil {
+		causes = append(causes, ierr.Causes{Field: "endTime", Message: errEnd.Error()})
+	}
+	if len(causes) == 0 && start == end {
+		causes = append(causes, ierr.Causes{Field: "endTime", Message: "must differ from startTime"})
+	}
+	if len(causes) > 0 {
+		return ierr.NewBadRequestValidationError("invalid shift template", causes)
+	}
+	template.CrossesMidnight = end < start
+	return nil
+}
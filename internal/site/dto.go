@@ -0,0 +1,47 @@
+package site
+
+import (
+	"escala-fds-api/internal/constants"
+	"escala-fds-api/internal/entity"
+)
+
+type SiteRequest struct {
+	Name  string `json:"name" binding:"required,max=100"`
+	State string `json:"state" binding:"required,len=2,alpha"`
+	City  string `json:"city" binding:"required,max=100"`
+}
+
+// TeamSiteRequest sets the site of a team; a null siteId clears it.
+type TeamSiteRequest struct {
+	SiteID *uint `json:"siteId"`
+}
+
+type SiteResponse struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	State string `json:"state"`
+	City  string `json:"city"`
+}
+
+type TeamSiteResponse struct {
+	Team      entity.TeamName `json:"team"`
+	SiteID    uint            `json:"siteId"`
+	UpdatedAt string          `json:"updatedAt"`
+}
+
+func ToSiteResponse(site *entity.Site) SiteResponse {
+	return SiteResponse{
+		ID:    site.ID,
+		Name:  site.Name,
+		State: site.State,
+		City:  site.City,
+	}
+}
+
+func ToTeamSiteResponse(teamSite *entity.TeamSite) TeamSiteResponse {
+	return TeamSiteResponse{
+		Team:      teamSite.Team,
+		SiteID:    teamSite.SiteID,
+		UpdatedAt: teamSite.UpdatedAt.Format(constants.ApiTimestampLayout),
+	}
+}
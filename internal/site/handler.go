@@ -0,0 +1,156 @@
+package site
+
+import (
+	"escala-fds-api/internal/auth"
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/validation"
+	"escala-fds-api/pkg/ierr"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	siteRoutes := router.Group("/sites")
+	siteRoutes.Use(auth.Middleware(), auth.RequireScope("sites"))
+	{
+		siteRoutes.GET("", h.FindAll)
+		siteRoutes.POST("", h.Create)
+		siteRoutes.GET("/teams", h.FindTeamSites)
+		siteRoutes.PUT("/teams/:team", h.SetTeamSite)
+		siteRoutes.GET("/:id", h.FindByID)
+		siteRoutes.PUT("/:id", h.Update)
+		siteRoutes.DELETE("/:id", h.Delete)
+	}
+}
+
+func (h *Handler) FindAll(c *gin.Context) {
+	sites, err := h.service.FindAllSites()
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	res := make([]SiteResponse, 0, len(sites))
+	for i := range sites {
+		res = append(res, ToSiteResponse(&sites[i]))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *Handler) FindByID(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	site, err := h.service.FindSiteByID(uint(id))
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusOK, ToSiteResponse(site))
+}
+
+func (h *Handler) Create(c *gin.Context) {
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	var req SiteRequest
+	if errBind := validation.BindJSON(c, &req); errBind != nil {
+		c.JSON(errBind.Code, errBind)
+		return
+	}
+	site := entity.Site{Name: req.Name, State: req.State, City: req.City}
+	created, err := h.service.CreateSite(site, entity.UserType(requestorType))
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusCreated, ToSiteResponse(created))
+}
+
+func (h *Handler) Update(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	var req SiteRequest
+	if errBind := validation.BindJSON(c, &req); errBind != nil {
+		c.JSON(errBind.Code, errBind)
+		return
+	}
+	site := entity.Site{Name: req.Name, State: req.State, City: req.City}
+	updated, err := h.service.UpdateSite(uint(id), site, entity.UserType(requestorType))
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusOK, ToSiteResponse(updated))
+}
+
+func (h *Handler) Delete(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	if err := h.service.DeleteSite(uint(id), entity.UserType(requestorType)); err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) FindTeamSites(c *gin.Context) {
+	teamSites, err := h.service.FindTeamSites()
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	res := make([]TeamSiteResponse, 0, len(teamSites))
+	for i := range teamSites {
+		res = append(res, ToTeamSiteResponse(&teamSites[i]))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *Handler) SetTeamSite(c *gin.Context) {
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	team := entity.TeamName(c.Param("team"))
+	if !team.IsValid() {
+		restErr := ierr.NewBadRequestValidationError("some fields are invalid", []ierr.Causes{
+			{Field: "team", Message: "must be a valid team: Security, Support, CustomerService"},
+		})
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	var req TeamSiteRequest
+	if errBind := validation.BindJSON(c, &req); errBind != nil {
+		c.JSON(errBind.Code, errBind)
+		return
+	}
+	teamSite, err := h.service.SetTeamSite(team, req.SiteID, entity.UserType(requestorType))
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	if teamSite == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.JSON(http.StatusOK, ToTeamSiteResponse(teamSite))
+}
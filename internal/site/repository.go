@@ -0,0 +1,89 @@
+package site
+
+import (
+	"escala-fds-api/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	CreateSite(site *entity.Site) error
+	FindSiteByID(id uint) (*entity.Site, error)
+	FindSiteByName(name string) (*entity.Site, error)
+	FindAllSites() ([]entity.Site, error)
+	UpdateSite(site *entity.Site) error
+	DeleteSite(id uint) error
+	FindTeamSites() ([]entity.TeamSite, error)
+	SetTeamSite(teamSite *entity.TeamSite) error
+	DeleteTeamSite(team entity.TeamName) error
+	CountUsersAtSite(id uint) (int64, error)
+	CountTeamsAtSite(id uint) (int64, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) CreateSite(site *entity.Site) error {
+	return r.db.Create(site).Error
+}
+
+func (r *repository) FindSiteByID(id uint) (*entity.Site, error) {
+	var site entity.Site
+	if err := r.db.First(&site, id).Error; err != nil {
+		return nil, err
+	}
+	return &site, nil
+}
+
+func (r *repository) FindSiteByName(name string) (*entity.Site, error) {
+	var site entity.Site
+	if err := r.db.Where("name = ?", name).First(&site).Error; err != nil {
+		return nil, err
+	}
+	return &site, nil
+}
+
+func (r *repository) FindAllSites() ([]entity.Site, error) {
+	var sites []entity.Site
+	err := r.db.Order("name asc").Find(&sites).Error
+	return sites, err
+}
+
+func (r *repository) UpdateSite(site *entity.Site) error {
+	return r.db.Save(site).Error
+}
+
+func (r *repository) DeleteSite(id uint) error {
+	return r.db.Delete(&entity.Site{}, id).Error
+}
+
+func (r *repository) FindTeamSites() ([]entity.TeamSite, error) {
+	var teamSites []entity.TeamSite
+	err := r.db.Order("team asc").Find(&teamSites).Error
+	return teamSites, err
+}
+
+func (r *repository) SetTeamSite(teamSite *entity.TeamSite) error {
+	return r.db.Save(teamSite).Error
+}
+
+func (r *repository) DeleteTeamSite(team entity.TeamName) error {
+	return r.db.Where("team = ?", team).Delete(&entity.TeamSite{}).Error
+}
+
+func (r *repository) CountUsersAtSite(id uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&entity.User{}).Where("site_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+func (r *repository) CountTeamsAtSite(id uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&entity.TeamSite{}).Where("site_id = ?", id).Count(&count).Error
+	return count, err
+}
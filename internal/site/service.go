@@ -0,0 +1,148 @@
+package site
+
+import (
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/pkg/ierr"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type Service interface {
+	CreateSite(site entity.Site, requestorType entity.UserType) (*entity.Site, *ierr.RestErr)
+	FindAllSites() ([]entity.Site, *ierr.RestErr)
+	FindSiteByID(id uint) (*entity.Site, *ierr.RestErr)
+	UpdateSite(id uint, site entity.Site, requestorType entity.UserType) (*entity.Site, *ierr.RestErr)
+	DeleteSite(id uint, requestorType entity.UserType) *ierr.RestErr
+	FindTeamSites() ([]entity.TeamSite, *ierr.RestErr)
+	SetTeamSite(team entity.TeamName, siteID *uint, requestorType entity.UserType) (*entity.TeamSite, *ierr.RestErr)
+}
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+func (s *service) CreateSite(site entity.Site, requestorType entity.UserType) (*entity.Site, *ierr.RestErr) {
+	if requestorType != entity.UserTypeMaster {
+		return nil, ierr.NewForbiddenError("only masters can manage sites")
+	}
+	normalizeSite(&site)
+	if _, err := s.repo.FindSiteByName(site.Name); err == nil {
+		return nil, ierr.NewConflictError("a site with this name already exists")
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, ierr.NewInternalServerError("error finding site")
+	}
+	if err := s.repo.CreateSite(&site); err != nil {
+		return nil, ierr.NewInternalServerError("error creating site")
+	}
+	return &site, nil
+}
+
+func (s *service) FindAllSites() ([]entity.Site, *ierr.RestErr) {
+	sites, err := s.repo.FindAllSites()
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding sites")
+	}
+	return sites, nil
+}
+
+func (s *service) FindSiteByID(id uint) (*entity.Site, *ierr.RestErr) {
+	site, err := s.repo.FindSiteByID(id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ierr.NewNotFoundError("site not found")
+		}
+		return nil, ierr.NewInternalServerError("error finding site")
+	}
+	return site, nil
+}
+
+func (s *service) UpdateSite(id uint, siteData entity.Site, requestorType entity.UserType) (*entity.Site, *ierr.RestErr) {
+	if requestorType != entity.UserTypeMaster {
+		return nil, ierr.NewForbiddenError("only masters can manage sites")
+	}
+	site, restErr := s.FindSiteByID(id)
+	if restErr != nil {
+		return nil, restErr
+	}
+	normalizeSite(&siteData)
+	if existing, err := s.repo.FindSiteByName(siteData.Name); err == nil && existing.ID != id {
+		return nil, ierr.NewConflictError("a site with this name already exists")
+	} else if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, ierr.NewInternalServerError("error finding site")
+	}
+	site.Name = siteData.Name
+	site.State = siteData.State
+	site.City = siteData.City
+	if err := s.repo.UpdateSite(site); err != nil {
+		return nil, ierr.NewInternalServerError("error updating site")
+	}
+	return site, nil
+}
+
+// DeleteSite refuses to remove a site still assigned to users or teams,
+// since their holidays would silently change.
+func (s *service) DeleteSite(id uint, requestorType entity.UserType) *ierr.RestErr {
+	if requestorType != entity.UserTypeMaster {
+		return ierr.NewForbiddenError("only masters can manage sites")
+	}
+	if _, err := s.FindSiteByID(id); err != nil {
+		return err
+	}
+	users, err := s.repo.CountUsersAtSite(id)
+	if err != nil {
+		return ierr.NewInternalServerError("error checking site usage")
+	}
+	teams, err := s.repo.CountTeamsAtSite(id)
+	if err != nil {
+		return ierr.NewInternalServerError("error checking site usage")
+	}
+	if users > 0 || teams > 0 {
+		return ierr.NewConflictError(fmt.Sprintf("site is assigned to %d users and %d teams", users, teams))
+	}
+	if err := s.repo.DeleteSite(id); err != nil {
+		return ierr.NewInternalServerError("error deleting site")
+	}
+	return nil
+}
+
+func (s *service) FindTeamSites() ([]entity.TeamSite, *ierr.RestErr) {
+	teamSites, err := s.repo.FindTeamSites()
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding team sites")
+	}
+	return teamSites, nil
+}
+
+// SetTeamSite assigns the site of a team's collaborators; a nil siteID
+// removes it, leaving them with national holidays only.
+func (s *service) SetTeamSite(team entity.TeamName, siteID *uint, requestorType entity.UserType) (*entity.TeamSite, *ierr.RestErr) {
+	if requestorType != entity.UserTypeMaster {
+		return nil, ierr.NewForbiddenError("only masters can manage sites")
+	}
+	if siteID == nil {
+		if err := s.repo.DeleteTeamSite(team); err != nil {
+			return nil, ierr.NewInternalServerError("error removing team site")
+		}
+		return nil, nil
+	}
+	if _, err := s.FindSiteByID(*siteID); err != nil {
+		return nil, err
+	}
+	teamSite := &entity.TeamSite{Team: team, SiteID: *siteID}
+	if err := s.repo.SetTeamSite(teamSite); err != nil {
+		return nil, ierr.NewInternalServerError("error setting team site")
+	}
+	return teamSite, nil
+}
+
+func normalizeSite(site *entity.Site) {
+	site.Name = strings.TrimSpace(site.Name)
+	site.State = strings.ToUpper(strings.TrimSpace(site.State))
+	site.City = strings.TrimSpace(site.City)
+}
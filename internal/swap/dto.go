@@ -1,21 +1,81 @@
 package swap
 
 import (
+	"escala-fds-api/internal/constants"
 	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/rules"
 	"escala-fds-api/internal/user"
+	"strconv"
+	"strings"
 )
 
 type CreateSwapRequest struct {
 	InvolvedCollaboratorID *uint            `json:"involvedCollaboratorId"`
-	OriginalDate           string           `json:"originalDate" binding:"required"`
-	NewDate                string           `json:"newDate" binding:"required"`
-	OriginalShift          entity.ShiftName `json:"originalShift" binding:"required"`
-	NewShift               entity.ShiftName `json:"newShift" binding:"required"`
+	OriginalDate           string           `json:"originalDate" binding:"required,apidate"`
+	NewDate                string           `json:"newDate" binding:"required,apidate"`
+	OriginalShift          entity.ShiftName `json:"originalShift" binding:"required,shift"`
+	NewShift               entity.ShiftName `json:"newShift" binding:"required,shift"`
 	Reason                 string           `json:"reason"`
+	Override               bool             `json:"override"`
+	OverrideReason         string           `json:"overrideReason" binding:"max=500"`
 }
 
 type UpdateSwapStatusRequest struct {
-	Status entity.SwapStatus `json:"status" binding:"required,oneof=approved rejected"`
+	Status         entity.SwapStatus `json:"status" binding:"required,oneof=approved rejected"`
+	Override       bool              `json:"override"`
+	OverrideReason string            `json:"overrideReason" binding:"max=500"`
+}
+
+type CreateShiftOfferRequest struct {
+	Date  string           `json:"date" binding:"required,apidate"`
+	Shift entity.ShiftName `json:"shift" binding:"required,shift"`
+	Note  string           `json:"note" binding:"max=500"`
+}
+
+type ShiftOfferResponse struct {
+	ID        uint                    `json:"id"`
+	Offerer   user.UserResponse       `json:"offerer"`
+	Team      entity.TeamName         `json:"team"`
+	Date      string                  `json:"date"`
+	Shift     entity.ShiftName        `json:"shift"`
+	Note      string                  `json:"note,omitempty"`
+	Status    entity.ShiftOfferStatus `json:"status"`
+	ClaimedBy *user.UserResponse      `json:"claimedBy,omitempty"`
+	SwapID    *uint                   `json:"swapId,omitempty"`
+	CreatedAt string                  `json:"createdAt"`
+}
+
+func ToShiftOfferResponse(offer *entity.ShiftOffer, offerer, claimedBy *entity.User) ShiftOfferResponse {
+	var claimedByResponse *user.UserResponse
+	if claimedBy != nil {
+		res := user.ToUserResponse(claimedBy)
+		claimedByResponse = &res
+	}
+	return ShiftOfferResponse{
+		ID:        offer.ID,
+		Offerer:   user.ToUserResponse(offerer),
+		Team:      offer.Team,
+		Date:      offer.Date.Format(constants.ApiDateLayout),
+		Shift:     offer.Shift,
+		Note:      offer.Note,
+		Status:    offer.Status,
+		ClaimedBy: claimedByResponse,
+		SwapID:    offer.SwapID,
+		CreatedAt: offer.CreatedAt.Format(constants.ApiTimestampLayout),
+	}
+}
+
+// SwapSuggestionResponse proposes a swap with Collaborator, as the fields of
+// the CreateSwapRequest to send: they work NewShift on NewDate, the day the
+// requester gives up, and are off on OriginalDate, where the requester works
+// NewShift instead of their OriginalShift.
+type SwapSuggestionResponse struct {
+	Collaborator  user.UserResponse `json:"collaborator"`
+	OriginalDate  string            `json:"originalDate"`
+	OriginalShift entity.ShiftName  `json:"originalShift"`
+	NewDate       string            `json:"newDate"`
+	NewShift      entity.ShiftName  `json:"newShift"`
+	RecentSwaps   int64             `json:"recentSwaps"`
 }
 
 type SwapResponse struct {
@@ -31,4 +91,48 @@ type SwapResponse struct {
 	ApprovedBy           *user.UserResponse `json:"approvedBy,omitempty"`
 	CreatedAt            string             `json:"createdAt"`
 	ApprovedAt           *string            `json:"approvedAt,omitempty"`
+	ShiftOfferID         *uint              `json:"shiftOfferId,omitempty"`
+	OverriddenRules      []entity.RuleName  `json:"overriddenRules,omitempty"`
+	OverrideReason       string             `json:"overrideReason,omitempty"`
+	Warnings             []rules.Violation  `json:"warnings,omitempty"`
+}
+
+var swapExportHeader = []string{"id", "requesterId", "requester", "involvedCollaboratorId", "involvedCollaborator",
+	"originalDate", "newDate", "originalShift", "newShift", "reason", "status", "approvedBy", "createdAt", "approvedAt",
+	"overriddenRules", "overrideReason"}
+
+func (r *SwapResponse) exportRow() []string {
+	var involvedID, involvedName, approvedBy, approvedAt string
+	if r.InvolvedCollaborator != nil {
+		involvedID = strconv.FormatUint(uint64(r.InvolvedCollaborator.ID), 10)
+		involvedName = r.InvolvedCollaborator.FirstName + " " + r.InvolvedCollaborator.LastName
+	}
+	if r.ApprovedBy != nil {
+		approvedBy = r.ApprovedBy.FirstName + " " + r.ApprovedBy.LastName
+	}
+	if r.ApprovedAt != nil {
+		approvedAt = *r.ApprovedAt
+	}
+	overridden := make([]string, 0, len(r.OverriddenRules))
+	for _, rule := range r.OverriddenRules {
+		overridden = append(overridden, string(rule))
+	}
+	return []string{
+		strconv.FormatUint(uint64(r.ID), 10),
+		strconv.FormatUint(uint64(r.Requester.ID), 10),
+		r.Requester.FirstName + " " + r.Requester.LastName,
+		involvedID,
+		involvedName,
+		r.OriginalDate,
+		r.NewDate,
+		string(r.OriginalShift),
+		string(r.NewShift),
+		r.Reason,
+		string(r.Status),
+		approvedBy,
+		r.CreatedAt,
+		approvedAt,
+		strings.Join(overridden, ";"),
+		r.OverrideReason,
+	}
 }
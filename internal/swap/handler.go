@@ -3,10 +3,10 @@ package swap
 import (
 	"escala-fds-api/internal/auth"
 	"escala-fds-api/internal/entity"
-	"escala-fds-api/pkg/ierr"
+	"escala-fds-api/internal/export"
+	"escala-fds-api/internal/validation"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,21 +21,30 @@ func NewHandler(service Service) *Handler {
 
 func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 	swapRoutes := router.Group("/swaps")
-	swapRoutes.Use(auth.Middleware())
+	swapRoutes.Use(auth.Middleware(), auth.RequireScope("swaps"))
 	{
 		swapRoutes.POST("", h.Create)
 		swapRoutes.GET("", h.FindAll)
+		swapRoutes.GET("/suggestions", h.Suggestions)
 		swapRoutes.GET("/user/:id", h.FindByUser)
 		swapRoutes.GET("/:id", h.FindByID)
 		swapRoutes.PATCH("/:id/status", h.UpdateStatus)
 		swapRoutes.DELETE("/:id", h.Delete)
 	}
+	offerRoutes := router.Group("/shift-offers")
+	offerRoutes.Use(auth.Middleware(), auth.RequireScope("swaps"))
+	{
+		offerRoutes.POST("", h.CreateOffer)
+		offerRoutes.GET("", h.FindEligibleOffers)
+		offerRoutes.POST("/:id/claim", h.ClaimOffer)
+		offerRoutes.DELETE("/:id", h.CancelOffer)
+	}
 }
 
 func (h *Handler) Create(c *gin.Context) {
 	var req CreateSwapRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ierr.NewBadRequestError(err.Error()))
+	if errBind := validation.BindJSON(c, &req); errBind != nil {
+		c.JSON(errBind.Code, errBind)
 		return
 	}
 
@@ -51,14 +60,14 @@ func (h *Handler) Create(c *gin.Context) {
 		return
 	}
 
-	originalDate, err := time.ParseInLocation("2006-01-02", req.OriginalDate, time.UTC)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ierr.NewBadRequestError("invalid originalDate format"))
+	originalDate, errDate := validation.ParseDate("originalDate", req.OriginalDate)
+	if errDate != nil {
+		c.JSON(errDate.Code, errDate)
 		return
 	}
-	newDate, err := time.ParseInLocation("2006-01-02", req.NewDate, time.UTC)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ierr.NewBadRequestError("invalid newDate format"))
+	newDate, errDate := validation.ParseDate("newDate", req.NewDate)
+	if errDate != nil {
+		c.JSON(errDate.Code, errDate)
 		return
 	}
 
@@ -71,7 +80,8 @@ func (h *Handler) Create(c *gin.Context) {
 		Reason:                 req.Reason,
 	}
 
-	newSwap, errSvc := h.service.CreateSwap(swapEntity, requesterID, entity.UserType(requesterType))
+	newSwap, errSvc := h.service.CreateSwap(swapEntity, requesterID, entity.UserType(requesterType),
+		Override{Enabled: req.Override, Reason: req.OverrideReason})
 	if errSvc != nil {
 		c.JSON(errSvc.Code, errSvc)
 		return
@@ -80,14 +90,52 @@ func (h *Handler) Create(c *gin.Context) {
 }
 
 func (h *Handler) FindAll(c *gin.Context) {
+	format, restErr := export.FormatFromRequest(c)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
 	swaps, err := h.service.FindAllSwaps()
 	if err != nil {
 		c.JSON(err.Code, err)
 		return
 	}
+	if format != export.FormatJSON {
+		export.Write(c, format, "swaps", swapExportHeader, func(w export.RowWriter) error {
+			for i := range swaps {
+				if err := w.WriteRow(swaps[i].exportRow()); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		return
+	}
 	c.JSON(http.StatusOK, swaps)
 }
 
+// Suggestions lists colleagues who can take the caller's shift on "date",
+// optionally checked against the expected "shift".
+func (h *Handler) Suggestions(c *gin.Context) {
+	requesterID, errAuth := auth.GetUserIDFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	date, errDate := validation.ParseDate("date", c.Query("date"))
+	if errDate != nil {
+		c.JSON(errDate.Code, errDate)
+		return
+	}
+	shift := entity.ShiftName(c.Query("shift"))
+	suggestions, err := h.service.SuggestSwaps(requesterID, date, shift)
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusOK, suggestions)
+}
+
 func (h *Handler) FindByUser(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
 	statusFilter := c.Query("status")
@@ -118,11 +166,12 @@ func (h *Handler) UpdateStatus(c *gin.Context) {
 		return
 	}
 	var req UpdateSwapStatusRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ierr.NewBadRequestError(err.Error()))
+	if errBind := validation.BindJSON(c, &req); errBind != nil {
+		c.JSON(errBind.Code, errBind)
 		return
 	}
-	updatedSwap, err := h.service.ApproveOrRejectSwap(uint(id), approverID, req.Status)
+	updatedSwap, err := h.service.ApproveOrRejectSwap(uint(id), approverID, req.Status,
+		Override{Enabled: req.Override, Reason: req.OverrideReason})
 	if err != nil {
 		c.JSON(err.Code, err)
 		return
@@ -150,3 +199,82 @@ func (h *Handler) Delete(c *gin.Context) {
 	}
 	c.Status(http.StatusNoContent)
 }
+
+func (h *Handler) CreateOffer(c *gin.Context) {
+	var req CreateShiftOfferRequest
+	if errBind := validation.BindJSON(c, &req); errBind != nil {
+		c.JSON(errBind.Code, errBind)
+		return
+	}
+	offererID, errAuth := auth.GetUserIDFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	date, errDate := validation.ParseDate("date", req.Date)
+	if errDate != nil {
+		c.JSON(errDate.Code, errDate)
+		return
+	}
+
+	offer := entity.ShiftOffer{Date: date, Shift: req.Shift, Note: req.Note}
+	newOffer, errSvc := h.service.CreateOffer(offer, offererID)
+	if errSvc != nil {
+		c.JSON(errSvc.Code, errSvc)
+		return
+	}
+	c.JSON(http.StatusCreated, newOffer)
+}
+
+func (h *Handler) FindEligibleOffers(c *gin.Context) {
+	requestorID, errAuth := auth.GetUserIDFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	offers, err := h.service.FindEligibleOffers(requestorID, entity.UserType(requestorType))
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusOK, offers)
+}
+
+func (h *Handler) ClaimOffer(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	claimerID, errAuth := auth.GetUserIDFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	swap, err := h.service.ClaimOffer(uint(id), claimerID)
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusCreated, swap)
+}
+
+func (h *Handler) CancelOffer(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	requesterID, errAuth := auth.GetUserIDFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	requesterType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	if err := h.service.CancelOffer(uint(id), requesterID, entity.UserType(requesterType)); err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
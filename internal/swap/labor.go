@@ -0,0 +1,179 @@
+package swap
+
+import (
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/rules"
+	"escala-fds-api/internal/schedule"
+	"escala-fds-api/pkg/ierr"
+	"sort"
+	"time"
+)
+
+// ruleContextDays is the margin loaded around the days a swap changes: two
+// weeks cover the weekly rest and consecutive days rules, and the range is
+// extended to whole months for the monthly limits.
+const ruleContextDays = 14
+
+// sideSchedule is the schedule of one side of a swap around the dates being
+// evaluated. It is loaded once and projected for every swap tried against
+// it, so that several candidate swaps cost no more queries than one.
+type sideSchedule struct {
+	user  *entity.User
+	field string
+	days  []schedule.Day
+	// swapsByMonth caches, per month, the swaps of the user other than the
+	// evaluated one.
+	swapsByMonth map[string]int
+	excludeID    uint
+}
+
+// shiftWindows resolves the hours of each shift once. Shift templates are
+// the same every day, so the window of a date is its offset from midnight.
+type shiftWindows struct {
+	schedule schedule.Service
+	offsets  map[entity.ShiftName][2]time.Duration
+}
+
+func newShiftWindows(scheduleService schedule.Service) *shiftWindows {
+	return &shiftWindows{schedule: scheduleService, offsets: make(map[entity.ShiftName][2]time.Duration)}
+}
+
+func (w *shiftWindows) window(shift entity.ShiftName, date time.Time) (time.Time, time.Time, error) {
+	day := schedule.DateOnly(date)
+	offsets, ok := w.offsets[shift]
+	if !ok {
+		start, end, err := w.schedule.ShiftWindow(shift, day)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		offsets = [2]time.Duration{start.Sub(day), end.Sub(day)}
+		w.offsets[shift] = offsets
+	}
+	return day.Add(offsets[0]), day.Add(offsets[1]), nil
+}
+
+// ruleViolations evaluates the labor rules of the requester's team on the
+// schedules of the requester and of the involved collaborator as they would
+// be once the swap is approved. The days the swap gives someone a shift to
+// work are the focus of the time-based rules.
+func (s *service) ruleViolations(swap *entity.Swap, requester, involved *entity.User) ([]rules.Violation, *ierr.RestErr) {
+	config, restErr := s.rules.Config(requester.Team)
+	if restErr != nil {
+		return nil, restErr
+	}
+	first, last := swap.OriginalDate, swap.NewDate
+	if last.Before(first) {
+		first, last = last, first
+	}
+	sides := make([]*sideSchedule, 0, 2)
+	for _, side := range []struct {
+		field string
+		user  *entity.User
+	}{{"requester", requester}, {"involvedCollaborator", involved}} {
+		if side.user == nil {
+			continue
+		}
+		loaded, restErr := s.loadSide(side.user, side.field, first, last, swap.ID)
+		if restErr != nil {
+			return nil, restErr
+		}
+		sides = append(sides, loaded)
+	}
+	return s.evaluateSwap(swap, config, newShiftWindows(s.schedule), sides...)
+}
+
+// evaluateSwap runs the rules on every side of the swap, with the swap
+// applied to the loaded schedules.
+func (s *service) evaluateSwap(swap *entity.Swap, config rules.Config, windows *shiftWindows, sides ...*sideSchedule) ([]rules.Violation, *ierr.RestErr) {
+	var violations []rules.Violation
+	for _, side := range sides {
+		timeline, restErr := s.projectedTimeline(side, swap, windows)
+		if restErr != nil {
+			return nil, restErr
+		}
+		violations = append(violations, rules.Evaluate(timeline, config, side.field)...)
+	}
+	return violations, nil
+}
+
+// loadSide resolves the schedule of u from ruleContextDays before first to
+// ruleContextDays after last.
+func (s *service) loadSide(u *entity.User, field string, first, last time.Time, excludeID uint) (*sideSchedule, *ierr.RestErr) {
+	first, last = schedule.DateOnly(first), schedule.DateOnly(last)
+	start := first.AddDate(0, 0, -ruleContextDays)
+	if monthStart := first.AddDate(0, 0, 1-first.Day()); monthStart.Before(start) {
+		start = monthStart
+	}
+	end := last.AddDate(0, 0, ruleContextDays)
+	if monthEnd := last.AddDate(0, 1, -last.Day()); monthEnd.After(end) {
+		end = monthEnd
+	}
+	days, err := s.schedule.ShiftsInRange(u, start, end)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("could not determine schedule for the swap")
+	}
+	return &sideSchedule{
+		user:         u,
+		field:        field,
+		days:         days,
+		swapsByMonth: make(map[string]int),
+		excludeID:    excludeID,
+	}, nil
+}
+
+// swapsInMonth counts the other swaps of the side in the month of date.
+func (s *service) swapsInMonth(side *sideSchedule, date time.Time) (int, *ierr.RestErr) {
+	month := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	key := month.Format("2006-01")
+	if count, ok := side.swapsByMonth[key]; ok {
+		return count, nil
+	}
+	count, err := s.swapRepo.CountSwapsBetween(side.user.ID, month, month.AddDate(0, 1, -1), side.excludeID)
+	if err != nil {
+		return 0, ierr.NewInternalServerError("error counting swaps of the month")
+	}
+	side.swapsByMonth[key] = int(count)
+	return int(count), nil
+}
+
+// projectedTimeline applies the swap to a copy of the loaded schedule.
+func (s *service) projectedTimeline(side *sideSchedule, swap *entity.Swap, windows *shiftWindows) (*rules.Timeline, *ierr.RestErr) {
+	swaps, restErr := s.swapsInMonth(side, swap.OriginalDate)
+	if restErr != nil {
+		return nil, restErr
+	}
+	timeline := &rules.Timeline{SwapsInMonth: swaps + 1}
+
+	for _, date := range []time.Time{swap.OriginalDate, swap.NewDate} {
+		if _, working, found := swap.Effect(side.user.ID, date); found && working && !containsDate(timeline.Focus, date) {
+			timeline.Focus = append(timeline.Focus, schedule.DateOnly(date))
+		}
+	}
+	if len(timeline.Focus) == 0 {
+		return timeline, nil
+	}
+	sort.Slice(timeline.Focus, func(i, j int) bool { return timeline.Focus[i].Before(timeline.Focus[j]) })
+
+	days := make([]schedule.Day, len(side.days))
+	copy(days, side.days)
+	for i := range days {
+		if shift, working, found := swap.Effect(side.user.ID, days[i].Date); found {
+			days[i].Shift, days[i].Working = shift, working
+		}
+	}
+	spans, err := rules.SpansOf(days, windows.window)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("could not determine hours of the shifts around the swap")
+	}
+	timeline.Days, timeline.Spans = days, spans
+	return timeline, nil
+}
+
+func containsDate(dates []time.Time, date time.Time) bool {
+	for _, d := range dates {
+		if schedule.SameDay(d, date) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,205 @@
+package swap
+
+import (
+	"errors"
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/rules"
+	"escala-fds-api/internal/schedule"
+	"escala-fds-api/pkg/ierr"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CreateOffer puts a shift on the marketplace. Only collaborators can offer,
+// and only a shift they are scheduled to work from today on.
+func (s *service) CreateOffer(offer entity.ShiftOffer, offererID uint) (*ShiftOfferResponse, *ierr.RestErr) {
+	offerer, err := s.userRepo.FindUserByID(offererID)
+	if err != nil {
+		return nil, ierr.NewBadRequestError("offerer not found")
+	}
+	if offerer.UserType != entity.UserTypeCollaborator {
+		return nil, ierr.NewForbiddenError("only collaborators can offer shifts")
+	}
+	offer.Date = schedule.DateOnly(offer.Date)
+	if offer.Date.Before(schedule.DateOnly(time.Now().UTC())) {
+		return nil, ierr.NewBadRequestValidationError("invalid shift offer", []ierr.Causes{
+			{Field: "date", Message: "must not be in the past"},
+		})
+	}
+	shift, working, err := s.schedule.ShiftForDay(offerer, offer.Date)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("could not determine schedule for the offered date")
+	}
+	if !working || shift != offer.Shift {
+		return nil, ierr.NewBadRequestValidationError("invalid shift offer", []ierr.Causes{
+			{Field: "shift", Message: "you are not scheduled to work this shift on this date"},
+		})
+	}
+	if _, err := s.swapRepo.FindOpenOfferByOfferer(offerer.ID, offer.Date); err == nil {
+		return nil, ierr.NewConflictError("you already have an open offer for this date")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ierr.NewInternalServerError("error finding shift offers")
+	}
+
+	offer.OffererID = offerer.ID
+	offer.Team = offerer.Team
+	offer.Status = entity.ShiftOfferOpen
+	if err := s.swapRepo.CreateOffer(&offer); err != nil {
+		return nil, ierr.NewInternalServerError("error creating shift offer")
+	}
+	res := ToShiftOfferResponse(&offer, offerer, nil)
+	return &res, nil
+}
+
+// FindEligibleOffers lists the open offers the requestor could claim: offers
+// of colleagues of the same team whose handover passes validateSwap and who
+// are off on that day. Masters see every open offer.
+func (s *service) FindEligibleOffers(requestorID uint, requestorType entity.UserType) ([]ShiftOfferResponse, *ierr.RestErr) {
+	today := schedule.DateOnly(time.Now().UTC())
+	if requestorType == entity.UserTypeMaster {
+		offers, err := s.swapRepo.FindOpenOffers("", today)
+		if err != nil {
+			return nil, ierr.NewInternalServerError("error finding shift offers")
+		}
+		return s.buildOfferResponses(offers), nil
+	}
+
+	claimer, err := s.userRepo.FindUserByID(requestorID)
+	if err != nil {
+		return nil, ierr.NewBadRequestError("requester not found")
+	}
+	offers, err := s.swapRepo.FindOpenOffers(claimer.Team, today)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding shift offers")
+	}
+	eligible := offers[:0]
+	for i := range offers {
+		_, restErr := s.checkClaim(&offers[i], claimer)
+		if restErr == nil {
+			eligible = append(eligible, offers[i])
+			continue
+		}
+		if restErr.Code == http.StatusInternalServerError {
+			return nil, restErr
+		}
+	}
+	return s.buildOfferResponses(eligible), nil
+}
+
+// ClaimOffer turns the claim into a pending handover swap for the offerer's
+// superior to approve.
+func (s *service) ClaimOffer(offerID, claimerID uint) (*SwapResponse, *ierr.RestErr) {
+	offer, restErr := s.findOffer(offerID)
+	if restErr != nil {
+		return nil, restErr
+	}
+	if offer.Status != entity.ShiftOfferOpen {
+		return nil, ierr.NewConflictError("this shift offer is no longer open")
+	}
+	if offer.Date.Before(schedule.DateOnly(time.Now().UTC())) {
+		return nil, ierr.NewBadRequestError("this shift offer has expired")
+	}
+	claimer, err := s.userRepo.FindUserByID(claimerID)
+	if err != nil {
+		return nil, ierr.NewBadRequestError("requester not found")
+	}
+	if claimer.UserType != entity.UserTypeCollaborator {
+		return nil, ierr.NewForbiddenError("only collaborators can claim shifts")
+	}
+	warnings, restErr := s.checkClaim(offer, claimer)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	swap := handoverSwap(offer, claimer.ID)
+	if err := s.swapRepo.ClaimOffer(offer, &swap); err != nil {
+		if errors.Is(err, ErrOfferTaken) {
+			return nil, ierr.NewConflictError("this shift offer is no longer open")
+		}
+		return nil, ierr.NewInternalServerError("error claiming shift offer")
+	}
+	return s.buildResponseWithWarnings(swap.ID, warnings)
+}
+
+// CancelOffer withdraws an open offer. Claimed offers are withdrawn by
+// deleting their pending swap instead.
+func (s *service) CancelOffer(offerID, requesterID uint, requesterType entity.UserType) *ierr.RestErr {
+	offer, restErr := s.findOffer(offerID)
+	if restErr != nil {
+		return restErr
+	}
+	if requesterType != entity.UserTypeMaster && offer.OffererID != requesterID {
+		return ierr.NewForbiddenError("you can only cancel your own shift offers")
+	}
+	if offer.Status != entity.ShiftOfferOpen {
+		return ierr.NewConflictError("only open shift offers can be cancelled")
+	}
+	offer.Status = entity.ShiftOfferCancelled
+	if err := s.swapRepo.UpdateOffer(offer); err != nil {
+		return ierr.NewInternalServerError("error cancelling shift offer")
+	}
+	return nil
+}
+
+// checkClaim tells whether claimer may take the offered shift: not their own
+// offer, a day they are off, and a handover validateSwap accepts. It returns
+// the rule warnings of the handover.
+func (s *service) checkClaim(offer *entity.ShiftOffer, claimer *entity.User) ([]rules.Violation, *ierr.RestErr) {
+	if offer.OffererID == claimer.ID {
+		return nil, ierr.NewBadRequestError("you cannot claim your own shift offer")
+	}
+	_, working, err := s.schedule.ShiftForDay(claimer, offer.Date)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("could not determine schedule for the offered date")
+	}
+	if working {
+		return nil, ierr.NewBadRequestError("you are already scheduled to work on this date")
+	}
+	swap := handoverSwap(offer, claimer.ID)
+	return s.validateSwap(&swap, Override{})
+}
+
+func (s *service) findOffer(id uint) (*entity.ShiftOffer, *ierr.RestErr) {
+	offer, err := s.swapRepo.FindOfferByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ierr.NewNotFoundError("shift offer not found")
+		}
+		return nil, ierr.NewInternalServerError("error finding shift offer")
+	}
+	return offer, nil
+}
+
+// handoverSwap is the pending swap created when claimerID claims offer.
+func handoverSwap(offer *entity.ShiftOffer, claimerID uint) entity.Swap {
+	offerID := offer.ID
+	return entity.Swap{
+		RequesterID:            offer.OffererID,
+		InvolvedCollaboratorID: &claimerID,
+		OriginalDate:           offer.Date,
+		NewDate:                offer.Date,
+		OriginalShift:          offer.Shift,
+		NewShift:               offer.Shift,
+		Reason:                 offer.Note,
+		Status:                 entity.StatusPending,
+		ShiftOfferID:           &offerID,
+	}
+}
+
+func (s *service) buildOfferResponses(offers []entity.ShiftOffer) []ShiftOfferResponse {
+	responses := make([]ShiftOfferResponse, 0, len(offers))
+	for i := range offers {
+		offerer, err := s.userRepo.FindUserByID(offers[i].OffererID)
+		if err != nil {
+			continue
+		}
+		var claimedBy *entity.User
+		if offers[i].ClaimedByID != nil {
+			claimedBy, _ = s.userRepo.FindUserByID(*offers[i].ClaimedByID)
+		}
+		responses = append(responses, ToShiftOfferResponse(&offers[i], offerer, claimedBy))
+	}
+	return responses
+}
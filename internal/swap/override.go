@@ -0,0 +1,86 @@
+package swap
+
+import (
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/rules"
+	"escala-fds-api/pkg/ierr"
+	"log"
+	"strings"
+)
+
+// auditEntitySwap names swaps in the audit trail.
+const auditEntitySwap = "swap"
+
+// Override is a master's decision to approve a swap despite the blocking
+// labor rules it breaks. Reason is mandatory.
+type Override struct {
+	Enabled bool
+	Reason  string
+}
+
+func (o Override) check(actorType entity.UserType) *ierr.RestErr {
+	if !o.Enabled {
+		return nil
+	}
+	if actorType != entity.UserTypeMaster {
+		return ierr.NewForbiddenError("only masters can override labor rules")
+	}
+	if strings.TrimSpace(o.Reason) == "" {
+		return ierr.NewBadRequestValidationError("some fields are invalid", []ierr.Causes{
+			{Field: "overrideReason", Message: "required when override is true"},
+		})
+	}
+	return nil
+}
+
+// applyOverride records on the swap the blocking rules the override lets
+// through, if any, replacing those of an earlier approval.
+func applyOverride(swap *entity.Swap, violations []rules.Violation, override Override) {
+	swap.OverriddenRules, swap.OverrideReason = "", ""
+	blocking, _ := rules.Split(violations)
+	if !override.Enabled || len(blocking) == 0 {
+		return
+	}
+	var names []string
+	for _, v := range blocking {
+		if !containsString(names, string(v.Rule)) {
+			names = append(names, string(v.Rule))
+		}
+	}
+	swap.OverriddenRules = strings.Join(names, ",")
+	swap.OverrideReason = strings.TrimSpace(override.Reason)
+}
+
+// recordOverride adds a swap just approved with an override to the audit
+// trail. The swap is already stored, so a failure is logged rather than
+// returned.
+func (s *service) recordOverride(swap *entity.Swap, actorID uint, violations []rules.Violation, override Override) {
+	if !override.Enabled || swap.OverriddenRules == "" {
+		return
+	}
+	blocking, _ := rules.Split(violations)
+	details := make([]string, 0, len(blocking))
+	for _, cause := range rules.Causes(blocking) {
+		details = append(details, cause.Field+": "+cause.Message)
+	}
+	entry := entity.AuditEntry{
+		ActorID:    actorID,
+		Action:     entity.AuditActionRuleOverride,
+		EntityType: auditEntitySwap,
+		EntityID:   swap.ID,
+		Reason:     swap.OverrideReason,
+		Details:    strings.Join(details, "\n"),
+	}
+	if restErr := s.audit.Record(entry); restErr != nil {
+		log.Printf("failed to audit rule override of swap %d: %s", swap.ID, restErr.Message)
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package swap
 
 import (
+	"errors"
 	"escala-fds-api/internal/entity"
 	"time"
 
@@ -13,10 +14,24 @@ type Repository interface {
 	FindSwapsByUserID(userID uint, statusFilter string) ([]entity.Swap, error)
 	FindApprovedSwapsForDateRange(userID uint, startDate, endDate time.Time) ([]entity.Swap, error)
 	FindAllSwaps() ([]entity.Swap, error)
+	CountSwapsSince(userID uint, since time.Time) (int64, error)
+	CountSwapsBetween(userID uint, startDate, endDate time.Time, excludeID uint) (int64, error)
+	CountOverriddenSwaps(userID uint, startDate, endDate time.Time) (int64, error)
 	UpdateSwap(swap *entity.Swap) error
 	DeleteSwap(id uint) error
+	CreateOffer(offer *entity.ShiftOffer) error
+	FindOfferByID(id uint) (*entity.ShiftOffer, error)
+	FindOpenOffers(team entity.TeamName, from time.Time) ([]entity.ShiftOffer, error)
+	FindOpenOfferByOfferer(offererID uint, date time.Time) (*entity.ShiftOffer, error)
+	UpdateOffer(offer *entity.ShiftOffer) error
+	ClaimOffer(offer *entity.ShiftOffer, swap *entity.Swap) error
+	ReopenOffer(swapID uint) error
 }
 
+// ErrOfferTaken is returned by ClaimOffer when the offer stopped being open
+// before the claim was stored.
+var ErrOfferTaken = errors.New("shift offer is no longer open")
+
 type repository struct {
 	db *gorm.DB
 }
@@ -68,6 +83,39 @@ func (r *repository) FindAllSwaps() ([]entity.Swap, error) {
 	return swaps, err
 }
 
+// CountSwapsSince counts the pending and approved swaps the user took part in
+// that were requested since the given instant.
+func (r *repository) CountSwapsSince(userID uint, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&entity.Swap{}).
+		Where("requester_id = ? OR involved_collaborator_id = ?", userID, userID).
+		Where("status <> ? AND created_at >= ?", entity.StatusRejected, since).
+		Count(&count).Error
+	return count, err
+}
+
+// CountSwapsBetween counts the pending and approved swaps the user takes part
+// in whose original date falls between the dates, leaving out excludeID.
+func (r *repository) CountSwapsBetween(userID uint, startDate, endDate time.Time, excludeID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&entity.Swap{}).
+		Where("requester_id = ? OR involved_collaborator_id = ?", userID, userID).
+		Where("status <> ? AND original_date BETWEEN ? AND ? AND id <> ?", entity.StatusRejected, startDate, endDate, excludeID).
+		Count(&count).Error
+	return count, err
+}
+
+// CountOverriddenSwaps counts the approved swaps of userID on OriginalDate
+// between startDate and endDate that were approved despite blocking rules.
+func (r *repository) CountOverriddenSwaps(userID uint, startDate, endDate time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&entity.Swap{}).
+		Where("requester_id = ? OR involved_collaborator_id = ?", userID, userID).
+		Where("status = ? AND overridden_rules <> '' AND original_date BETWEEN ? AND ?", entity.StatusApproved, startDate, endDate).
+		Count(&count).Error
+	return count, err
+}
+
 func (r *repository) UpdateSwap(swap *entity.Swap) error {
 	return r.db.Save(swap).Error
 }
@@ -75,3 +123,78 @@ func (r *repository) UpdateSwap(swap *entity.Swap) error {
 func (r *repository) DeleteSwap(id uint) error {
 	return r.db.Delete(&entity.Swap{}, id).Error
 }
+
+func (r *repository) CreateOffer(offer *entity.ShiftOffer) error {
+	return r.db.Create(offer).Error
+}
+
+func (r *repository) FindOfferByID(id uint) (*entity.ShiftOffer, error) {
+	var offer entity.ShiftOffer
+	if err := r.db.First(&offer, id).Error; err != nil {
+		return nil, err
+	}
+	return &offer, nil
+}
+
+// FindOpenOffers returns the open offers from the given date on, of every
+// team when team is empty.
+func (r *repository) FindOpenOffers(team entity.TeamName, from time.Time) ([]entity.ShiftOffer, error) {
+	var offers []entity.ShiftOffer
+	query := r.db.Where("status = ? AND date >= ?", entity.ShiftOfferOpen, from)
+	if team != "" {
+		query = query.Where("team = ?", team)
+	}
+	err := query.Order("date asc, id asc").Find(&offers).Error
+	return offers, err
+}
+
+func (r *repository) FindOpenOfferByOfferer(offererID uint, date time.Time) (*entity.ShiftOffer, error) {
+	var offer entity.ShiftOffer
+	err := r.db.Where("offerer_id = ? AND date = ? AND status = ?", offererID, date, entity.ShiftOfferOpen).First(&offer).Error
+	if err != nil {
+		return nil, err
+	}
+	return &offer, nil
+}
+
+func (r *repository) UpdateOffer(offer *entity.ShiftOffer) error {
+	return r.db.Save(offer).Error
+}
+
+// ClaimOffer creates the handover swap and marks the offer claimed in one
+// transaction. The offer is only updated while still open, so of two
+// concurrent claims the second gets ErrOfferTaken.
+func (r *repository) ClaimOffer(offer *entity.ShiftOffer, swap *entity.Swap) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(swap).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&entity.ShiftOffer{}).
+			Where("id = ? AND status = ?", offer.ID, entity.ShiftOfferOpen).
+			Updates(map[string]interface{}{
+				"status":        entity.ShiftOfferClaimed,
+				"claimed_by_id": swap.InvolvedCollaboratorID,
+				"swap_id":       swap.ID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOfferTaken
+		}
+		return nil
+	})
+}
+
+// ReopenOffer puts back on the marketplace the offer claimed by a swap that
+// was rejected or withdrawn. Swaps that did not come from an offer are
+// ignored.
+func (r *repository) ReopenOffer(swapID uint) error {
+	return r.db.Model(&entity.ShiftOffer{}).
+		Where("swap_id = ? AND status = ?", swapID, entity.ShiftOfferClaimed).
+		Updates(map[string]interface{}{
+			"status":        entity.ShiftOfferOpen,
+			"claimed_by_id": nil,
+			"swap_id":       nil,
+		}).Error
+}
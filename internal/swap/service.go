@@ -1,9 +1,11 @@
 package swap
 
 import (
+	"escala-fds-api/internal/audit"
 	"escala-fds-api/internal/constants"
 	"escala-fds-api/internal/entity"
-	"escala-fds-api/internal/holiday"
+	"escala-fds-api/internal/rules"
+	"escala-fds-api/internal/schedule"
 	"escala-fds-api/internal/user"
 	"escala-fds-api/pkg/ierr"
 	"fmt"
@@ -13,29 +15,41 @@ import (
 )
 
 type Service interface {
-	CreateSwap(swap entity.Swap, requesterID uint, requesterType entity.UserType) (*SwapResponse, *ierr.RestErr)
-	ApproveOrRejectSwap(swapID, approverID uint, newStatus entity.SwapStatus) (*SwapResponse, *ierr.RestErr)
+	CreateSwap(swap entity.Swap, requesterID uint, requesterType entity.UserType, override Override) (*SwapResponse, *ierr.RestErr)
+	ApproveOrRejectSwap(swapID, approverID uint, newStatus entity.SwapStatus, override Override) (*SwapResponse, *ierr.RestErr)
 	FindSwapByID(id uint) (*SwapResponse, *ierr.RestErr)
 	FindSwapsForUser(userID uint, statusFilter string) ([]SwapResponse, *ierr.RestErr)
 	FindAllSwaps() ([]SwapResponse, *ierr.RestErr)
 	DeleteSwap(id, requesterID uint, requesterType entity.UserType) *ierr.RestErr
+	SuggestSwaps(requesterID uint, date time.Time, shift entity.ShiftName) ([]SwapSuggestionResponse, *ierr.RestErr)
+	CreateOffer(offer entity.ShiftOffer, offererID uint) (*ShiftOfferResponse, *ierr.RestErr)
+	FindEligibleOffers(requestorID uint, requestorType entity.UserType) ([]ShiftOfferResponse, *ierr.RestErr)
+	ClaimOffer(offerID, claimerID uint) (*SwapResponse, *ierr.RestErr)
+	CancelOffer(offerID, requesterID uint, requesterType entity.UserType) *ierr.RestErr
 }
 
 type service struct {
-	swapRepo    Repository
-	userRepo    user.Repository
-	holidayRepo holiday.Repository
+	swapRepo Repository
+	userRepo user.Repository
+	schedule schedule.Service
+	rules    rules.Service
+	audit    audit.Service
 }
 
-func NewService(swapRepo Repository, userRepo user.Repository, holidayRepo holiday.Repository) Service {
+func NewService(swapRepo Repository, userRepo user.Repository, scheduleService schedule.Service, rulesService rules.Service, auditService audit.Service) Service {
 	return &service{
-		swapRepo:    swapRepo,
-		userRepo:    userRepo,
-		holidayRepo: holidayRepo,
+		swapRepo: swapRepo,
+		userRepo: userRepo,
+		schedule: scheduleService,
+		rules:    rulesService,
+		audit:    auditService,
 	}
 }
 
-func (s *service) CreateSwap(swap entity.Swap, requesterID uint, requesterType entity.UserType) (*SwapResponse, *ierr.RestErr) {
+func (s *service) CreateSwap(swap entity.Swap, requesterID uint, requesterType entity.UserType, override Override) (*SwapResponse, *ierr.RestErr) {
+	if err := override.check(requesterType); err != nil {
+		return nil, err
+	}
 	swap.RequesterID = requesterID
 
 	if requesterType == entity.UserTypeMaster {
@@ -47,43 +61,70 @@ func (s *service) CreateSwap(swap entity.Swap, requesterID uint, requesterType e
 		swap.Status = entity.StatusPending
 	}
 
-	if err := s.validateSwap(&swap); err != nil {
+	warnings, err := s.validateSwap(&swap, override)
+	if err != nil {
 		return nil, err
 	}
 	if err := s.swapRepo.CreateSwap(&swap); err != nil {
 		return nil, ierr.NewInternalServerError("error creating swap request")
 	}
+	s.recordOverride(&swap, requesterID, warnings, override)
 
-	return s.buildSingleResponse(swap.ID)
+	return s.buildResponseWithWarnings(swap.ID, warnings)
 }
 
-func (s *service) validateSwap(swap *entity.Swap) *ierr.RestErr {
+// validateSwap checks the swap and returns the labor rule violations to report
+// as warnings. Blocking violations reject it unless overridden; the override
+// is then recorded on the swap.
+func (s *service) validateSwap(swap *entity.Swap, override Override) ([]rules.Violation, *ierr.RestErr) {
 	requester, err := s.userRepo.FindUserByID(swap.RequesterID)
 	if err != nil {
-		return ierr.NewBadRequestError("requester not found")
+		return nil, ierr.NewBadRequestError("requester not found")
 	}
 
 	// Validações gerais que se aplicam a todos os cenários
+	var involved *entity.User
 	if swap.InvolvedCollaboratorID != nil {
-		involved, err := s.userRepo.FindUserByID(*swap.InvolvedCollaboratorID)
+		involved, err = s.userRepo.FindUserByID(*swap.InvolvedCollaboratorID)
 		if err != nil {
-			return ierr.NewBadRequestError("involved collaborator not found")
+			return nil, ierr.NewBadRequestError("involved collaborator not found")
 		}
 		if requester.Team != involved.Team {
-			return ierr.NewBadRequestError("swaps can only occur between members of the same team")
+			return nil, ierr.NewBadRequestError("swaps can only occur between members of the same team")
 		}
 	}
 
-	// A regra principal e única é a verificação do intervalo de descanso.
-	// Esta função já verifica o dia anterior e o dia posterior ao novo turno.
-	if err := s.checkRestInterval(requester, swap); err != nil {
-		return err
+	var causes []ierr.Causes
+	if _, _, err := s.schedule.ShiftWindow(swap.OriginalShift, swap.OriginalDate); err != nil {
+		causes = append(causes, ierr.Causes{Field: "originalShift", Message: "shift template not found"})
+	}
+	if _, _, err := s.schedule.ShiftWindow(swap.NewShift, swap.NewDate); err != nil {
+		causes = append(causes, ierr.Causes{Field: "newShift", Message: "shift template not found"})
+	}
+	if len(causes) > 0 {
+		return nil, ierr.NewBadRequestValidationError("invalid swap shifts", causes)
+	}
+
+	if swap.IsHandover() && involved == nil {
+		return nil, ierr.NewBadRequestError("a handover needs an involved collaborator")
 	}
 
-	return nil
+	// As regras trabalhistas do time valem para os dois lados da troca: o
+	// solicitante e o colaborador envolvido, com a escala de cada um depois
+	// da troca.
+	violations, restErr := s.ruleViolations(swap, requester, involved)
+	if restErr != nil {
+		return nil, restErr
+	}
+	warnings, restErr := rules.Enforce(violations, override.Enabled)
+	if restErr != nil {
+		return nil, restErr
+	}
+	applyOverride(swap, violations, override)
+	return warnings, nil
 }
 
-func (s *service) ApproveOrRejectSwap(swapID, approverID uint, newStatus entity.SwapStatus) (*SwapResponse, *ierr.RestErr) {
+func (s *service) ApproveOrRejectSwap(swapID, approverID uint, newStatus entity.SwapStatus, override Override) (*SwapResponse, *ierr.RestErr) {
 	swap, err := s.swapRepo.FindSwapByID(swapID)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -102,6 +143,19 @@ func (s *service) ApproveOrRejectSwap(swapID, approverID uint, newStatus entity.
 	if approver.UserType != entity.UserTypeMaster && (requester.SuperiorID == nil || *requester.SuperiorID != approverID) {
 		return nil, ierr.NewForbiddenError("you do not have permission to approve this request")
 	}
+	if err := override.check(approver.UserType); err != nil {
+		return nil, err
+	}
+	// The schedules may have changed since the request, so the rules are
+	// checked again before approving.
+	var warnings []rules.Violation
+	approving := newStatus == entity.StatusApproved && swap.Status != entity.StatusApproved
+	if approving {
+		var restErr *ierr.RestErr
+		if warnings, restErr = s.validateSwap(swap, override); restErr != nil {
+			return nil, restErr
+		}
+	}
 	swap.Status = newStatus
 	now := time.Now().UTC()
 	if newStatus == entity.StatusApproved {
@@ -114,7 +168,15 @@ func (s *service) ApproveOrRejectSwap(swapID, approverID uint, newStatus entity.
 	if err := s.swapRepo.UpdateSwap(swap); err != nil {
 		return nil, ierr.NewInternalServerError(fmt.Sprintf("error updating swap status: %v", err))
 	}
-	return s.buildSingleResponse(swapID)
+	if approving {
+		s.recordOverride(swap, approverID, warnings, override)
+	}
+	if swap.IsHandover() && newStatus == entity.StatusRejected {
+		if err := s.swapRepo.ReopenOffer(swap.ID); err != nil {
+			return nil, ierr.NewInternalServerError("error reopening shift offer")
+		}
+	}
+	return s.buildResponseWithWarnings(swapID, warnings)
 }
 
 func (s *service) FindSwapByID(id uint) (*SwapResponse, *ierr.RestErr) {
@@ -154,6 +216,11 @@ func (s *service) DeleteSwap(id, requesterID uint, requesterType entity.UserType
 	if err := s.swapRepo.DeleteSwap(id); err != nil {
 		return ierr.NewInternalServerError("error deleting swap request")
 	}
+	if swap.IsHandover() {
+		if err := s.swapRepo.ReopenOffer(swap.ID); err != nil {
+			return ierr.NewInternalServerError("error reopening shift offer")
+		}
+	}
 	return nil
 }
 
@@ -175,6 +242,16 @@ func (s *service) buildSingleResponse(id uint) (*SwapResponse, *ierr.RestErr) {
 	return &list[0], nil
 }
 
+// buildResponseWithWarnings adds the rule warnings of the change just made.
+func (s *service) buildResponseWithWarnings(id uint, warnings []rules.Violation) (*SwapResponse, *ierr.RestErr) {
+	response, restErr := s.buildSingleResponse(id)
+	if restErr != nil {
+		return nil, restErr
+	}
+	response.Warnings = warnings
+	return response, nil
+}
+
 func (s *service) buildResponseList(swaps []entity.Swap) ([]SwapResponse, *ierr.RestErr) {
 	var responses []SwapResponse
 	for _, swap := range swaps {
@@ -225,130 +302,8 @@ func (s *service) toResponse(swap *entity.Swap, requester, involved, approvedBy
 		ApprovedBy:           approvedByResponse,
 		CreatedAt:            swap.CreatedAt.Format(constants.ApiTimestampLayout),
 		ApprovedAt:           approvedAt,
+		ShiftOfferID:         swap.ShiftOfferID,
+		OverriddenRules:      swap.Overrides(),
+		OverrideReason:       swap.OverrideReason,
 	}
 }
-
-var shiftTimings = map[entity.ShiftName]struct {
-	start time.Duration
-	end   time.Duration
-}{
-	entity.ShiftMorning:   {start: 6 * time.Hour, end: 14 * time.Hour},
-	entity.ShiftAfternoon: {start: 14 * time.Hour, end: 22 * time.Hour},
-	entity.ShiftNight:     {start: 22 * time.Hour, end: 30 * time.Hour},
-}
-
-func (s *service) checkRestInterval(user *entity.User, swap *entity.Swap) *ierr.RestErr {
-	dayBefore := swap.NewDate.AddDate(0, 0, -1)
-	dayAfter := swap.NewDate.AddDate(0, 0, 1)
-	shiftBefore, isWorkDayBefore, err := s.getShiftForDay(user, dayBefore)
-	if err != nil {
-		return ierr.NewInternalServerError("could not determine schedule for previous day")
-	}
-	if isWorkDayBefore {
-		endOfShiftBefore := dayBefore.Add(shiftTimings[shiftBefore].end)
-		startOfNewShift := swap.NewDate.Add(shiftTimings[swap.NewShift].start)
-		if startOfNewShift.Sub(endOfShiftBefore) < 11*time.Hour {
-			return ierr.NewBadRequestError("the proposed swap violates the minimum 11-hour rest interval with the previous day's shift")
-		}
-	}
-	shiftAfter, isWorkDayAfter, err := s.getShiftForDay(user, dayAfter)
-	if err != nil {
-		return ierr.NewInternalServerError("could not determine schedule for next day")
-	}
-	if isWorkDayAfter {
-		endOfNewShift := swap.NewDate.Add(shiftTimings[swap.NewShift].end)
-		startOfShiftAfter := dayAfter.Add(shiftTimings[shiftAfter].start)
-		if startOfShiftAfter.Sub(endOfNewShift) < 11*time.Hour {
-			return ierr.NewBadRequestError("the proposed swap violates the minimum 11-hour rest interval with the next day's shift")
-		}
-	}
-	return nil
-}
-
-func (s *service) getShiftForDay(u *entity.User, date time.Time) (entity.ShiftName, bool, error) {
-	swaps, err := s.swapRepo.FindApprovedSwapsForDateRange(u.ID, date, date)
-	if err != nil && err != gorm.ErrRecordNotFound {
-		return "", false, err
-	}
-	if len(swaps) > 0 {
-		for _, swap := range swaps {
-			isSameNewDate := swap.NewDate.Year() == date.Year() && swap.NewDate.YearDay() == date.YearDay()
-			isSameOriginalDate := swap.OriginalDate.Year() == date.Year() && swap.OriginalDate.YearDay() == date.YearDay()
-			isRequester := swap.RequesterID == u.ID
-			isInvolved := swap.InvolvedCollaboratorID != nil && *swap.InvolvedCollaboratorID == u.ID
-			if isSameNewDate && isRequester {
-				return "", false, nil
-			}
-			if isSameOriginalDate && isRequester {
-				return swap.NewShift, true, nil
-			}
-			if isSameNewDate && isInvolved {
-				return swap.NewShift, true, nil
-			}
-			if isSameOriginalDate && isInvolved {
-				return "", false, nil
-			}
-		}
-	}
-	isHoliday, err := s.holidayRepo.IsHoliday(date)
-	if err != nil {
-		return "", false, err
-	}
-	if isHoliday {
-		return "", false, nil
-	}
-	if isRegularDayOff(date, u) {
-		return "", false, nil
-	}
-	return u.Shift, true, nil
-}
-
-func isWeekend(date time.Time) bool {
-	wd := date.Weekday()
-	return wd == time.Saturday || wd == time.Sunday
-}
-
-func isRegularDayOff(date time.Time, user *entity.User) bool {
-	weekdayMap := map[time.Weekday]entity.WeekdayName{
-		time.Monday:    entity.WeekdayMonday,
-		time.Tuesday:   entity.WeekdayTuesday,
-		time.Wednesday: entity.WeekdayWednesday,
-		time.Thursday:  entity.WeekdayThursday,
-		time.Friday:    entity.WeekdayFriday,
-	}
-	if user.WeekdayOff == weekdayMap[date.Weekday()] {
-		return true
-	}
-	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
-		if user.InitialWeekendOff == "" {
-			return false
-		}
-		firstWeekendOffDay := time.Sunday
-		if user.InitialWeekendOff == entity.WeekendSaturday {
-			firstWeekendOffDay = time.Saturday
-		}
-		firstOccurrence := user.CreatedAt
-		for firstOccurrence.Weekday() != firstWeekendOffDay {
-			firstOccurrence = firstOccurrence.AddDate(0, 0, 1)
-		}
-		firstOccurrence = time.Date(firstOccurrence.Year(), firstOccurrence.Month(), firstOccurrence.Day(), 0, 0, 0, 0, time.UTC)
-		currentDayOnly := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
-		if currentDayOnly.Before(firstOccurrence) {
-			return false
-		}
-		daysDiff := currentDayOnly.Sub(firstOccurrence).Hours() / 24
-		weekDiff := int(daysDiff / 7)
-		currentWeekendOffDay := firstWeekendOffDay
-		if weekDiff%2 != 0 {
-			if firstWeekendOffDay == time.Saturday {
-				currentWeekendOffDay = time.Sunday
-			} else {
-				currentWeekendOffDay = time.Saturday
-			}
-		}
-		if date.Weekday() == currentWeekendOffDay {
-			return true
-		}
-	}
-	return false
-}
@@ -0,0 +1,152 @@
+package swap
+
+import (
+	"escala-fds-api/internal/constants"
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/rules"
+	"escala-fds-api/internal/schedule"
+	"escala-fds-api/internal/user"
+	"escala-fds-api/pkg/ierr"
+	"sort"
+	"time"
+)
+
+// SuggestionWindowDays is how far from the day off the reciprocal shift of a
+// suggested swap may be.
+const SuggestionWindowDays = 7
+
+// RecentSwapsWindow is the period whose swaps rank the suggestions.
+const RecentSwapsWindow = 90 * 24 * time.Hour
+
+// SuggestSwaps finds same-team colleagues who can take the requester's shift
+// on date, each with the closest day in SuggestionWindowDays on which they
+// work and the requester can work in return. The swap must break no blocking
+// labor rule of the team on either side. Colleagues with fewer recent swaps
+// come first. An empty shift means the shift the requester works on date.
+func (s *service) SuggestSwaps(requesterID uint, date time.Time, shift entity.ShiftName) ([]SwapSuggestionResponse, *ierr.RestErr) {
+	requester, err := s.userRepo.FindUserByID(requesterID)
+	if err != nil {
+		return nil, ierr.NewBadRequestError("requester not found")
+	}
+	if requester.UserType != entity.UserTypeCollaborator {
+		return nil, ierr.NewForbiddenError("only collaborators can look for swap partners")
+	}
+	date = schedule.DateOnly(date)
+	start := date.AddDate(0, 0, -SuggestionWindowDays)
+	end := date.AddDate(0, 0, SuggestionWindowDays)
+
+	// Everything the rules need is loaded once: the team's config, the
+	// schedule of the requester and, below, that of each colleague.
+	config, restErr := s.rules.Config(requester.Team)
+	if restErr != nil {
+		return nil, restErr
+	}
+	windows := newShiftWindows(s.schedule)
+	mine, restErr := s.loadSide(requester, "requester", start, end, 0)
+	if restErr != nil {
+		return nil, restErr
+	}
+	day := dayOn(mine.days, date)
+	if day == nil || !day.Working {
+		return nil, ierr.NewBadRequestValidationError("invalid swap suggestion request", []ierr.Causes{
+			{Field: "date", Message: "you are not scheduled to work on this date"},
+		})
+	}
+	if shift == "" {
+		shift = day.Shift
+	} else if shift != day.Shift {
+		return nil, ierr.NewBadRequestValidationError("invalid swap suggestion request", []ierr.Causes{
+			{Field: "shift", Message: "you are not scheduled to work this shift on this date"},
+		})
+	}
+
+	colleagues, err := s.userRepo.FindUsersByTeam(requester.Team)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding team members")
+	}
+	since := time.Now().UTC().Add(-RecentSwapsWindow)
+	suggestions := make([]SwapSuggestionResponse, 0)
+	for i := range colleagues {
+		colleague := &colleagues[i]
+		if colleague.ID == requester.ID || colleague.UserType != entity.UserTypeCollaborator {
+			continue
+		}
+		theirs, restErr := s.loadSide(colleague, "involvedCollaborator", start, end, 0)
+		if restErr != nil {
+			return nil, restErr
+		}
+		suggestion, restErr := s.suggestWith(mine, theirs, date, shift, config, windows)
+		if restErr != nil {
+			return nil, restErr
+		}
+		if suggestion == nil {
+			continue
+		}
+		if suggestion.RecentSwaps, err = s.swapRepo.CountSwapsSince(colleague.ID, since); err != nil {
+			return nil, ierr.NewInternalServerError("error counting recent swaps")
+		}
+		suggestions = append(suggestions, *suggestion)
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].RecentSwaps < suggestions[j].RecentSwaps
+	})
+	return suggestions, nil
+}
+
+// suggestWith returns the swap with the colleague of theirs, or nil when
+// there is none.
+func (s *service) suggestWith(mine, theirs *sideSchedule, date time.Time, shift entity.ShiftName, config rules.Config, windows *shiftWindows) (*SwapSuggestionResponse, *ierr.RestErr) {
+	if day := dayOn(theirs.days, date); day == nil || day.Working {
+		return nil, nil
+	}
+
+	for _, candidate := range reciprocalCandidates(date) {
+		theirDay := dayOn(theirs.days, candidate)
+		myDay := dayOn(mine.days, candidate)
+		if theirDay == nil || myDay == nil || !theirDay.Working || myDay.Working {
+			continue
+		}
+		// The requester gives up NewDate and works NewShift on OriginalDate,
+		// where the colleague is off in return.
+		swap := entity.Swap{
+			RequesterID:            mine.user.ID,
+			InvolvedCollaboratorID: &theirs.user.ID,
+			OriginalDate:           candidate,
+			NewDate:                date,
+			OriginalShift:          theirDay.Shift,
+			NewShift:               shift,
+		}
+		violations, restErr := s.evaluateSwap(&swap, config, windows, mine, theirs)
+		if restErr != nil {
+			return nil, restErr
+		}
+		if blocking, _ := rules.Split(violations); len(blocking) == 0 {
+			return &SwapSuggestionResponse{
+				Collaborator:  user.ToUserResponse(theirs.user),
+				OriginalDate:  candidate.Format(constants.ApiDateLayout),
+				OriginalShift: theirDay.Shift,
+				NewDate:       date.Format(constants.ApiDateLayout),
+				NewShift:      shift,
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// reciprocalCandidates lists the days around date, closest first.
+func reciprocalCandidates(date time.Time) []time.Time {
+	candidates := make([]time.Time, 0, 2*SuggestionWindowDays)
+	for offset := 1; offset <= SuggestionWindowDays; offset++ {
+		candidates = append(candidates, date.AddDate(0, 0, offset), date.AddDate(0, 0, -offset))
+	}
+	return candidates
+}
+
+func dayOn(days []schedule.Day, date time.Time) *schedule.Day {
+	for i := range days {
+		if schedule.SameDay(days[i].Date, date) {
+			return &days[i]
+		}
+	}
+	return nil
+}
@@ -3,39 +3,85 @@ package user
 import (
 	"escala-fds-api/internal/constants"
 	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/validation"
+	"escala-fds-api/pkg/ierr"
+	"time"
 )
 
 type CreateUserRequest struct {
-	Email             string                `json:"email" binding:"required,email"`
-	Password          string                `json:"password" binding:"required,min=6"`
-	FirstName         string                `json:"firstName" binding:"required"`
-	LastName          string                `json:"lastName" binding:"required"`
-	PhoneNumber       string                `json:"phoneNumber" binding:"required"`
-	Birthday          string                `json:"birthday,omitempty"`
-	UserType          entity.UserType       `json:"userType" binding:"required"`
-	Team              entity.TeamName       `json:"team"`
-	Position          entity.PositionName   `json:"position"`
-	Shift             entity.ShiftName      `json:"shift"`
-	WeekdayOff        entity.WeekdayName    `json:"weekdayOff"`
-	InitialWeekendOff entity.WeekendDayName `json:"initialWeekendOff"`
-	SuperiorID        *uint                 `json:"superiorId"`
+	Email                 string                `json:"email" binding:"required,email"`
+	Password              string                `json:"password" binding:"required,min=6"`
+	FirstName             string                `json:"firstName" binding:"required"`
+	LastName              string                `json:"lastName" binding:"required"`
+	PhoneNumber           string                `json:"phoneNumber" binding:"required"`
+	Birthday              string                `json:"birthday,omitempty" binding:"omitempty,apidate"`
+	UserType              entity.UserType       `json:"userType" binding:"required,oneof=master collaborator"`
+	Team                  entity.TeamName       `json:"team" binding:"omitempty,team"`
+	Position              entity.PositionName   `json:"position"`
+	Shift                 entity.ShiftName      `json:"shift" binding:"omitempty,shift"`
+	WeekdayOff            entity.WeekdayName    `json:"weekdayOff" binding:"omitempty,weekday"`
+	InitialWeekendOff     entity.WeekendDayName `json:"initialWeekendOff" binding:"omitempty,weekend_day"`
+	SuperiorID            *uint                 `json:"superiorId"`
+	RotationPatternID     *uint                 `json:"rotationPatternId"`
+	RotationAnchor        string                `json:"rotationAnchor,omitempty" binding:"omitempty,apidate"`
+	WeekendRotationAnchor string                `json:"weekendRotationAnchor,omitempty" binding:"omitempty,apidate"`
+	SiteID                *uint                 `json:"siteId"`
+}
+
+func (req *CreateUserRequest) toEntity() (entity.User, *ierr.RestErr) {
+	birthday, errDate := validation.ParseOptionalDate("birthday", req.Birthday)
+	if errDate != nil {
+		return entity.User{}, errDate
+	}
+	rotationAnchor, errDate := validation.ParseOptionalDate("rotationAnchor", req.RotationAnchor)
+	if errDate != nil {
+		return entity.User{}, errDate
+	}
+	weekendAnchor, errDate := validation.ParseOptionalDate("weekendRotationAnchor", req.WeekendRotationAnchor)
+	if errDate != nil {
+		return entity.User{}, errDate
+	}
+	return entity.User{
+		Email:                 req.Email,
+		Password:              req.Password,
+		FirstName:             req.FirstName,
+		LastName:              req.LastName,
+		PhoneNumber:           req.PhoneNumber,
+		Birthday:              birthday,
+		UserType:              req.UserType,
+		Team:                  req.Team,
+		Position:              req.Position,
+		Shift:                 req.Shift,
+		WeekdayOff:            req.WeekdayOff,
+		InitialWeekendOff:     req.InitialWeekendOff,
+		SuperiorID:            req.SuperiorID,
+		RotationPatternID:     req.RotationPatternID,
+		RotationAnchor:        rotationAnchor,
+		WeekendRotationAnchor: weekendAnchor,
+		SiteID:                req.SiteID,
+	}, nil
 }
 
 type UpdatePersonalDataRequest struct {
 	FirstName   string `json:"firstName"`
 	LastName    string `json:"lastName"`
 	PhoneNumber string `json:"phoneNumber"`
-	Birthday    string `json:"birthday,omitempty"`
+	Birthday    string `json:"birthday,omitempty" binding:"omitempty,apidate"`
 	Password    string `json:"password" binding:"omitempty,min=6"`
 }
 
 type UpdateWorkDataRequest struct {
-	Team              entity.TeamName       `json:"team" binding:"required"`
-	Position          entity.PositionName   `json:"position" binding:"required"`
-	Shift             entity.ShiftName      `json:"shift" binding:"required"`
-	WeekdayOff        entity.WeekdayName    `json:"weekdayOff" binding:"required"`
-	InitialWeekendOff entity.WeekendDayName `json:"initialWeekendOff" binding:"required"`
-	SuperiorID        *uint                 `json:"superiorId"`
+	Team                  entity.TeamName       `json:"team" binding:"required,team"`
+	Position              entity.PositionName   `json:"position" binding:"required"`
+	Shift                 entity.ShiftName      `json:"shift" binding:"required,shift"`
+	WeekdayOff            entity.WeekdayName    `json:"weekdayOff" binding:"required,weekday"`
+	InitialWeekendOff     entity.WeekendDayName `json:"initialWeekendOff" binding:"required,weekend_day"`
+	SuperiorID            *uint                 `json:"superiorId"`
+	RotationPatternID     *uint                 `json:"rotationPatternId"`
+	RotationAnchor        string                `json:"rotationAnchor,omitempty" binding:"omitempty,apidate"`
+	WeekendRotationAnchor string                `json:"weekendRotationAnchor,omitempty" binding:"omitempty,apidate"`
+	EffectiveFrom         string                `json:"effectiveFrom,omitempty" binding:"omitempty,apidate"`
+	SiteID                *uint                 `json:"siteId"`
 }
 
 type LoginRequest struct {
@@ -44,21 +90,72 @@ type LoginRequest struct {
 }
 
 type UserResponse struct {
-	ID                uint                  `json:"id"`
-	Email             string                `json:"email"`
-	FirstName         string                `json:"firstName"`
-	LastName          string                `json:"lastName"`
-	PhoneNumber       string                `json:"phoneNumber"`
-	Birthday          string                `json:"birthday,omitempty"`
-	UserType          entity.UserType       `json:"userType"`
-	Team              entity.TeamName       `json:"team,omitempty"`
-	Position          entity.PositionName   `json:"position,omitempty"`
-	Shift             entity.ShiftName      `json:"shift,omitempty"`
-	WeekdayOff        entity.WeekdayName    `json:"weekdayOff,omitempty"`
-	InitialWeekendOff entity.WeekendDayName `json:"initialWeekendOff,omitempty"`
-	SuperiorID        *uint                 `json:"superiorId,omitempty"`
-	CreatedAt         string                `json:"createdAt"`
-	UpdatedAt         string                `json:"updatedAt"`
+	ID                    uint                  `json:"id"`
+	Email                 string                `json:"email"`
+	FirstName             string                `json:"firstName"`
+	LastName              string                `json:"lastName"`
+	PhoneNumber           string                `json:"phoneNumber"`
+	Birthday              string                `json:"birthday,omitempty"`
+	UserType              entity.UserType       `json:"userType"`
+	Team                  entity.TeamName       `json:"team,omitempty"`
+	Position              entity.PositionName   `json:"position,omitempty"`
+	Shift                 entity.ShiftName      `json:"shift,omitempty"`
+	WeekdayOff            entity.WeekdayName    `json:"weekdayOff,omitempty"`
+	InitialWeekendOff     entity.WeekendDayName `json:"initialWeekendOff,omitempty"`
+	SuperiorID            *uint                 `json:"superiorId,omitempty"`
+	RotationPatternID     *uint                 `json:"rotationPatternId,omitempty"`
+	RotationAnchor        string                `json:"rotationAnchor,omitempty"`
+	WeekendRotationAnchor string                `json:"weekendRotationAnchor,omitempty"`
+	SiteID                *uint                 `json:"siteId,omitempty"`
+	CreatedAt             string                `json:"createdAt"`
+	UpdatedAt             string                `json:"updatedAt"`
+}
+
+type WeekendRotationRequest struct {
+	Anchor    string `json:"anchor" binding:"required,apidate"`
+	Alternate bool   `json:"alternate"`
+}
+
+type WeekendOffResponse struct {
+	Date    string                `json:"date"`
+	Weekday entity.WeekendDayName `json:"weekday"`
+}
+
+type WorkAssignmentResponse struct {
+	ID                    uint                  `json:"id"`
+	Team                  entity.TeamName       `json:"team,omitempty"`
+	Position              entity.PositionName   `json:"position,omitempty"`
+	Shift                 entity.ShiftName      `json:"shift,omitempty"`
+	WeekdayOff            entity.WeekdayName    `json:"weekdayOff,omitempty"`
+	InitialWeekendOff     entity.WeekendDayName `json:"initialWeekendOff,omitempty"`
+	RotationPatternID     *uint                 `json:"rotationPatternId,omitempty"`
+	RotationAnchor        string                `json:"rotationAnchor,omitempty"`
+	WeekendRotationAnchor string                `json:"weekendRotationAnchor,omitempty"`
+	SuperiorID            *uint                 `json:"superiorId,omitempty"`
+	ValidFrom             string                `json:"validFrom"`
+	ValidTo               string                `json:"validTo,omitempty"`
+	CreatedByID           *uint                 `json:"createdById,omitempty"`
+	CreatedAt             string                `json:"createdAt"`
+}
+
+// ImportRow is a CSV row of a user import; Line is its line number in the
+// file, header included, and is used in the field of every error cause.
+type ImportRow struct {
+	Line int
+	User entity.User
+}
+
+type ImportedUserResponse struct {
+	Line int          `json:"line"`
+	User UserResponse `json:"user"`
+	// SuperiorLine is set when the superior is created by the same import.
+	SuperiorLine      int    `json:"superiorLine,omitempty"`
+	TemporaryPassword string `json:"temporaryPassword,omitempty"`
+}
+
+type ImportResponse struct {
+	DryRun bool                   `json:"dryRun"`
+	Users  []ImportedUserResponse `json:"users"`
 }
 
 type LoginResponse struct {
@@ -71,22 +168,63 @@ func ToUserResponse(user *entity.User) UserResponse {
 	if user.Birthday != nil {
 		birthday = user.Birthday.Format(constants.ApiDateLayout)
 	}
+	var rotationAnchor string
+	if user.RotationAnchor != nil {
+		rotationAnchor = user.RotationAnchor.Format(constants.ApiDateLayout)
+	}
 
 	return UserResponse{
-		ID:                user.ID,
-		Email:             user.Email,
-		FirstName:         user.FirstName,
-		LastName:          user.LastName,
-		PhoneNumber:       user.PhoneNumber,
-		Birthday:          birthday,
-		UserType:          user.UserType,
-		Team:              user.Team,
-		Position:          user.Position,
-		Shift:             user.Shift,
-		WeekdayOff:        user.WeekdayOff,
-		InitialWeekendOff: user.InitialWeekendOff,
-		SuperiorID:        user.SuperiorID,
-		CreatedAt:         user.CreatedAt.Format(constants.ApiTimestampLayout),
-		UpdatedAt:         user.UpdatedAt.Format(constants.ApiTimestampLayout),
+		ID:                    user.ID,
+		Email:                 user.Email,
+		FirstName:             user.FirstName,
+		LastName:              user.LastName,
+		PhoneNumber:           user.PhoneNumber,
+		Birthday:              birthday,
+		UserType:              user.UserType,
+		Team:                  user.Team,
+		Position:              user.Position,
+		Shift:                 user.Shift,
+		WeekdayOff:            user.WeekdayOff,
+		InitialWeekendOff:     user.InitialWeekendOff,
+		SuperiorID:            user.SuperiorID,
+		RotationPatternID:     user.RotationPatternID,
+		RotationAnchor:        rotationAnchor,
+		WeekendRotationAnchor: formatOptionalDate(user.WeekendRotationAnchor),
+		SiteID:                user.SiteID,
+		CreatedAt:             user.CreatedAt.Format(constants.ApiTimestampLayout),
+		UpdatedAt:             user.UpdatedAt.Format(constants.ApiTimestampLayout),
+	}
+}
+
+func ToWorkAssignmentResponse(assignment *entity.WorkAssignment) WorkAssignmentResponse {
+	var rotationAnchor, validTo string
+	if assignment.RotationAnchor != nil {
+		rotationAnchor = assignment.RotationAnchor.Format(constants.ApiDateLayout)
+	}
+	if assignment.ValidTo != nil {
+		validTo = assignment.ValidTo.Format(constants.ApiDateLayout)
+	}
+	return WorkAssignmentResponse{
+		ID:                    assignment.ID,
+		Team:                  assignment.Team,
+		Position:              assignment.Position,
+		Shift:                 assignment.Shift,
+		WeekdayOff:            assignment.WeekdayOff,
+		InitialWeekendOff:     assignment.InitialWeekendOff,
+		RotationPatternID:     assignment.RotationPatternID,
+		RotationAnchor:        rotationAnchor,
+		WeekendRotationAnchor: formatOptionalDate(assignment.WeekendRotationAnchor),
+		SuperiorID:            assignment.SuperiorID,
+		ValidFrom:             assignment.ValidFrom.Format(constants.ApiDateLayout),
+		ValidTo:               validTo,
+		CreatedByID:           assignment.CreatedByID,
+		CreatedAt:             assignment.CreatedAt.Format(constants.ApiTimestampLayout),
+	}
+}
+
+func formatOptionalDate(date *time.Time) string {
+	if date == nil {
+		return ""
 	}
+	return date.Format(constants.ApiDateLayout)
 }
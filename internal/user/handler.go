@@ -3,10 +3,12 @@ package user
 import (
 	"escala-fds-api/internal/auth"
 	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/validation"
 	"escala-fds-api/pkg/ierr"
+	"io"
 	"net/http"
 	"strconv"
-	"time"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -22,15 +24,23 @@ func NewHandler(service Service) *Handler {
 func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 	router.POST("/login", h.Login)
 	userRoutes := router.Group("/users")
-	userRoutes.Use(auth.Middleware())
+	userRoutes.Use(auth.Middleware(), auth.RequireScope("users"))
 	{
 		userRoutes.POST("", h.CreateUser)
+		userRoutes.POST("/import", h.ImportUsers)
 		userRoutes.GET("", h.FindAll)
 		userRoutes.GET("/:id", h.FindByID)
 		userRoutes.PUT("/:id/personal", h.UpdatePersonalData)
 		userRoutes.PUT("/:id/work", h.UpdateWorkData)
+		userRoutes.GET("/:id/work-history", h.FindWorkHistory)
+		userRoutes.GET("/:id/weekends-off", h.FindWeekendsOff)
 		userRoutes.DELETE("/:id", h.Delete)
 	}
+	teamRoutes := router.Group("/teams/:team")
+	teamRoutes.Use(auth.Middleware(), auth.RequireScope("users"))
+	{
+		teamRoutes.POST("/weekend-rotation", h.RealignWeekendRotation)
+	}
 }
 
 func (h *Handler) CreateUser(c *gin.Context) {
@@ -41,34 +51,15 @@ func (h *Handler) CreateUser(c *gin.Context) {
 	}
 
 	var req CreateUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		restErr := ierr.NewBadRequestValidationError("invalid request body", nil)
+	if restErr := validation.BindJSON(c, &req); restErr != nil {
 		c.JSON(restErr.Code, restErr)
 		return
 	}
 
-	var birthday *time.Time
-	if req.Birthday != "" {
-		parsedTime, err := time.Parse("2006-01-02", req.Birthday)
-		if err == nil {
-			birthday = &parsedTime
-		}
-	}
-
-	userEntity := entity.User{
-		Email:             req.Email,
-		Password:          req.Password,
-		FirstName:         req.FirstName,
-		LastName:          req.LastName,
-		PhoneNumber:       req.PhoneNumber,
-		Birthday:          birthday,
-		UserType:          req.UserType,
-		Team:              req.Team,
-		Position:          req.Position,
-		Shift:             req.Shift,
-		WeekdayOff:        req.WeekdayOff,
-		InitialWeekendOff: req.InitialWeekendOff,
-		SuperiorID:        req.SuperiorID,
+	userEntity, errDate := req.toEntity()
+	if errDate != nil {
+		c.JSON(errDate.Code, errDate)
+		return
 	}
 
 	newUser, err := h.service.CreateUser(userEntity, entity.UserType(creatorTypeStr))
@@ -80,6 +71,61 @@ func (h *Handler) CreateUser(c *gin.Context) {
 	c.JSON(http.StatusCreated, ToUserResponse(newUser))
 }
 
+// ImportUsers creates users from a CSV file sent as the "file" field of a
+// multipart form or as the raw request body. With dryRun=true the rows are
+// validated and previewed without creating anything.
+func (h *Handler) ImportUsers(c *gin.Context) {
+	creatorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	dryRun := false
+	if value := c.Query("dryRun"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			restErr := ierr.NewBadRequestValidationError("some fields are invalid", []ierr.Causes{
+				{Field: "dryRun", Message: "must be true or false"},
+			})
+			c.JSON(restErr.Code, restErr)
+			return
+		}
+		dryRun = parsed
+	}
+
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ierr.NewBadRequestError("the import file is required"))
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ierr.NewBadRequestError("could not read uploaded file"))
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	rows, restErr := ParseImportCSV(body)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	res, err := h.service.ImportUsers(rows, dryRun, entity.UserType(creatorType))
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	status := http.StatusCreated
+	if dryRun {
+		status = http.StatusOK
+	}
+	c.JSON(status, res)
+}
+
 func (h *Handler) UpdatePersonalData(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
 	requestorId, errAuth := auth.GetUserIDFromContext(c)
@@ -94,18 +140,15 @@ func (h *Handler) UpdatePersonalData(c *gin.Context) {
 	}
 
 	var req UpdatePersonalDataRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		restErr := ierr.NewBadRequestValidationError("invalid request body", nil)
+	if restErr := validation.BindJSON(c, &req); restErr != nil {
 		c.JSON(restErr.Code, restErr)
 		return
 	}
 
-	var birthday *time.Time
-	if req.Birthday != "" {
-		parsedTime, err := time.Parse("2006-01-02", req.Birthday)
-		if err == nil {
-			birthday = &parsedTime
-		}
+	birthday, errDate := validation.ParseOptionalDate("birthday", req.Birthday)
+	if errDate != nil {
+		c.JSON(errDate.Code, errDate)
+		return
 	}
 
 	userEntity := entity.User{
@@ -126,8 +169,7 @@ func (h *Handler) UpdatePersonalData(c *gin.Context) {
 
 func (h *Handler) Login(c *gin.Context) {
 	var req LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		restErr := ierr.NewBadRequestValidationError("invalid request body", nil)
+	if restErr := validation.BindJSON(c, &req); restErr != nil {
 		c.JSON(restErr.Code, restErr)
 		return
 	}
@@ -169,22 +211,41 @@ func (h *Handler) FindAll(c *gin.Context) {
 
 func (h *Handler) UpdateWorkData(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
+	requestorID, _ := auth.GetUserIDFromContext(c)
 	requestorType, _ := auth.GetUserTypeFromContext(c)
 	var req UpdateWorkDataRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		restErr := ierr.NewBadRequestValidationError("invalid request body", nil)
+	if restErr := validation.BindJSON(c, &req); restErr != nil {
 		c.JSON(restErr.Code, restErr)
 		return
 	}
+	rotationAnchor, errDate := validation.ParseOptionalDate("rotationAnchor", req.RotationAnchor)
+	if errDate != nil {
+		c.JSON(errDate.Code, errDate)
+		return
+	}
+	weekendAnchor, errDate := validation.ParseOptionalDate("weekendRotationAnchor", req.WeekendRotationAnchor)
+	if errDate != nil {
+		c.JSON(errDate.Code, errDate)
+		return
+	}
+	effectiveFrom, errDate := validation.ParseOptionalDate("effectiveFrom", req.EffectiveFrom)
+	if errDate != nil {
+		c.JSON(errDate.Code, errDate)
+		return
+	}
 	userEntity := entity.User{
-		Team:              req.Team,
-		Position:          req.Position,
-		Shift:             req.Shift,
-		WeekdayOff:        req.WeekdayOff,
-		InitialWeekendOff: req.InitialWeekendOff,
-		SuperiorID:        req.SuperiorID,
-	}
-	updatedUser, err := h.service.UpdateWorkData(uint(id), entity.UserType(requestorType), userEntity)
+		Team:                  req.Team,
+		Position:              req.Position,
+		Shift:                 req.Shift,
+		WeekdayOff:            req.WeekdayOff,
+		InitialWeekendOff:     req.InitialWeekendOff,
+		SuperiorID:            req.SuperiorID,
+		RotationPatternID:     req.RotationPatternID,
+		RotationAnchor:        rotationAnchor,
+		WeekendRotationAnchor: weekendAnchor,
+		SiteID:                req.SiteID,
+	}
+	updatedUser, err := h.service.UpdateWorkData(uint(id), requestorID, entity.UserType(requestorType), userEntity, effectiveFrom)
 	if err != nil {
 		c.JSON(err.Code, err)
 		return
@@ -192,6 +253,105 @@ func (h *Handler) UpdateWorkData(c *gin.Context) {
 	c.JSON(http.StatusOK, ToUserResponse(updatedUser))
 }
 
+func (h *Handler) FindWorkHistory(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	requestorID, errAuth := auth.GetUserIDFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	assignments, err := h.service.FindWorkHistory(uint(id), requestorID, entity.UserType(requestorType))
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	res := make([]WorkAssignmentResponse, 0, len(assignments))
+	for i := range assignments {
+		res = append(res, ToWorkAssignmentResponse(&assignments[i]))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *Handler) FindWeekendsOff(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	requestorID, errAuth := auth.GetUserIDFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	count := 8
+	if countStr := c.Query("count"); countStr != "" {
+		parsed, err := strconv.Atoi(countStr)
+		if err != nil || parsed < 1 || parsed > 52 {
+			restErr := ierr.NewBadRequestValidationError("some fields are invalid", []ierr.Causes{
+				{Field: "count", Message: "must be a number between 1 and 52"},
+			})
+			c.JSON(restErr.Code, restErr)
+			return
+		}
+		count = parsed
+	}
+
+	weekends, err := h.service.FindWeekendsOff(uint(id), count, requestorID, entity.UserType(requestorType))
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	c.JSON(http.StatusOK, weekends)
+}
+
+func (h *Handler) RealignWeekendRotation(c *gin.Context) {
+	requestorID, errAuth := auth.GetUserIDFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	requestorType, errAuth := auth.GetUserTypeFromContext(c)
+	if errAuth != nil {
+		c.JSON(errAuth.Code, errAuth)
+		return
+	}
+	team := entity.TeamName(c.Param("team"))
+	if !team.IsValid() {
+		restErr := ierr.NewBadRequestValidationError("some fields are invalid", []ierr.Causes{
+			{Field: "team", Message: "must be a valid team: Security, Support, CustomerService"},
+		})
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	var req WeekendRotationRequest
+	if restErr := validation.BindJSON(c, &req); restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	anchor, restErr := validation.ParseDate("anchor", req.Anchor)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+
+	users, err := h.service.RealignWeekendRotation(team, anchor, req.Alternate, requestorID, entity.UserType(requestorType))
+	if err != nil {
+		c.JSON(err.Code, err)
+		return
+	}
+	res := make([]UserResponse, 0, len(users))
+	for i := range users {
+		res = append(res, ToUserResponse(&users[i]))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
 func (h *Handler) Delete(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
 	requestorType, _ := auth.GetUserTypeFromContext(c)
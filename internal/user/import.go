@@ -0,0 +1,367 @@
+package user
+
+import (
+	"crypto/rand"
+	"encoding/csv"
+	"errors"
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/validation"
+	"escala-fds-api/pkg/ierr"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MaxImportRows caps the number of users a single import may create.
+const MaxImportRows = 500
+
+const temporaryPasswordLength = 12
+
+// temporaryPasswordAlphabet leaves out characters that are easy to misread.
+const temporaryPasswordAlphabet = "abcdefghjkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// errDryRun rolls back the import transaction once a dry run is validated.
+var errDryRun = errors.New("dry run")
+
+// importColumns are the accepted CSV columns, named after the JSON fields of
+// CreateUserRequest. Only email, firstName, lastName and phoneNumber are
+// required; userType defaults to collaborator.
+var importColumns = []string{
+	"email", "firstName", "lastName", "phoneNumber", "birthday", "userType",
+	"team", "position", "shift", "weekdayOff", "initialWeekendOff",
+	"rotationPatternId", "rotationAnchor", "weekendRotationAnchor", "siteId",
+}
+
+// ParseImportCSV reads a user import file. Every row is checked against the
+// CreateUserRequest rules and all problems are reported together, with the
+// field of each cause prefixed by "rows[<line>]".
+func ParseImportCSV(r io.Reader) ([]ImportRow, *ierr.RestErr) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, ierr.NewBadRequestError("the import file is empty")
+	}
+	if err != nil {
+		return nil, ierr.NewBadRequestError(fmt.Sprintf("invalid CSV: %v", err))
+	}
+	columns, restErr := importHeader(header)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	var rows []ImportRow
+	var causes []ierr.Causes
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, ierr.NewBadRequestError(fmt.Sprintf("invalid CSV: %v", err))
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+		if len(rows) == MaxImportRows {
+			return nil, ierr.NewBadRequestError(fmt.Sprintf("an import may contain at most %d users", MaxImportRows))
+		}
+		row, rowCauses := parseImportRecord(line, columns, record)
+		if len(rowCauses) > 0 {
+			causes = append(causes, rowCauses...)
+			continue
+		}
+		rows = append(rows, row)
+	}
+	if len(causes) > 0 {
+		return nil, ierr.NewBadRequestValidationError("invalid user import", causes)
+	}
+	if len(rows) == 0 {
+		return nil, ierr.NewBadRequestError("the import file has no users")
+	}
+	return rows, nil
+}
+
+func importHeader(header []string) (map[string]int, *ierr.RestErr) {
+	known := make(map[string]string, len(importColumns))
+	for _, column := range importColumns {
+		known[strings.ToLower(column)] = column
+	}
+	columns := make(map[string]int, len(header))
+	var causes []ierr.Causes
+	for i, name := range header {
+		column, ok := known[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))]
+		if !ok {
+			causes = append(causes, ierr.Causes{Field: "header", Message: fmt.Sprintf("unknown column %q", name)})
+			continue
+		}
+		if _, duplicated := columns[column]; duplicated {
+			causes = append(causes, ierr.Causes{Field: "header", Message: fmt.Sprintf("column %q appears more than once", column)})
+			continue
+		}
+		columns[column] = i
+	}
+	for _, column := range []string{"email", "firstName", "lastName", "phoneNumber"} {
+		if _, ok := columns[column]; !ok {
+			causes = append(causes, ierr.Causes{Field: "header", Message: fmt.Sprintf("missing required column %q", column)})
+		}
+	}
+	if len(causes) > 0 {
+		return nil, ierr.NewBadRequestValidationError("invalid user import", causes)
+	}
+	return columns, nil
+}
+
+func parseImportRecord(line int, columns map[string]int, record []string) (ImportRow, []ierr.Causes) {
+	value := func(column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	prefix := fmt.Sprintf("rows[%d].", line)
+
+	req := CreateUserRequest{
+		Email:                 value("email"),
+		FirstName:             value("firstName"),
+		LastName:              value("lastName"),
+		PhoneNumber:           value("phoneNumber"),
+		Birthday:              value("birthday"),
+		UserType:              entity.UserType(value("userType")),
+		Team:                  entity.TeamName(value("team")),
+		Position:              entity.PositionName(value("position")),
+		Shift:                 entity.ShiftName(value("shift")),
+		WeekdayOff:            entity.WeekdayName(value("weekdayOff")),
+		InitialWeekendOff:     entity.WeekendDayName(value("initialWeekendOff")),
+		RotationAnchor:        value("rotationAnchor"),
+		WeekendRotationAnchor: value("weekendRotationAnchor"),
+		// Replaced by a generated temporary password when the user is created.
+		Password: "placeholder",
+	}
+	if req.UserType == "" {
+		req.UserType = entity.UserTypeCollaborator
+	}
+
+	var causes []ierr.Causes
+	parseID := func(column string) *uint {
+		raw := value(column)
+		if raw == "" {
+			return nil
+		}
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			causes = append(causes, ierr.Causes{Field: prefix + column, Message: "must be a number"})
+			return nil
+		}
+		parsed := uint(id)
+		return &parsed
+	}
+	req.RotationPatternID = parseID("rotationPatternId")
+	req.SiteID = parseID("siteId")
+	if req.UserType == entity.UserTypeCollaborator {
+		if req.Team == "" {
+			causes = append(causes, ierr.Causes{Field: prefix + "team", Message: "is required for collaborators"})
+		}
+		if req.Position == "" {
+			causes = append(causes, ierr.Causes{Field: prefix + "position", Message: "is required for collaborators"})
+		}
+	}
+	if restErr := validation.ValidateStruct(&req); restErr != nil {
+		causes = append(causes, prefixCauses(prefix, restErr)...)
+	}
+	if len(causes) > 0 {
+		return ImportRow{}, causes
+	}
+
+	user, restErr := req.toEntity()
+	if restErr != nil {
+		return ImportRow{}, prefixCauses(prefix, restErr)
+	}
+	return ImportRow{Line: line, User: user}, nil
+}
+
+// ImportUsers creates the users of an import in one transaction: either all
+// of them are created or none is. Supervisors are created before the rest so
+// that determineSuperior finds them for the collaborators of the same file.
+// The temporary passwords are hashed before the transaction is opened, since
+// bcrypt takes about a second per user. A dry run skips the hashing and
+// otherwise goes through exactly the same steps, then rolls back.
+func (s *service) ImportUsers(rows []ImportRow, dryRun bool, creatorType entity.UserType) (*ImportResponse, *ierr.RestErr) {
+	if creatorType != entity.UserTypeMaster {
+		return nil, ierr.NewForbiddenError("only masters can create new users")
+	}
+
+	ordered := make([]ImportRow, len(rows))
+	copy(ordered, rows)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return importRank(ordered[i].User) < importRank(ordered[j].User)
+	})
+
+	var causes []ierr.Causes
+	lines := make(map[string]int, len(rows))
+	for _, row := range rows {
+		email := strings.ToLower(row.User.Email)
+		if first, ok := lines[email]; ok {
+			causes = append(causes, ierr.Causes{
+				Field:   fmt.Sprintf("rows[%d].email", row.Line),
+				Message: fmt.Sprintf("duplicates the email of line %d", first),
+			})
+			continue
+		}
+		lines[email] = row.Line
+	}
+	if len(causes) > 0 {
+		return nil, ierr.NewBadRequestValidationError("invalid user import", causes)
+	}
+
+	passwords := make([]string, len(ordered))
+	for i := range ordered {
+		password, err := temporaryPassword()
+		if err != nil {
+			return nil, ierr.NewInternalServerError("error generating temporary password")
+		}
+		passwords[i] = password
+	}
+	stored := passwords
+	if !dryRun {
+		hashes, err := hashPasswords(passwords)
+		if err != nil {
+			return nil, ierr.NewInternalServerError("error hashing password")
+		}
+		stored = hashes
+	}
+
+	response := &ImportResponse{DryRun: dryRun, Users: make([]ImportedUserResponse, 0, len(ordered))}
+	var failure *ierr.RestErr
+	err := s.repo.WithTx(func(repo Repository) error {
+		tx := &service{repo: repo, keys: s.keys, shiftRepo: s.shiftRepo, siteRepo: s.siteRepo}
+		createdLines := make(map[uint]int, len(ordered))
+		for i, row := range ordered {
+			user := row.User
+			user.Password = stored[i]
+			if restErr := tx.createUser(&user, false); restErr != nil {
+				if restErr.Code == http.StatusInternalServerError {
+					failure = restErr
+					return failure
+				}
+				causes = append(causes, prefixCauses(fmt.Sprintf("rows[%d].", row.Line), restErr)...)
+				continue
+			}
+			createdLines[user.ID] = row.Line
+
+			imported := ImportedUserResponse{Line: row.Line, User: ToUserResponse(&user), TemporaryPassword: passwords[i]}
+			if user.SuperiorID != nil {
+				imported.SuperiorLine = createdLines[*user.SuperiorID]
+			}
+			if dryRun {
+				imported.User.ID = 0
+				imported.TemporaryPassword = ""
+				if imported.SuperiorLine != 0 {
+					imported.User.SuperiorID = nil
+				}
+			}
+			response.Users = append(response.Users, imported)
+		}
+		if len(causes) > 0 {
+			failure = ierr.NewBadRequestValidationError("invalid user import", causes)
+			return failure
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if failure != nil {
+		return nil, failure
+	}
+	if err != nil && err != errDryRun {
+		return nil, ierr.NewInternalServerError("error importing users")
+	}
+	sort.Slice(response.Users, func(i, j int) bool {
+		return response.Users[i].Line < response.Users[j].Line
+	})
+	return response, nil
+}
+
+// importRank orders supervisors before the collaborators they supervise.
+func importRank(user entity.User) int {
+	switch user.Position {
+	case entity.PositionSupervisorII:
+		return 0
+	case entity.PositionSupervisorI:
+		return 1
+	}
+	return 2
+}
+
+// prefixCauses turns a row error into causes whose fields point at the row.
+func prefixCauses(prefix string, restErr *ierr.RestErr) []ierr.Causes {
+	if len(restErr.Causes) == 0 {
+		return []ierr.Causes{{Field: strings.TrimSuffix(prefix, "."), Message: restErr.Message}}
+	}
+	causes := make([]ierr.Causes, 0, len(restErr.Causes))
+	for _, cause := range restErr.Causes {
+		causes = append(causes, ierr.Causes{Field: prefix + cause.Field, Message: cause.Message})
+	}
+	return causes
+}
+
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// hashPasswords hashes the passwords with bcrypt, spreading the work over the
+// available CPUs.
+func hashPasswords(passwords []string) ([]string, error) {
+	hashes := make([]string, len(passwords))
+	errs := make([]error, len(passwords))
+	slots := make(chan struct{}, runtime.NumCPU())
+	var wg sync.WaitGroup
+	for i, password := range passwords {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, password string) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			user := entity.User{Password: password}
+			errs[i] = user.HashPassword()
+			hashes[i] = user.Password
+		}(i, password)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return hashes, nil
+}
+
+func temporaryPassword() (string, error) {
+	max := big.NewInt(int64(len(temporaryPasswordAlphabet)))
+	password := make([]byte, temporaryPasswordLength)
+	for i := range password {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		password[i] = temporaryPasswordAlphabet[n.Int64()]
+	}
+	return string(password), nil
+}
@@ -2,6 +2,7 @@ package user
 
 import (
 	"escala-fds-api/internal/entity"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -17,6 +18,11 @@ type Repository interface {
 	FindMasterUser() (*entity.User, error)
 	UpdateUser(user *entity.User) error
 	DeleteUser(id uint) error
+	UpdateWorkData(user *entity.User, assignment *entity.WorkAssignment) error
+	UpdateWorkDataBatch(users []entity.User, assignments []entity.WorkAssignment) error
+	FindAssignments(userID uint) ([]entity.WorkAssignment, error)
+	FindAssignmentsInRange(userID uint, startDate, endDate time.Time) ([]entity.WorkAssignment, error)
+	WithTx(fn func(repo Repository) error) error
 }
 
 type repository struct {
@@ -27,8 +33,16 @@ func NewRepository(db *gorm.DB) Repository {
 	return &repository{db: db}
 }
 
+// CreateUser stores the user and opens its first work assignment.
 func (r *repository) CreateUser(user *entity.User) error {
-	return r.db.Create(user).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		created := user.CreatedAt.UTC()
+		assignment := entity.NewWorkAssignment(user, time.Date(created.Year(), created.Month(), created.Day(), 0, 0, 0, 0, time.UTC))
+		return tx.Create(&assignment).Error
+	})
 }
 
 func (r *repository) FindUserByEmail(email string) (*entity.User, error) {
@@ -89,3 +103,66 @@ func (r *repository) UpdateUser(user *entity.User) error {
 func (r *repository) DeleteUser(id uint) error {
 	return r.db.Delete(&entity.User{}, id).Error
 }
+
+// UpdateWorkData saves the user and makes assignment the one in effect from
+// its ValidFrom: assignments starting on or after that date are dropped and
+// the one running at that date is closed the day before.
+func (r *repository) UpdateWorkData(user *entity.User, assignment *entity.WorkAssignment) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return updateWorkData(tx, user, assignment)
+	})
+}
+
+// UpdateWorkDataBatch applies UpdateWorkData to several users at once, all or
+// nothing.
+func (r *repository) UpdateWorkDataBatch(users []entity.User, assignments []entity.WorkAssignment) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for i := range users {
+			if err := updateWorkData(tx, &users[i], &assignments[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *repository) FindAssignments(userID uint) ([]entity.WorkAssignment, error) {
+	var assignments []entity.WorkAssignment
+	err := r.db.Where("user_id = ?", userID).Order("valid_from desc").Find(&assignments).Error
+	return assignments, err
+}
+
+func (r *repository) FindAssignmentsInRange(userID uint, startDate, endDate time.Time) ([]entity.WorkAssignment, error) {
+	var assignments []entity.WorkAssignment
+	err := r.db.Where("user_id = ? AND valid_from <= ? AND (valid_to IS NULL OR valid_to >= ?)", userID, endDate, startDate).
+		Order("valid_from asc").
+		Find(&assignments).Error
+	return assignments, err
+}
+
+// WithTx runs fn with a repository bound to a single transaction, committed
+// only when fn returns nil.
+func (r *repository) WithTx(fn func(repo Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&repository{db: tx})
+	})
+}
+
+func updateWorkData(tx *gorm.DB, user *entity.User, assignment *entity.WorkAssignment) error {
+	err := tx.Where("user_id = ? AND valid_from >= ?", user.ID, assignment.ValidFrom).
+		Delete(&entity.WorkAssignment{}).Error
+	if err != nil {
+		return err
+	}
+	dayBefore := assignment.ValidFrom.AddDate(0, 0, -1)
+	err = tx.Model(&entity.WorkAssignment{}).
+		Where("user_id = ? AND (valid_to IS NULL OR valid_to > ?)", user.ID, dayBefore).
+		Update("valid_to", dayBefore).Error
+	if err != nil {
+		return err
+	}
+	if err := tx.Create(assignment).Error; err != nil {
+		return err
+	}
+	return tx.Save(user).Error
+}
@@ -1,11 +1,14 @@
 package user
 
 import (
+	"escala-fds-api/internal/auth"
+	"escala-fds-api/internal/constants"
 	"escala-fds-api/internal/entity"
+	"escala-fds-api/internal/shift"
+	"escala-fds-api/internal/site"
 	"escala-fds-api/pkg/ierr"
 	"fmt"
 	"log"
-	"os"
 	"strings"
 	"time"
 
@@ -19,19 +22,27 @@ type Service interface {
 	FindUserByID(id uint) (*entity.User, *ierr.RestErr)
 	FindAllUsers(requestorType entity.UserType, requestorTeam entity.TeamName) ([]entity.User, *ierr.RestErr)
 	UpdatePersonalData(id, requestorId uint, requestorType entity.UserType, userUpdates entity.User) (*entity.User, *ierr.RestErr)
-	UpdateWorkData(id uint, requestorType entity.UserType, userUpdates entity.User) (*entity.User, *ierr.RestErr)
+	UpdateWorkData(id, requestorID uint, requestorType entity.UserType, userUpdates entity.User, effectiveFrom *time.Time) (*entity.User, *ierr.RestErr)
+	FindWorkHistory(id, requestorID uint, requestorType entity.UserType) ([]entity.WorkAssignment, *ierr.RestErr)
+	FindWeekendsOff(id uint, count int, requestorID uint, requestorType entity.UserType) ([]WeekendOffResponse, *ierr.RestErr)
+	RealignWeekendRotation(team entity.TeamName, anchor time.Time, alternate bool, requestorID uint, requestorType entity.UserType) ([]entity.User, *ierr.RestErr)
 	DeleteUser(id uint, requestorType entity.UserType) *ierr.RestErr
+	ImportUsers(rows []ImportRow, dryRun bool, creatorType entity.UserType) (*ImportResponse, *ierr.RestErr)
 }
 
 type service struct {
 	repo      Repository
-	jwtSecret string
+	keys      *auth.KeySet
+	shiftRepo shift.Repository
+	siteRepo  site.Repository
 }
 
-func NewService(repo Repository) Service {
+func NewService(repo Repository, keys *auth.KeySet, shiftRepo shift.Repository, siteRepo site.Repository) Service {
 	return &service{
 		repo:      repo,
-		jwtSecret: os.Getenv("JWT_SECRET_KEY"),
+		keys:      keys,
+		shiftRepo: shiftRepo,
+		siteRepo:  siteRepo,
 	}
 }
 
@@ -96,8 +107,7 @@ func (s *service) Login(email, password string) (string, *entity.User, *ierr.Res
 		"team":      user.Team,
 		"exp":       time.Now().Add(time.Hour * 24).Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.jwtSecret))
+	tokenString, err := s.keys.Sign(claims)
 	if err != nil {
 		log.Printf("LOGIN ERROR: Token generation failed: %v", err)
 		return "", nil, ierr.NewInternalServerError("error generating token")
@@ -109,30 +119,45 @@ func (s *service) CreateUser(user entity.User, creatorType entity.UserType) (*en
 	if creatorType != entity.UserTypeMaster {
 		return nil, ierr.NewForbiddenError("only masters can create new users")
 	}
+	if err := s.createUser(&user, true); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// createUser validates the work data, resolves the superior and stores the
+// user, hashing its password unless hashPassword is false because the caller
+// already did.
+func (s *service) createUser(user *entity.User, hashPassword bool) *ierr.RestErr {
 	if user.UserType == entity.UserTypeCollaborator {
-		if err := s.validateWorkData(&user); err != nil {
-			return nil, err
+		if err := s.validateWorkData(user); err != nil {
+			return err
+		}
+		if err := resolveWeekendAnchor(user, nil, time.Now().UTC()); err != nil {
+			return err
 		}
 		superiorID, err := s.determineSuperior(user.Team, user.Position)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		user.SuperiorID = superiorID
 	}
 	existingUser, err := s.repo.FindUserByEmail(user.Email)
 	if err != nil && err != gorm.ErrRecordNotFound {
-		return nil, ierr.NewInternalServerError("error finding user by email")
+		return ierr.NewInternalServerError("error finding user by email")
 	}
 	if existingUser != nil {
-		return nil, ierr.NewConflictError("user with this email already exists")
+		return ierr.NewConflictError("user with this email already exists")
 	}
-	if err := user.HashPassword(); err != nil {
-		return nil, ierr.NewInternalServerError("error hashing password")
+	if hashPassword {
+		if err := user.HashPassword(); err != nil {
+			return ierr.NewInternalServerError("error hashing password")
+		}
 	}
-	if err := s.repo.CreateUser(&user); err != nil {
-		return nil, ierr.NewInternalServerError("error creating user")
+	if err := s.repo.CreateUser(user); err != nil {
+		return ierr.NewInternalServerError("error creating user")
 	}
-	return &user, nil
+	return nil
 }
 
 func (s *service) FindUserByID(id uint) (*entity.User, *ierr.RestErr) {
@@ -160,7 +185,10 @@ func (s *service) FindAllUsers(requestorType entity.UserType, requestorTeam enti
 	return users, nil
 }
 
-func (s *service) UpdateWorkData(id uint, requestorType entity.UserType, userUpdates entity.User) (*entity.User, *ierr.RestErr) {
+// UpdateWorkData changes the work data of a user from effectiveFrom onwards
+// (today when nil). Earlier dates keep the previous assignment, so past
+// schedules are not recomputed with the new values.
+func (s *service) UpdateWorkData(id, requestorID uint, requestorType entity.UserType, userUpdates entity.User, effectiveFrom *time.Time) (*entity.User, *ierr.RestErr) {
 	if requestorType != entity.UserTypeMaster {
 		return nil, ierr.NewForbiddenError("only masters can update work data")
 	}
@@ -171,22 +199,156 @@ func (s *service) UpdateWorkData(id uint, requestorType entity.UserType, userUpd
 	if err := s.validateWorkData(&userUpdates); err != nil {
 		return nil, err
 	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	validFrom := today
+	if effectiveFrom != nil {
+		validFrom = *effectiveFrom
+	}
+	created := user.CreatedAt.UTC().Truncate(24 * time.Hour)
+	if validFrom.After(today) {
+		return nil, ierr.NewBadRequestValidationError("invalid work data", []ierr.Causes{
+			{Field: "effectiveFrom", Message: "must not be in the future"},
+		})
+	}
+	if validFrom.Before(created) {
+		return nil, ierr.NewBadRequestValidationError("invalid work data", []ierr.Causes{
+			{Field: "effectiveFrom", Message: fmt.Sprintf("must not be before the user was created (%s)", created.Format(constants.ApiDateLayout))},
+		})
+	}
+
+	if err := resolveWeekendAnchor(&userUpdates, user, validFrom); err != nil {
+		return nil, err
+	}
+
 	user.Team = userUpdates.Team
 	user.Position = userUpdates.Position
 	user.Shift = userUpdates.Shift
 	user.WeekdayOff = userUpdates.WeekdayOff
 	user.InitialWeekendOff = userUpdates.InitialWeekendOff
+	user.RotationPatternID = userUpdates.RotationPatternID
+	user.RotationAnchor = userUpdates.RotationAnchor
+	user.WeekendRotationAnchor = userUpdates.WeekendRotationAnchor
+	user.LegacyWeekendCount = userUpdates.LegacyWeekendCount
+	user.SiteID = userUpdates.SiteID
 	superiorID, err := s.determineSuperior(userUpdates.Team, userUpdates.Position)
 	if err != nil {
 		return nil, err
 	}
 	user.SuperiorID = superiorID
-	if err := s.repo.UpdateUser(user); err != nil {
+
+	assignment := entity.NewWorkAssignment(user, validFrom)
+	assignment.CreatedByID = &requestorID
+	if err := s.repo.UpdateWorkData(user, &assignment); err != nil {
 		return nil, ierr.NewInternalServerError("error updating user work data")
 	}
 	return user, nil
 }
 
+func (s *service) FindWorkHistory(id, requestorID uint, requestorType entity.UserType) ([]entity.WorkAssignment, *ierr.RestErr) {
+	user, restErr := s.FindUserByID(id)
+	if restErr != nil {
+		return nil, restErr
+	}
+	isSuperior := user.SuperiorID != nil && *user.SuperiorID == requestorID
+	if requestorType != entity.UserTypeMaster && id != requestorID && !isSuperior {
+		return nil, ierr.NewForbiddenError("you do not have permission to view this work history")
+	}
+	assignments, err := s.repo.FindAssignments(id)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding work history")
+	}
+	return assignments, nil
+}
+
+// FindWeekendsOff previews the next count weekend days off of the user's
+// alternating weekend rotation, starting today.
+func (s *service) FindWeekendsOff(id uint, count int, requestorID uint, requestorType entity.UserType) ([]WeekendOffResponse, *ierr.RestErr) {
+	user, restErr := s.FindUserByID(id)
+	if restErr != nil {
+		return nil, restErr
+	}
+	isSuperior := user.SuperiorID != nil && *user.SuperiorID == requestorID
+	if requestorType != entity.UserTypeMaster && id != requestorID && !isSuperior {
+		return nil, ierr.NewForbiddenError("you do not have permission to view this schedule")
+	}
+
+	weekends := []WeekendOffResponse{}
+	if user.WeekendRotationAnchor == nil && user.InitialWeekendOff == "" {
+		return weekends, nil
+	}
+	saturday := entity.FirstWeekendOff(time.Now().UTC(), entity.WeekendSaturday)
+	if time.Now().UTC().Weekday() == time.Sunday {
+		saturday = saturday.AddDate(0, 0, -7)
+	}
+	for checked := 0; len(weekends) < count && checked < 2*count+2; checked++ {
+		for _, day := range []time.Time{saturday, saturday.AddDate(0, 0, 1)} {
+			if day.Before(time.Now().UTC().Truncate(24*time.Hour)) || !user.IsWeekendDayOff(day) {
+				continue
+			}
+			weekday := entity.WeekendSaturday
+			if day.Weekday() == time.Sunday {
+				weekday = entity.WeekendSunday
+			}
+			weekends = append(weekends, WeekendOffResponse{Date: day.Format(constants.ApiDateLayout), Weekday: weekday})
+		}
+		saturday = saturday.AddDate(0, 0, 7)
+	}
+	if len(weekends) > count {
+		weekends = weekends[:count]
+	}
+	return weekends, nil
+}
+
+// RealignWeekendRotation gives every collaborator of the team the same
+// weekend parity from anchor, or with alternate splits them so that half of
+// the team is off on each weekend day. The change takes effect today.
+func (s *service) RealignWeekendRotation(team entity.TeamName, anchor time.Time, alternate bool, requestorID uint, requestorType entity.UserType) ([]entity.User, *ierr.RestErr) {
+	if requestorType != entity.UserTypeMaster {
+		return nil, ierr.NewForbiddenError("only masters can realign weekend rotations")
+	}
+	if anchor.Weekday() != time.Saturday && anchor.Weekday() != time.Sunday {
+		return nil, ierr.NewBadRequestValidationError("some fields are invalid", []ierr.Causes{
+			{Field: "anchor", Message: "must be a saturday or a sunday"},
+		})
+	}
+	opposite := anchor.AddDate(0, 0, 1)
+	if anchor.Weekday() == time.Sunday {
+		opposite = anchor.AddDate(0, 0, -1)
+	}
+
+	members, err := s.repo.FindUsersByTeam(team)
+	if err != nil {
+		return nil, ierr.NewInternalServerError("error finding team members")
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	var users []entity.User
+	var assignments []entity.WorkAssignment
+	for _, member := range members {
+		if member.UserType != entity.UserTypeCollaborator {
+			continue
+		}
+		memberAnchor := anchor
+		if alternate && len(users)%2 == 1 {
+			memberAnchor = opposite
+		}
+		member.WeekendRotationAnchor = &memberAnchor
+		member.LegacyWeekendCount = false
+		member.InitialWeekendOff = weekendDayName(memberAnchor)
+		assignment := entity.NewWorkAssignment(&member, today)
+		assignment.CreatedByID = &requestorID
+		users = append(users, member)
+		assignments = append(assignments, assignment)
+	}
+	if len(users) == 0 {
+		return []entity.User{}, nil
+	}
+	if err := s.repo.UpdateWorkDataBatch(users, assignments); err != nil {
+		return nil, ierr.NewInternalServerError("error realigning weekend rotation")
+	}
+	return users, nil
+}
+
 func (s *service) DeleteUser(id uint, requestorType entity.UserType) *ierr.RestErr {
 	if requestorType != entity.UserTypeMaster {
 		return ierr.NewForbiddenError("only masters can delete users")
@@ -215,6 +377,88 @@ func (s *service) validateWorkData(user *entity.User) *ierr.RestErr {
 	if !isValidPosition {
 		return ierr.NewBadRequestError(fmt.Sprintf("position '%s' is not valid for team '%s'", user.Position, user.Team))
 	}
+	if user.SiteID != nil {
+		if _, err := s.siteRepo.FindSiteByID(*user.SiteID); err != nil {
+			if err != gorm.ErrRecordNotFound {
+				return ierr.NewInternalServerError("error finding site")
+			}
+			return ierr.NewBadRequestValidationError("invalid work data", []ierr.Causes{
+				{Field: "siteId", Message: "site not found"},
+			})
+		}
+	}
+	return s.validateShiftAssignment(user)
+}
+
+// resolveWeekendAnchor keeps WeekendRotationAnchor and InitialWeekendOff
+// consistent. An explicit anchor decides the initial weekend day; otherwise
+// the previous anchor is kept when the weekend day did not change, or a new
+// one is set at the first such day on or after from. Only a kept anchor keeps
+// the legacy week count of a backfilled one.
+func resolveWeekendAnchor(user *entity.User, previous *entity.User, from time.Time) *ierr.RestErr {
+	user.LegacyWeekendCount = false
+	if user.WeekendRotationAnchor != nil {
+		anchor := *user.WeekendRotationAnchor
+		if anchor.Weekday() != time.Saturday && anchor.Weekday() != time.Sunday {
+			return ierr.NewBadRequestValidationError("invalid work data", []ierr.Causes{
+				{Field: "weekendRotationAnchor", Message: "must be a saturday or a sunday"},
+			})
+		}
+		user.InitialWeekendOff = weekendDayName(anchor)
+		if previous != nil && previous.WeekendRotationAnchor != nil && previous.WeekendRotationAnchor.Format(constants.ApiDateLayout) == anchor.Format(constants.ApiDateLayout) {
+			user.LegacyWeekendCount = previous.LegacyWeekendCount
+		}
+		return nil
+	}
+	if user.InitialWeekendOff == "" {
+		return nil
+	}
+	if previous != nil && previous.InitialWeekendOff == user.InitialWeekendOff && previous.WeekendRotationAnchor != nil {
+		user.WeekendRotationAnchor = previous.WeekendRotationAnchor
+		user.LegacyWeekendCount = previous.LegacyWeekendCount
+		return nil
+	}
+	anchor := entity.FirstWeekendOff(from, user.InitialWeekendOff)
+	user.WeekendRotationAnchor = &anchor
+	return nil
+}
+
+func weekendDayName(date time.Time) entity.WeekendDayName {
+	if date.Weekday() == time.Saturday {
+		return entity.WeekendSaturday
+	}
+	return entity.WeekendSunday
+}
+
+// validateShiftAssignment checks that the user's shift is a template
+// available to their team and that a rotation pattern comes with an anchor.
+func (s *service) validateShiftAssignment(user *entity.User) *ierr.RestErr {
+	var causes []ierr.Causes
+	if user.Shift != "" {
+		template, err := s.shiftRepo.FindTemplateByName(user.Shift)
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return ierr.NewInternalServerError("error finding shift template")
+		}
+		if err == gorm.ErrRecordNotFound {
+			causes = append(causes, ierr.Causes{Field: "shift", Message: "shift template not found"})
+		} else if !template.AvailableTo(user.Team) {
+			causes = append(causes, ierr.Causes{Field: "shift", Message: fmt.Sprintf("shift template is not available to team %s", user.Team)})
+		}
+	}
+	if user.RotationPatternID != nil {
+		if _, err := s.shiftRepo.FindPatternByID(*user.RotationPatternID); err != nil {
+			if err != gorm.ErrRecordNotFound {
+				return ierr.NewInternalServerError("error finding rotation pattern")
+			}
+			causes = append(causes, ierr.Causes{Field: "rotationPatternId", Message: "rotation pattern not found"})
+		}
+		if user.RotationAnchor == nil {
+			causes = append(causes, ierr.Causes{Field: "rotationAnchor", Message: "is required when a rotation pattern is set"})
+		}
+	}
+	if len(causes) > 0 {
+		return ierr.NewBadRequestValidationError("invalid shift assignment", causes)
+	}
 	return nil
 }
 
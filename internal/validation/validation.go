@@ -0,0 +1,208 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"escala-fds-api/internal/constants"
+	"escala-fds-api/internal/entity"
+	"escala-fds-api/pkg/ierr"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+	// Shift names reference DB templates; services check they exist.
+	v.RegisterValidation("shift", func(fl validator.FieldLevel) bool {
+		name := fl.Field().String()
+		return name != "" && len(name) <= 20
+	})
+	v.RegisterValidation("clock", func(fl validator.FieldLevel) bool {
+		_, err := entity.ParseClock(fl.Field().String())
+		return err == nil
+	})
+	v.RegisterValidation("team", func(fl validator.FieldLevel) bool {
+		return entity.TeamName(fl.Field().String()).IsValid()
+	})
+	v.RegisterValidation("weekday", func(fl validator.FieldLevel) bool {
+		return entity.WeekdayName(fl.Field().String()).IsValid()
+	})
+	v.RegisterValidation("weekend_day", func(fl validator.FieldLevel) bool {
+		return entity.WeekendDayName(fl.Field().String()).IsValid()
+	})
+	v.RegisterValidation("apidate", func(fl validator.FieldLevel) bool {
+		_, err := time.Parse(constants.ApiDateLayout, fl.Field().String())
+		return err == nil
+	})
+	v.RegisterValidation("gtedatefield", validateDateAfterField)
+}
+
+// validateDateAfterField checks that a date string is not before the date
+// string held by the sibling field named in the tag parameter. Malformed
+// dates are left for the apidate tag to report.
+func validateDateAfterField(fl validator.FieldLevel) bool {
+	parent := fl.Parent()
+	if parent.Kind() == reflect.Ptr {
+		parent = parent.Elem()
+	}
+	other := parent.FieldByName(fl.Param())
+	if !other.IsValid() || other.Kind() != reflect.String {
+		return false
+	}
+	date, err := time.Parse(constants.ApiDateLayout, fl.Field().String())
+	if err != nil {
+		return true
+	}
+	otherDate, err := time.Parse(constants.ApiDateLayout, other.String())
+	if err != nil {
+		return true
+	}
+	return !date.Before(otherDate)
+}
+
+// BindJSON decodes and validates the request body, translating validator
+// errors into one ierr.Causes entry per offending field.
+func BindJSON(c *gin.Context, obj any) *ierr.RestErr {
+	return FromBindingError(c.ShouldBindJSON(obj))
+}
+
+// Bind is like BindJSON but picks the binding from the request Content-Type,
+// so it also accepts form and multipart bodies.
+func Bind(c *gin.Context, obj any) *ierr.RestErr {
+	return FromBindingError(c.ShouldBind(obj))
+}
+
+// ValidateStruct runs the binding tags of obj for values that did not come
+// from a request body, such as CSV rows.
+func ValidateStruct(obj any) *ierr.RestErr {
+	return FromBindingError(binding.Validator.ValidateStruct(obj))
+}
+
+func FromBindingError(err error) *ierr.RestErr {
+	if err == nil {
+		return nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		causes := make([]ierr.Causes, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			causes = append(causes, ierr.Causes{Field: fe.Field(), Message: messageFor(fe)})
+		}
+		return ierr.NewBadRequestValidationError("some fields are invalid", causes)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return ierr.NewBadRequestValidationError("some fields are invalid", []ierr.Causes{
+			{Field: typeErr.Field, Message: fmt.Sprintf("must be of type %s", typeErr.Type.String())},
+		})
+	}
+
+	return ierr.NewBadRequestError("invalid request body")
+}
+
+// ParseDate parses a date in constants.ApiDateLayout as UTC midnight.
+func ParseDate(field, value string) (time.Time, *ierr.RestErr) {
+	date, err := time.ParseInLocation(constants.ApiDateLayout, value, time.UTC)
+	if err != nil {
+		return time.Time{}, ierr.NewBadRequestValidationError("some fields are invalid", []ierr.Causes{
+			{Field: field, Message: "must be a date in YYYY-MM-DD format"},
+		})
+	}
+	return date, nil
+}
+
+// ParseMonth parses a month in constants.ApiMonthLayout and returns its
+// first day as UTC midnight.
+func ParseMonth(field, value string) (time.Time, *ierr.RestErr) {
+	month, err := time.ParseInLocation(constants.ApiMonthLayout, value, time.UTC)
+	if err != nil {
+		return time.Time{}, ierr.NewBadRequestValidationError("some fields are invalid", []ierr.Causes{
+			{Field: field, Message: "must be a month in YYYY-MM format"},
+		})
+	}
+	return month, nil
+}
+
+// ParseOptionalDate is ParseDate for fields that may be left empty.
+func ParseOptionalDate(field, value string) (*time.Time, *ierr.RestErr) {
+	if value == "" {
+		return nil, nil
+	}
+	date, err := ParseDate(field, value)
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
+
+func messageFor(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email"
+	case "min":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters long", fe.Param())
+		}
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must contain at least %s items", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters long", fe.Param())
+		}
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must contain at most %s items", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.ReplaceAll(fe.Param(), " ", ", "))
+	case "shift":
+		return "must be a shift template name of at most 20 characters"
+	case "clock":
+		return "must be a time of day in HH:MM format"
+	case "team":
+		return fmt.Sprintf("must be a valid team: %s, %s, %s", entity.TeamSecurity, entity.TeamSupport, entity.TeamCustomerService)
+	case "weekday":
+		return "must be a weekday from monday to friday"
+	case "weekend_day":
+		return "must be saturday or sunday"
+	case "apidate":
+		return "must be a date in YYYY-MM-DD format"
+	case "gtedatefield":
+		return fmt.Sprintf("must be on or after %s", lowerFirst(fe.Param()))
+	}
+	return fmt.Sprintf("failed on the '%s' validation", fe.Tag())
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}